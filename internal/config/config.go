@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -12,11 +14,24 @@ import (
 
 // Config holds all configuration for our application
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
-	CORS     CORSConfig     `mapstructure:"cors"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Logger    LoggerConfig    `mapstructure:"logger"`
+	CORS      CORSConfig      `mapstructure:"cors"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	Account   AccountConfig   `mapstructure:"account"`
+	Mailer    MailerConfig    `mapstructure:"mailer"`
+	Tenant    TenantConfig    `mapstructure:"tenant"`
+	RateLimit RateLimitConfig `mapstructure:"ratelimit"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	IPFilter    IPFilterConfig    `mapstructure:"ipfilter"`
+	Compression CompressionConfig `mapstructure:"compression"`
+	Maintenance MaintenanceConfig `mapstructure:"maintenance"`
+	Flags       FlagsConfig       `mapstructure:"flags"`
 }
 
 // ServerConfig holds server related configuration
@@ -24,6 +39,28 @@ type ServerConfig struct {
 	Host string `mapstructure:"host"`
 	Port string `mapstructure:"port"`
 	Mode string `mapstructure:"mode"` // debug, release, test
+
+	// TrustedProxies lists the proxies (CIDRs or IPs) whose RemoteIPHeaders are
+	// believed; other peers are taken as the client
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// RemoteIPHeaders lists, in order of preference, the headers trusted proxies report
+	// the client IP in: X-Forwarded-For, X-Real-IP or Forwarded. Only list headers the
+	// proxy sets or overwrites, since clients can send any of them.
+	RemoteIPHeaders []string `mapstructure:"remote_ip_headers"`
+
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+
+	// MaxBodySize caps request bodies in bytes and HandlerTimeout the time a handler
+	// gets before its request is cancelled. Both can be overridden by path prefix of a
+	// route group, 0 lifting the limit.
+	MaxBodySize     int64                    `mapstructure:"max_body_size"`
+	BodyLimits      map[string]int64         `mapstructure:"body_limits"`
+	HandlerTimeout  time.Duration            `mapstructure:"handler_timeout"`
+	HandlerTimeouts map[string]time.Duration `mapstructure:"handler_timeouts"`
 }
 
 // DatabaseConfig holds database related configuration
@@ -47,6 +84,20 @@ type LoggerConfig struct {
 	Level      string `mapstructure:"level"`       // debug, info, warn, error
 	Format     string `mapstructure:"format"`      // json, json-pretty, console
 	DisableGin bool   `mapstructure:"disable_gin"` // disable gin debug logs
+
+	Bodies BodyLogConfig `mapstructure:"bodies"`
+}
+
+// BodyLogConfig holds request and response body logging configuration. Bodies are
+// only logged for the listed routes, and password, token and similar fields, as well as
+// Authorization and cookie headers, are always redacted.
+type BodyLogConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	Routes        []string `mapstructure:"routes"`         // path prefixes whose bodies are logged
+	MaxSize       int      `mapstructure:"max_size"`       // bytes logged of each body; the rest is truncated
+	SampleRate    float64  `mapstructure:"sample_rate"`    // fraction of requests logged, 0-1
+	RedactFields  []string `mapstructure:"redact_fields"`  // JSON and form fields or dotted paths redacted besides the defaults
+	RedactHeaders []string `mapstructure:"redact_headers"` // headers redacted besides the defaults
 }
 
 // CORSConfig holds CORS related configuration
@@ -56,6 +107,171 @@ type CORSConfig struct {
 	AllowedHeaders []string `mapstructure:"allowed_headers"`
 }
 
+// SecurityConfig holds the security headers sent with responses
+type SecurityConfig struct {
+	Headers SecurityHeaders            `mapstructure:"headers"`
+	Routes  map[string]SecurityHeaders `mapstructure:"routes"` // overrides by path prefix, e.g. "/docs"
+	HSTS    HSTSConfig                 `mapstructure:"hsts"`
+}
+
+// SecurityHeaders are the values of the configurable security headers. In route
+// overrides, empty values keep the default and "-" drops the header.
+type SecurityHeaders struct {
+	ContentSecurityPolicy     string `mapstructure:"content_security_policy"` // "{nonce}" becomes the request's nonce
+	FrameOptions              string `mapstructure:"frame_options"`
+	ReferrerPolicy            string `mapstructure:"referrer_policy"`
+	PermissionsPolicy         string `mapstructure:"permissions_policy"`
+	CrossOriginOpenerPolicy   string `mapstructure:"cross_origin_opener_policy"`
+	CrossOriginEmbedderPolicy string `mapstructure:"cross_origin_embedder_policy"`
+	CrossOriginResourcePolicy string `mapstructure:"cross_origin_resource_policy"`
+}
+
+// HSTSConfig holds Strict-Transport-Security configuration. The header is only sent in
+// release mode, so development over plain HTTP keeps working.
+type HSTSConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	MaxAge            time.Duration `mapstructure:"max_age"`
+	IncludeSubdomains bool          `mapstructure:"include_subdomains"`
+	Preload           bool          `mapstructure:"preload"`
+}
+
+// CompressionConfig holds response compression and request decompression configuration
+type CompressionConfig struct {
+	Enabled             bool     `mapstructure:"enabled"`
+	Encodings           []string `mapstructure:"encodings"`             // zstd, br, gzip; preferred first
+	MinSize             int      `mapstructure:"min_size"`              // smaller responses are sent uncompressed
+	MaxDecompressedSize int64    `mapstructure:"max_decompressed_size"` // cap of gzip request bodies once decompressed
+}
+
+// MaintenanceConfig holds maintenance mode configuration. The state file, when set,
+// overrides Mode and is what the admin endpoint writes, so the mode survives restarts
+// and reaches every instance that shares the file.
+type MaintenanceConfig struct {
+	Mode           string        `mapstructure:"mode"`            // off, read_only, full
+	StateFile      string        `mapstructure:"state_file"`      // JSON state file, optional
+	AllowedIPs     []string      `mapstructure:"allowed_ips"`     // CIDRs or IPs that bypass maintenance, e.g. admin networks
+	RetryAfter     time.Duration `mapstructure:"retry_after"`     // sent in Retry-After with rejected requests
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // how often the state file is checked for changes
+}
+
+// FlagsConfig holds feature flag configuration. Flags are defined in the file and
+// through the admin API, which stores them in the database and wins over the file.
+type FlagsConfig struct {
+	File            string        `mapstructure:"file"`             // YAML flags file, optional
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"` // how often flags are reloaded from the file and database; 0 turns reloading off
+}
+
+// StorageConfig holds blob storage related configuration
+type StorageConfig struct {
+	Driver        string        `mapstructure:"driver"`          // local, s3
+	LocalPath     string        `mapstructure:"local_path"`      // root directory for the local driver
+	S3            S3Config      `mapstructure:"s3"`              // settings for the s3 driver
+	PublicURL     string        `mapstructure:"public_url"`      // external base URL of the API, used in signed links
+	URLSecret     string        `mapstructure:"url_secret"`      // HMAC key for signed links, defaults to the JWT secret
+	URLExpiry     time.Duration `mapstructure:"url_expiry"`      // how long signed links stay valid
+	MaxAvatarSize int64         `mapstructure:"max_avatar_size"` // maximum avatar upload size in bytes
+}
+
+// S3Config holds S3-compatible object storage configuration
+type S3Config struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	UsePathStyle    bool   `mapstructure:"use_path_style"`
+}
+
+// AccountConfig holds account lifecycle related configuration
+type AccountConfig struct {
+	DeletionGracePeriod   time.Duration `mapstructure:"deletion_grace_period"`   // how long a self-service deletion can be cancelled
+	DeletionCheckInterval time.Duration `mapstructure:"deletion_check_interval"` // how often the deletion job looks for due accounts
+	DeletionMode          string        `mapstructure:"deletion_mode"`           // soft_delete, anonymize
+}
+
+// MailerConfig holds outgoing email configuration
+type MailerConfig struct {
+	Driver string     `mapstructure:"driver"` // log, smtp
+	From   string     `mapstructure:"from"`
+	SMTP   SMTPConfig `mapstructure:"smtp"`
+}
+
+// SMTPConfig holds SMTP server configuration
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// TenantConfig holds multi-tenancy related configuration
+type TenantConfig struct {
+	Header      string `mapstructure:"header"`       // request header naming the tenant by ID or slug
+	BaseDomain  string `mapstructure:"base_domain"`  // tenants are resolved from <slug>.<base_domain> when set
+	DefaultSlug string `mapstructure:"default_slug"` // tenant used when a request names none, empty to require one
+}
+
+// RateLimitConfig holds rate limiting configuration
+type RateLimitConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Store       string `mapstructure:"store"`        // memory, redis (shared by all instances)
+	FailureMode string `mapstructure:"failure_mode"` // open, closed: whether requests pass while the store is unreachable
+	MaxKeys     int    `mapstructure:"max_keys"`     // clients the memory store tracks; the least recently seen are dropped beyond it
+
+	// Policies are keyed by route group name (api, auth, protected, profile, teams,
+	// invitations, admin, organizations) or by route pattern, either
+	// "/api/v1/admin/users/:id" or "PUT /api/v1/admin/users/:id" to limit a single method
+	Policies map[string]RateLimitPolicy `mapstructure:"policies"`
+
+	// Plans set the rate of policies with use_plan and the daily and monthly request
+	// quotas of authenticated users. A plan named after a user's role wins over the
+	// user's own plan; users without a plan get the default plan.
+	Plans       map[string]RateLimitPlan `mapstructure:"plans"`
+	DefaultPlan string                   `mapstructure:"default_plan"`
+}
+
+// RateLimitPolicy limits how many requests a client can make to the routes it applies to
+type RateLimitPolicy struct {
+	Requests int           `mapstructure:"requests"` // requests allowed per period
+	Period   time.Duration `mapstructure:"period"`
+	Burst    int           `mapstructure:"burst"`    // requests allowed at once, defaults to requests
+	Key      string        `mapstructure:"key"`      // ip, user, api_key (fall back to user, then ip)
+	UsePlan  bool          `mapstructure:"use_plan"` // authenticated requests get the rate of their plan instead
+}
+
+// RateLimitPlan holds the limits of a plan
+type RateLimitPlan struct {
+	Requests     int           `mapstructure:"requests"` // requests allowed per period
+	Period       time.Duration `mapstructure:"period"`
+	Burst        int           `mapstructure:"burst"`         // requests allowed at once, defaults to requests
+	DailyQuota   int64         `mapstructure:"daily_quota"`   // requests per UTC day, 0 for unlimited
+	MonthlyQuota int64         `mapstructure:"monthly_quota"` // requests per UTC month, 0 for unlimited
+}
+
+// IdempotencyConfig holds Idempotency-Key handling configuration
+type IdempotencyConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Store       string        `mapstructure:"store"`        // database, redis
+	TTL         time.Duration `mapstructure:"ttl"`          // how long responses are replayed
+	LockTimeout time.Duration `mapstructure:"lock_timeout"` // how long a key stays reserved for a request that never completes
+}
+
+// IPFilterConfig holds IP filter configuration. The allow and deny lists of each route
+// group live in the rules file, so they can change without a restart.
+type IPFilterConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	RulesFile      string        `mapstructure:"rules_file"`      // YAML rules by route group
+	GeoIPDatabase  string        `mapstructure:"geoip_database"`  // MaxMind-format .mmdb file for country rules, optional
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // how often the files are checked for changes
+}
+
+// RedisConfig holds Redis connection configuration
+type RedisConfig struct {
+	Addr     string `mapstructure:"addr"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+}
+
 // Load reads configuration from file and environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists (for development)
@@ -111,6 +327,18 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.host", "localhost")
 	v.SetDefault("server.port", "8080")
 	v.SetDefault("server.mode", "debug")
+	v.SetDefault("server.trusted_proxies", []string{"127.0.0.1/8", "::1/128"})
+	v.SetDefault("server.remote_ip_headers", []string{"X-Forwarded-For"})
+	v.SetDefault("server.read_timeout", "10s")
+	v.SetDefault("server.read_header_timeout", "5s")
+	v.SetDefault("server.write_timeout", "30s")
+	v.SetDefault("server.idle_timeout", "60s")
+	v.SetDefault("server.max_header_bytes", 1<<20) // 1MB
+	v.SetDefault("server.max_body_size", 1<<20)    // 1MB
+	v.SetDefault("server.handler_timeout", "15s")
+	// The avatar handler enforces the avatar size, and blob downloads stream
+	v.SetDefault("server.body_limits", map[string]interface{}{"/api/v1/profile/avatar": 0})
+	v.SetDefault("server.handler_timeouts", map[string]interface{}{"/api/v1/files": "0s"})
 
 	// Database defaults
 	v.SetDefault("database.host", "localhost")
@@ -128,11 +356,134 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("logger.level", "debug")
 	v.SetDefault("logger.format", "console")
 	v.SetDefault("logger.disable_gin", false)
+	v.SetDefault("logger.bodies.enabled", false)
+	v.SetDefault("logger.bodies.routes", []string{})
+	v.SetDefault("logger.bodies.max_size", 4096)
+	v.SetDefault("logger.bodies.sample_rate", 1.0)
+	v.SetDefault("logger.bodies.redact_fields", []string{})
+	v.SetDefault("logger.bodies.redact_headers", []string{})
 
 	// CORS defaults
 	v.SetDefault("cors.allowed_origins", []string{"*"})
-	v.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
-	v.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization"})
+	v.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+	v.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization", "If-Match", "If-None-Match", "X-Tenant-ID", "Idempotency-Key", "Content-Encoding"})
+
+	// Security header defaults: JSON responses need no content, framing or embedding
+	v.SetDefault("security.headers.content_security_policy", "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'")
+	v.SetDefault("security.headers.frame_options", "DENY")
+	v.SetDefault("security.headers.referrer_policy", "strict-origin-when-cross-origin")
+	v.SetDefault("security.headers.permissions_policy", "camera=(), microphone=(), geolocation=(), payment=(), usb=()")
+	v.SetDefault("security.headers.cross_origin_opener_policy", "same-origin")
+	v.SetDefault("security.headers.cross_origin_embedder_policy", "require-corp")
+	v.SetDefault("security.headers.cross_origin_resource_policy", "same-origin")
+	// Signed avatar links are embedded by frontends on other origins
+	v.SetDefault("security.routes", map[string]interface{}{
+		"/api/v1/files": map[string]interface{}{"cross_origin_resource_policy": "cross-origin"},
+	})
+	v.SetDefault("security.hsts.enabled", true)
+	v.SetDefault("security.hsts.max_age", "8760h")
+	v.SetDefault("security.hsts.include_subdomains", true)
+	v.SetDefault("security.hsts.preload", false)
+
+	// Compression defaults
+	v.SetDefault("compression.enabled", true)
+	v.SetDefault("compression.encodings", []string{"zstd", "br", "gzip"})
+	v.SetDefault("compression.min_size", 1024)
+	v.SetDefault("compression.max_decompressed_size", 1<<20) // 1MB
+
+	// Maintenance defaults
+	v.SetDefault("maintenance.mode", "off")
+	v.SetDefault("maintenance.state_file", "")
+	v.SetDefault("maintenance.allowed_ips", []string{})
+	v.SetDefault("maintenance.retry_after", "5m")
+	v.SetDefault("maintenance.reload_interval", "10s")
+
+	// Feature flag defaults
+	v.SetDefault("flags.file", "./configs/flags.yaml")
+	v.SetDefault("flags.refresh_interval", "30s")
+
+	// Storage defaults
+	v.SetDefault("storage.driver", "local")
+	v.SetDefault("storage.local_path", "./uploads")
+	v.SetDefault("storage.s3.endpoint", "")
+	v.SetDefault("storage.s3.region", "us-east-1")
+	v.SetDefault("storage.s3.bucket", "")
+	v.SetDefault("storage.s3.access_key_id", "")
+	v.SetDefault("storage.s3.secret_access_key", "")
+	v.SetDefault("storage.s3.use_path_style", false)
+	v.SetDefault("storage.public_url", "")
+	v.SetDefault("storage.url_secret", "")
+	v.SetDefault("storage.url_expiry", "1h")
+	v.SetDefault("storage.max_avatar_size", 5<<20) // 5MB
+
+	// Account defaults
+	v.SetDefault("account.deletion_grace_period", "720h") // 30 days
+	v.SetDefault("account.deletion_check_interval", "1h")
+	v.SetDefault("account.deletion_mode", "soft_delete")
+
+	// Mailer defaults
+	v.SetDefault("mailer.driver", "log")
+	v.SetDefault("mailer.from", "no-reply@localhost")
+	v.SetDefault("mailer.smtp.host", "")
+	v.SetDefault("mailer.smtp.port", "587")
+	v.SetDefault("mailer.smtp.username", "")
+	v.SetDefault("mailer.smtp.password", "")
+
+	// Tenant defaults
+	v.SetDefault("tenant.header", "X-Tenant-ID")
+	v.SetDefault("tenant.base_domain", "")
+	v.SetDefault("tenant.default_slug", "default")
+
+	// Rate limit defaults
+	v.SetDefault("ratelimit.enabled", true)
+	v.SetDefault("ratelimit.store", "memory")
+	v.SetDefault("ratelimit.failure_mode", "open")
+	v.SetDefault("ratelimit.max_keys", 100000)
+	v.SetDefault("ratelimit.policies.api.requests", 100)
+	v.SetDefault("ratelimit.policies.api.period", "1m")
+	v.SetDefault("ratelimit.policies.api.burst", 10)
+	v.SetDefault("ratelimit.policies.api.key", "ip")
+	v.SetDefault("ratelimit.policies.auth.requests", 5)
+	v.SetDefault("ratelimit.policies.auth.period", "1m")
+	v.SetDefault("ratelimit.policies.auth.burst", 5)
+	v.SetDefault("ratelimit.policies.auth.key", "ip")
+	v.SetDefault("ratelimit.policies.protected.requests", 60)
+	v.SetDefault("ratelimit.policies.protected.period", "1m")
+	v.SetDefault("ratelimit.policies.protected.key", "api_key")
+	v.SetDefault("ratelimit.policies.protected.use_plan", true)
+	v.SetDefault("ratelimit.default_plan", "free")
+	v.SetDefault("ratelimit.plans.free.requests", 60)
+	v.SetDefault("ratelimit.plans.free.period", "1m")
+	v.SetDefault("ratelimit.plans.free.burst", 20)
+	v.SetDefault("ratelimit.plans.free.daily_quota", 10000)
+	v.SetDefault("ratelimit.plans.free.monthly_quota", 100000)
+	v.SetDefault("ratelimit.plans.pro.requests", 600)
+	v.SetDefault("ratelimit.plans.pro.period", "1m")
+	v.SetDefault("ratelimit.plans.pro.burst", 100)
+	v.SetDefault("ratelimit.plans.pro.monthly_quota", 5000000)
+	v.SetDefault("ratelimit.plans.admin.requests", 1200)
+	v.SetDefault("ratelimit.plans.admin.period", "1m")
+	v.SetDefault("ratelimit.plans.admin.burst", 200)
+	v.SetDefault("ratelimit.plans.super_admin.requests", 1200)
+	v.SetDefault("ratelimit.plans.super_admin.period", "1m")
+	v.SetDefault("ratelimit.plans.super_admin.burst", 200)
+
+	// Idempotency defaults
+	v.SetDefault("idempotency.enabled", true)
+	v.SetDefault("idempotency.store", "database")
+	v.SetDefault("idempotency.ttl", "24h")
+	v.SetDefault("idempotency.lock_timeout", "1m")
+
+	// IP filter defaults
+	v.SetDefault("ipfilter.enabled", false)
+	v.SetDefault("ipfilter.rules_file", "./configs/ipfilter.yaml")
+	v.SetDefault("ipfilter.geoip_database", "")
+	v.SetDefault("ipfilter.reload_interval", "30s")
+
+	// Redis defaults
+	v.SetDefault("redis.addr", "localhost:6379")
+	v.SetDefault("redis.password", "")
+	v.SetDefault("redis.db", 0)
 }
 
 // validateConfig validates the configuration
@@ -150,6 +501,110 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid server mode: %s (valid options: debug, release, test)", config.Server.Mode)
 	}
 
+	// Validate security headers
+	for prefix := range config.Security.Routes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("security headers route %s must be a path starting with /", prefix)
+		}
+	}
+	if config.Security.HSTS.Enabled && config.Security.HSTS.MaxAge < 0 {
+		return fmt.Errorf("hsts max age cannot be negative")
+	}
+
+	// Validate server timeouts and limits
+	if config.Server.ReadTimeout < 0 || config.Server.ReadHeaderTimeout < 0 || config.Server.WriteTimeout < 0 || config.Server.IdleTimeout < 0 {
+		return fmt.Errorf("server timeouts cannot be negative")
+	}
+	if config.Server.MaxBodySize < 0 || config.Server.HandlerTimeout < 0 {
+		return fmt.Errorf("server max body size and handler timeout cannot be negative")
+	}
+	for prefix, limit := range config.Server.BodyLimits {
+		if !strings.HasPrefix(prefix, "/") || limit < 0 {
+			return fmt.Errorf("body limit %s: must be a path starting with / and a size that isn't negative", prefix)
+		}
+	}
+	for prefix, timeout := range config.Server.HandlerTimeouts {
+		if !strings.HasPrefix(prefix, "/") || timeout < 0 {
+			return fmt.Errorf("handler timeout %s: must be a path starting with / and a duration that isn't negative", prefix)
+		}
+		if config.Server.WriteTimeout > 0 && timeout >= config.Server.WriteTimeout {
+			return fmt.Errorf("handler timeout %s must be shorter than the write timeout", prefix)
+		}
+	}
+	// The timeout response has to be written before the server gives up on the connection
+	if config.Server.WriteTimeout > 0 && config.Server.HandlerTimeout >= config.Server.WriteTimeout {
+		return fmt.Errorf("server handler timeout must be shorter than the write timeout")
+	}
+
+	// Validate compression
+	if config.Compression.Enabled {
+		validEncodings := map[string]bool{
+			"zstd": true, "br": true, "gzip": true,
+		}
+		if len(config.Compression.Encodings) == 0 {
+			return fmt.Errorf("compression encodings cannot be empty")
+		}
+		for _, encoding := range config.Compression.Encodings {
+			if !validEncodings[encoding] {
+				return fmt.Errorf("invalid compression encoding: %s (valid options: zstd, br, gzip)", encoding)
+			}
+		}
+		if config.Compression.MinSize < 0 || config.Compression.MaxDecompressedSize <= 0 {
+			return fmt.Errorf("compression min size cannot be negative and max decompressed size must be positive")
+		}
+	}
+
+	// Validate body logging configuration
+	if config.Logger.Bodies.Enabled {
+		if config.Logger.Bodies.MaxSize <= 0 {
+			return fmt.Errorf("body logging max size must be positive")
+		}
+		if config.Logger.Bodies.SampleRate < 0 || config.Logger.Bodies.SampleRate > 1 {
+			return fmt.Errorf("body logging sample rate must be between 0 and 1")
+		}
+	}
+
+	// Validate maintenance configuration
+	validMaintenanceModes := map[string]bool{
+		"off": true, "read_only": true, "full": true,
+	}
+	if config.Maintenance.Mode != "" && !validMaintenanceModes[config.Maintenance.Mode] {
+		return fmt.Errorf("invalid maintenance mode: %s (valid options: off, read_only, full)", config.Maintenance.Mode)
+	}
+	for _, network := range config.Maintenance.AllowedIPs {
+		if _, err := netip.ParsePrefix(network); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(network); err != nil {
+			return fmt.Errorf("invalid maintenance allowed IP: %s (expected a CIDR or an IP)", network)
+		}
+	}
+	if config.Maintenance.StateFile != "" && config.Maintenance.ReloadInterval <= 0 {
+		return fmt.Errorf("maintenance reload interval must be positive")
+	}
+
+	// Validate feature flag configuration
+	if config.Flags.RefreshInterval < 0 {
+		return fmt.Errorf("feature flag refresh interval cannot be negative")
+	}
+
+	// Validate trusted proxies
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			return fmt.Errorf("invalid trusted proxy: %s (expected a CIDR or an IP)", proxy)
+		}
+	}
+	for _, header := range config.Server.RemoteIPHeaders {
+		switch http.CanonicalHeaderKey(header) {
+		case "X-Forwarded-For", "X-Real-Ip", "Forwarded":
+		default:
+			return fmt.Errorf("invalid remote IP header: %s (valid options: X-Forwarded-For, X-Real-IP, Forwarded)", header)
+		}
+	}
+
 	// Validate database configuration
 	if config.Database.Host == "" {
 		return fmt.Errorf("database host cannot be empty")
@@ -179,9 +634,143 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid log format: %s (valid options: console, json, json-pretty)", config.Logger.Format)
 	}
 
+	// Validate storage driver
+	switch config.Storage.Driver {
+	case "local":
+		if config.Storage.LocalPath == "" {
+			return fmt.Errorf("storage local path cannot be empty")
+		}
+	case "s3":
+		if config.Storage.S3.Endpoint == "" || config.Storage.S3.Bucket == "" {
+			return fmt.Errorf("storage s3 endpoint and bucket must be set")
+		}
+	default:
+		return fmt.Errorf("invalid storage driver: %s (valid options: local, s3)", config.Storage.Driver)
+	}
+
+	// Validate account deletion settings
+	if config.Account.DeletionGracePeriod <= 0 || config.Account.DeletionCheckInterval <= 0 {
+		return fmt.Errorf("account deletion grace period and check interval must be positive")
+	}
+	validDeletionModes := map[string]bool{
+		"soft_delete": true, "anonymize": true,
+	}
+	if !validDeletionModes[config.Account.DeletionMode] {
+		return fmt.Errorf("invalid account deletion mode: %s (valid options: soft_delete, anonymize)", config.Account.DeletionMode)
+	}
+
+	// Validate mailer driver
+	switch config.Mailer.Driver {
+	case "log":
+	case "smtp":
+		if config.Mailer.SMTP.Host == "" || config.Mailer.From == "" {
+			return fmt.Errorf("mailer smtp host and from address must be set")
+		}
+	default:
+		return fmt.Errorf("invalid mailer driver: %s (valid options: log, smtp)", config.Mailer.Driver)
+	}
+
+	// Validate tenant resolution
+	if config.Tenant.Header == "" {
+		return fmt.Errorf("tenant header cannot be empty")
+	}
+
+	// Validate rate limit store
+	if config.RateLimit.Enabled {
+		switch config.RateLimit.Store {
+		case "memory":
+			if config.RateLimit.MaxKeys < 0 {
+				return fmt.Errorf("rate limit max keys cannot be negative")
+			}
+		case "redis":
+			if config.Redis.Addr == "" {
+				return fmt.Errorf("redis address must be set for the redis rate limit store")
+			}
+		default:
+			return fmt.Errorf("invalid rate limit store: %s (valid options: memory, redis)", config.RateLimit.Store)
+		}
+
+		validFailureModes := map[string]bool{
+			"open": true, "closed": true,
+		}
+		if !validFailureModes[config.RateLimit.FailureMode] {
+			return fmt.Errorf("invalid rate limit failure mode: %s (valid options: open, closed)", config.RateLimit.FailureMode)
+		}
+	}
+
+	// Validate rate limit policies
+	validRateLimitKeys := map[string]bool{
+		"ip": true, "user": true, "api_key": true,
+	}
+	for name, policy := range config.RateLimit.Policies {
+		if policy.Requests <= 0 || policy.Period <= 0 || policy.Burst < 0 {
+			return fmt.Errorf("rate limit policy %s: requests and period must be positive and burst cannot be negative", name)
+		}
+		if !validRateLimitKeys[policy.Key] {
+			return fmt.Errorf("rate limit policy %s: invalid key: %s (valid options: ip, user, api_key)", name, policy.Key)
+		}
+	}
+
+	// Validate rate limit plans
+	for name, plan := range config.RateLimit.Plans {
+		if plan.Requests <= 0 || plan.Period <= 0 || plan.Burst < 0 {
+			return fmt.Errorf("rate limit plan %s: requests and period must be positive and burst cannot be negative", name)
+		}
+		if plan.DailyQuota < 0 || plan.MonthlyQuota < 0 {
+			return fmt.Errorf("rate limit plan %s: quotas cannot be negative", name)
+		}
+	}
+	if config.RateLimit.DefaultPlan != "" {
+		if _, ok := config.RateLimit.Plans[config.RateLimit.DefaultPlan]; !ok {
+			return fmt.Errorf("default rate limit plan %s is not configured", config.RateLimit.DefaultPlan)
+		}
+	}
+
+	// Validate idempotency
+	if config.Idempotency.Enabled {
+		switch config.Idempotency.Store {
+		case "database":
+		case "redis":
+			if config.Redis.Addr == "" {
+				return fmt.Errorf("redis address must be set for the redis idempotency store")
+			}
+		default:
+			return fmt.Errorf("invalid idempotency store: %s (valid options: database, redis)", config.Idempotency.Store)
+		}
+
+		if config.Idempotency.TTL <= 0 || config.Idempotency.LockTimeout <= 0 {
+			return fmt.Errorf("idempotency ttl and lock timeout must be positive")
+		}
+	}
+
+	// Validate IP filter
+	if config.IPFilter.Enabled {
+		if config.IPFilter.RulesFile == "" {
+			return fmt.Errorf("ip filter rules file cannot be empty")
+		}
+		if config.IPFilter.ReloadInterval <= 0 {
+			return fmt.Errorf("ip filter reload interval must be positive")
+		}
+	}
+
 	return nil
 }
 
+// Plan returns the name and limits of the plan that applies to a user with the given
+// role and plan. It returns false if no plan applies.
+func (c RateLimitConfig) Plan(role, plan string) (string, RateLimitPlan, bool) {
+	for _, name := range []string{role, plan, c.DefaultPlan} {
+		if name == "" {
+			continue
+		}
+		if limits, ok := c.Plans[name]; ok {
+			return name, limits, true
+		}
+	}
+
+	return "", RateLimitPlan{}, false
+}
+
 // GetDatabaseDSN returns the database connection string
 func (c *Config) GetDatabaseDSN() string {
 	return fmt.Sprintf(
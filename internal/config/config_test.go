@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -32,6 +33,43 @@ func TestLoad(t *testing.T) {
 		t.Errorf("Expected database name test_db, got %s", config.Database.Name)
 	}
 
+	// Test default rate limit policies
+	if policy := config.RateLimit.Policies["auth"]; policy.Requests != 5 || policy.Period != time.Minute {
+		t.Errorf("Expected auth rate limit of 5 per minute, got %d per %s", policy.Requests, policy.Period)
+	}
+
+	// Test default body limit and handler timeout overrides
+	if limit, ok := config.Server.BodyLimits["/api/v1/profile/avatar"]; !ok || limit != 0 {
+		t.Errorf("Expected the avatar upload to have no body limit, got %v", config.Server.BodyLimits)
+	}
+	if timeout, ok := config.Server.HandlerTimeouts["/api/v1/files"]; !ok || timeout != 0 {
+		t.Errorf("Expected file downloads to have no handler timeout, got %v", config.Server.HandlerTimeouts)
+	}
+	if config.Server.HandlerTimeout != 15*time.Second || config.Server.WriteTimeout != 30*time.Second {
+		t.Errorf("Expected a 15s handler timeout within a 30s write timeout, got %s and %s", config.Server.HandlerTimeout, config.Server.WriteTimeout)
+	}
+
+	// Test default maintenance and feature flag settings
+	if config.Maintenance.Mode != "off" || config.Flags.RefreshInterval != 30*time.Second {
+		t.Errorf("Expected maintenance off and flags refreshed every 30s, got %q and %s", config.Maintenance.Mode, config.Flags.RefreshInterval)
+	}
+
+	// Test default security header overrides
+	if route, ok := config.Security.Routes["/api/v1/files"]; !ok || route.CrossOriginResourcePolicy != "cross-origin" {
+		t.Errorf("Expected signed file links to allow cross-origin embedding, got %+v", config.Security.Routes)
+	}
+
+	// Test plan resolution: role plans win over the user's plan, unknown plans fall back to the default
+	if name, _, _ := config.RateLimit.Plan("admin", "pro"); name != "admin" {
+		t.Errorf("Expected the admin plan for admins, got %s", name)
+	}
+	if name, plan, _ := config.RateLimit.Plan("user", "pro"); name != "pro" || plan.DailyQuota != 0 {
+		t.Errorf("Expected the pro plan without a daily quota, got %s with %d", name, plan.DailyQuota)
+	}
+	if name, plan, _ := config.RateLimit.Plan("user", "unknown"); name != "free" || plan.DailyQuota != 10000 {
+		t.Errorf("Expected the free plan with a daily quota of 10000, got %s with %d", name, plan.DailyQuota)
+	}
+
 	// Test helper methods
 	dsn := config.GetDatabaseDSN()
 	if dsn == "" {
@@ -53,20 +91,93 @@ func TestValidation(t *testing.T) {
 	}{
 		{
 			name: "valid config",
+			config: Config{
+				Server:    ServerConfig{Port: "8080", Mode: "debug", TrustedProxies: []string{"172.16.0.0/12", "127.0.0.1"}},
+				Database:  DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:       JWTConfig{Secret: "valid-secret"},
+				Logger:    LoggerConfig{Level: "info", Format: "console"},
+				Storage:   StorageConfig{Driver: "local", LocalPath: "./uploads"},
+				Account:   AccountConfig{DeletionGracePeriod: 720 * time.Hour, DeletionCheckInterval: time.Hour, DeletionMode: "soft_delete"},
+				Mailer:    MailerConfig{Driver: "log"},
+				Tenant:    TenantConfig{Header: "X-Tenant-ID", DefaultSlug: "default"},
+				RateLimit: RateLimitConfig{Enabled: true, Store: "redis", FailureMode: "closed"},
+				Redis:     RedisConfig{Addr: "localhost:6379"},
+
+				Idempotency: IdempotencyConfig{Enabled: true, Store: "redis", TTL: 24 * time.Hour, LockTimeout: time.Minute},
+				IPFilter:    IPFilterConfig{Enabled: true, RulesFile: "./configs/ipfilter.yaml", ReloadInterval: 30 * time.Second},
+				Compression: CompressionConfig{Enabled: true, Encodings: []string{"zstd", "br", "gzip"}, MinSize: 1024, MaxDecompressedSize: 1 << 20},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid JWT secret",
 			config: Config{
 				Server:   ServerConfig{Port: "8080", Mode: "debug"},
 				Database: DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:      JWTConfig{Secret: "change-this-in-production"},
+				Logger:   LoggerConfig{Level: "info", Format: "console"},
+			},
+			expectError: true,
+		},
+		{
+			name: "handler timeout beyond the write timeout",
+			config: Config{
+				Server:   ServerConfig{Port: "8080", Mode: "debug", WriteTimeout: 10 * time.Second, HandlerTimeout: 10 * time.Second},
+				Database: DatabaseConfig{Host: "localhost", Name: "test"},
 				JWT:      JWTConfig{Secret: "valid-secret"},
 				Logger:   LoggerConfig{Level: "info", Format: "console"},
 			},
-			expectError: false,
+			expectError: true,
 		},
 		{
-			name: "invalid JWT secret",
+			name: "unsupported compression encoding",
+			config: Config{
+				Server:      ServerConfig{Port: "8080", Mode: "debug"},
+				Database:    DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:         JWTConfig{Secret: "valid-secret"},
+				Logger:      LoggerConfig{Level: "info", Format: "console"},
+				Compression: CompressionConfig{Enabled: true, Encodings: []string{"gzip", "deflate"}, MaxDecompressedSize: 1 << 20},
+			},
+			expectError: true,
+		},
+		{
+			name: "body logging sample rate above 1",
 			config: Config{
 				Server:   ServerConfig{Port: "8080", Mode: "debug"},
 				Database: DatabaseConfig{Host: "localhost", Name: "test"},
-				JWT:      JWTConfig{Secret: "change-this-in-production"},
+				JWT:      JWTConfig{Secret: "valid-secret"},
+				Logger: LoggerConfig{Level: "info", Format: "console",
+					Bodies: BodyLogConfig{Enabled: true, MaxSize: 4096, SampleRate: 10}},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid maintenance mode",
+			config: Config{
+				Server:      ServerConfig{Port: "8080", Mode: "debug"},
+				Database:    DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:         JWTConfig{Secret: "valid-secret"},
+				Logger:      LoggerConfig{Level: "info", Format: "console"},
+				Maintenance: MaintenanceConfig{Mode: "closed"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid trusted proxy",
+			config: Config{
+				Server:   ServerConfig{Port: "8080", Mode: "debug", TrustedProxies: []string{"10.0.0.0/8", "nginx"}},
+				Database: DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:      JWTConfig{Secret: "valid-secret"},
+				Logger:   LoggerConfig{Level: "info", Format: "console"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid remote IP header",
+			config: Config{
+				Server:   ServerConfig{Port: "8080", Mode: "debug", RemoteIPHeaders: []string{"forwarded", "X-Client-IP"}},
+				Database: DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:      JWTConfig{Secret: "valid-secret"},
 				Logger:   LoggerConfig{Level: "info", Format: "console"},
 			},
 			expectError: true,
@@ -81,6 +192,64 @@ func TestValidation(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "invalid storage driver",
+			config: Config{
+				Server:   ServerConfig{Port: "8080", Mode: "debug"},
+				Database: DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:      JWTConfig{Secret: "valid-secret"},
+				Logger:   LoggerConfig{Level: "info", Format: "console"},
+				Storage:  StorageConfig{Driver: "ftp"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid account deletion mode",
+			config: Config{
+				Server:   ServerConfig{Port: "8080", Mode: "debug"},
+				Database: DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:      JWTConfig{Secret: "valid-secret"},
+				Logger:   LoggerConfig{Level: "info", Format: "console"},
+				Storage:  StorageConfig{Driver: "local", LocalPath: "./uploads"},
+				Account:  AccountConfig{DeletionGracePeriod: 720 * time.Hour, DeletionCheckInterval: time.Hour, DeletionMode: "shred"},
+				Mailer:   MailerConfig{Driver: "log"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid rate limit policy key",
+			config: Config{
+				Server:   ServerConfig{Port: "8080", Mode: "debug"},
+				Database: DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:      JWTConfig{Secret: "valid-secret"},
+				Logger:   LoggerConfig{Level: "info", Format: "console"},
+				Storage:  StorageConfig{Driver: "local", LocalPath: "./uploads"},
+				Account:  AccountConfig{DeletionGracePeriod: 720 * time.Hour, DeletionCheckInterval: time.Hour, DeletionMode: "soft_delete"},
+				Mailer:   MailerConfig{Driver: "log"},
+				Tenant:   TenantConfig{Header: "X-Tenant-ID", DefaultSlug: "default"},
+				RateLimit: RateLimitConfig{Enabled: true, Store: "memory", FailureMode: "open", Policies: map[string]RateLimitPolicy{
+					"api": {Requests: 100, Period: time.Minute, Key: "session"},
+				}},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown default rate limit plan",
+			config: Config{
+				Server:   ServerConfig{Port: "8080", Mode: "debug"},
+				Database: DatabaseConfig{Host: "localhost", Name: "test"},
+				JWT:      JWTConfig{Secret: "valid-secret"},
+				Logger:   LoggerConfig{Level: "info", Format: "console"},
+				Storage:  StorageConfig{Driver: "local", LocalPath: "./uploads"},
+				Account:  AccountConfig{DeletionGracePeriod: 720 * time.Hour, DeletionCheckInterval: time.Hour, DeletionMode: "soft_delete"},
+				Mailer:   MailerConfig{Driver: "log"},
+				Tenant:   TenantConfig{Header: "X-Tenant-ID", DefaultSlug: "default"},
+				RateLimit: RateLimitConfig{Enabled: true, Store: "memory", FailureMode: "open", DefaultPlan: "basic", Plans: map[string]RateLimitPlan{
+					"free": {Requests: 60, Period: time.Minute},
+				}},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler handles self-service account deletion HTTP requests
+type AccountHandler struct {
+	accountService *service.AccountService
+	logger         *logger.Logger
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *service.AccountService, logger *logger.Logger) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		logger:         logger,
+	}
+}
+
+// DeleteAccount schedules the current user's account for deletion after the grace period
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req model.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid delete account request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	pending, err := h.accountService.ScheduleDeletion(c.Request.Context(), userID, &req)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to schedule account deletion")
+
+		switch err.Error() {
+		case "password is incorrect":
+			response.Error(c, http.StatusBadRequest, "INCORRECT_PASSWORD", "Password is incorrect")
+		case "deletion already scheduled":
+			response.Error(c, http.StatusConflict, "DELETION_ALREADY_SCHEDULED", "Account deletion is already scheduled")
+		case "user not found":
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		default:
+			response.InternalServerError(c, "Failed to schedule account deletion")
+		}
+		return
+	}
+
+	response.Accepted(c, "Account scheduled for deletion", pending)
+}
+
+// CancelDeletion cancels the current user's pending account deletion
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if err := h.accountService.CancelDeletion(c.Request.Context(), userID); err != nil {
+		h.logger.WithError(err).Warn("Failed to cancel account deletion")
+
+		switch err.Error() {
+		case "no deletion scheduled":
+			response.Error(c, http.StatusConflict, "NO_DELETION_SCHEDULED", "No account deletion is scheduled")
+		case "user not found":
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		default:
+			response.InternalServerError(c, "Failed to cancel account deletion")
+		}
+		return
+	}
+
+	response.Success(c, "Account deletion cancelled", nil)
+}
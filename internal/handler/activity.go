@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityHandler handles user activity timeline HTTP requests
+type ActivityHandler struct {
+	activityService *service.ActivityService
+	logger          *logger.Logger
+}
+
+// NewActivityHandler creates a new activity handler
+func NewActivityHandler(activityService *service.ActivityService, logger *logger.Logger) *ActivityHandler {
+	return &ActivityHandler{
+		activityService: activityService,
+		logger:          logger,
+	}
+}
+
+// ListUserActivity lists a user's activity timeline with cursor pagination (admin only)
+func (h *ActivityHandler) ListUserActivity(c *gin.Context) {
+	userID := c.Param("id")
+	currentUserRole := c.GetString("user_role")
+
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	page, err := h.activityService.ListUserActivity(c.Request.Context(), userID, c.Query("cursor"), limit, currentUserRole)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list user activity")
+
+		switch err.Error() {
+		case "insufficient permissions to view user activity":
+			response.Error(c, http.StatusForbidden, "INSUFFICIENT_PERMISSIONS", "You don't have permission to view user activity")
+		case "invalid cursor":
+			response.BadRequest(c, "Invalid cursor")
+		case "user not found":
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		default:
+			response.InternalServerError(c, "Failed to list user activity")
+		}
+		return
+	}
+
+	response.Success(c, "User activity retrieved successfully", page)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler handles the current user's API keys
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+	logger        *logger.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService, logger *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		logger:        logger,
+	}
+}
+
+// CreateAPIKey creates an API key for the current user and returns the key, once
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	if !h.requireToken(c) {
+		return
+	}
+
+	var req model.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid create API key request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), &req, c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to create API key")
+		return
+	}
+
+	response.Created(c, "API key created successfully; store the key now, it won't be shown again", key)
+}
+
+// ListAPIKeys lists the current user's API keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to list API keys")
+		return
+	}
+
+	response.Success(c, "API keys retrieved successfully", keys)
+}
+
+// RevokeAPIKey deletes one of the current user's API keys
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if !h.requireToken(c) {
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), c.Param("id"), c.GetString("user_id")); err != nil {
+		h.respondError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	response.Success(c, "API key revoked successfully", nil)
+}
+
+// requireToken rejects requests authenticated with an API key, so a leaked key can't
+// be used to create more keys or revoke the owner's other keys
+func (h *APIKeyHandler) requireToken(c *gin.Context) bool {
+	if c.GetString("api_key_id") == "" {
+		return true
+	}
+
+	response.Error(c, http.StatusForbidden, "API_KEY_NOT_ALLOWED", "API keys cannot be used to manage API keys; sign in instead")
+	return false
+}
+
+// respondError maps API key service errors to HTTP responses
+func (h *APIKeyHandler) respondError(c *gin.Context, err error, fallback string) {
+	h.logger.WithError(err).Warn(fallback)
+
+	switch err.Error() {
+	case "api key not found":
+		response.Error(c, http.StatusNotFound, "API_KEY_NOT_FOUND", "API key not found")
+	case "api key limit reached":
+		response.Error(c, http.StatusConflict, "API_KEY_LIMIT_REACHED", "You have reached the maximum number of API keys; revoke one first")
+	default:
+		response.InternalServerError(c, fallback)
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/dev-mayanktiwari/api-server/pkg/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the allowance for multipart framing on top of the file size
+const multipartOverhead = 64 << 10
+
+// AvatarHandler handles avatar uploads and serves signed blob links
+type AvatarHandler struct {
+	avatarService *service.AvatarService
+	store         storage.BlobStore
+	signer        *storage.URLSigner
+	logger        *logger.Logger
+}
+
+// NewAvatarHandler creates a new avatar handler
+func NewAvatarHandler(avatarService *service.AvatarService, store storage.BlobStore, signer *storage.URLSigner, logger *logger.Logger) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+		store:         store,
+		signer:        signer,
+		logger:        logger,
+	}
+}
+
+// UploadAvatar replaces the current user's avatar with the uploaded image
+// (multipart/form-data, field "avatar")
+func (h *AvatarHandler) UploadAvatar(c *gin.Context) {
+	userID := c.GetString("user_id")
+	maxSize := h.avatarService.MaxSize()
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+
+	file, header, err := c.Request.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.avatarTooLarge(c, maxSize)
+			return
+		}
+
+		response.BadRequest(c, "Request must be multipart/form-data with an \"avatar\" file field")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		h.avatarTooLarge(c, maxSize)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		response.BadRequest(c, "Failed to read avatar file")
+		return
+	}
+
+	user, err := h.avatarService.UploadAvatar(c.Request.Context(), userID, data)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to upload avatar")
+
+		switch err.Error() {
+		case "avatar exceeds maximum size":
+			h.avatarTooLarge(c, maxSize)
+		case "unsupported avatar format":
+			response.Error(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Avatar must be a JPEG, PNG or GIF image")
+		case "invalid avatar image", "avatar dimensions are too large":
+			response.Error(c, http.StatusUnprocessableEntity, "INVALID_IMAGE", "Avatar image is invalid or its dimensions are too large")
+		case "version mismatch":
+			response.Error(c, http.StatusConflict, "CONFLICT", "The profile was modified during the upload, please retry")
+		case "user not found":
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, "AVATAR_UPLOAD_FAILED", "Failed to upload avatar")
+		}
+		return
+	}
+
+	response.SetETag(c, user.Version)
+	response.Success(c, "Avatar uploaded successfully", user)
+}
+
+// DeleteAvatar removes the current user's avatar
+func (h *AvatarHandler) DeleteAvatar(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if err := h.avatarService.DeleteAvatar(c.Request.Context(), userID); err != nil {
+		h.logger.WithError(err).Warn("Failed to delete avatar")
+
+		switch err.Error() {
+		case "avatar not found":
+			response.NotFound(c, "No avatar has been uploaded")
+		case "version mismatch":
+			response.Error(c, http.StatusConflict, "CONFLICT", "The profile was modified concurrently, please retry")
+		case "user not found":
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, "AVATAR_DELETE_FAILED", "Failed to delete avatar")
+		}
+		return
+	}
+
+	response.Success(c, "Avatar deleted successfully", nil)
+}
+
+// ServeFile streams a stored blob if the link's signature is valid and unexpired
+func (h *AvatarHandler) ServeFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	expires := c.Query("expires")
+
+	if err := h.signer.Verify(key, expires, c.Query("signature")); err != nil {
+		response.Error(c, http.StatusForbidden, "INVALID_SIGNATURE", "The link is invalid or has expired")
+		return
+	}
+
+	obj, err := h.store.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			response.NotFound(c, "File not found")
+			return
+		}
+
+		h.logger.WithError(err).WithField("key", key).Error("Failed to read blob")
+		response.InternalServerError(c, "Failed to read file")
+		return
+	}
+	defer obj.Body.Close()
+
+	// Let clients cache the file until the link expires
+	headers := map[string]string{}
+	if expiresAt, err := strconv.ParseInt(expires, 10, 64); err == nil {
+		if maxAge := expiresAt - time.Now().Unix(); maxAge > 0 {
+			headers["Cache-Control"] = "private, max-age=" + strconv.FormatInt(maxAge, 10)
+		}
+	}
+
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, obj.Body, headers)
+}
+
+// avatarTooLarge sends a 413 error response with the size limit
+func (h *AvatarHandler) avatarTooLarge(c *gin.Context, maxSize int64) {
+	response.Error(c, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE",
+		"Avatar must be at most "+strconv.FormatInt(maxSize>>10, 10)+"KB")
+}
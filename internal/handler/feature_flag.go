@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureFlagHandler handles feature flag HTTP requests
+type FeatureFlagHandler struct {
+	flagService *service.FeatureFlagService
+	logger      *logger.Logger
+}
+
+// NewFeatureFlagHandler creates a new feature flag handler
+func NewFeatureFlagHandler(flagService *service.FeatureFlagService, logger *logger.Logger) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		flagService: flagService,
+		logger:      logger,
+	}
+}
+
+// GetMyFlags returns every feature flag evaluated for the current user
+func (h *FeatureFlagHandler) GetMyFlags(c *gin.Context) {
+	response.Success(c, "Feature flags retrieved successfully", middleware.FeatureFlags(c))
+}
+
+// ListFlags lists the feature flags stored in the database (super admin only)
+func (h *FeatureFlagHandler) ListFlags(c *gin.Context) {
+	flags, err := h.flagService.ListFlags(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list feature flags")
+		response.InternalServerError(c, "Failed to list feature flags")
+		return
+	}
+
+	response.Success(c, "Feature flags retrieved successfully", flags)
+}
+
+// GetFlag retrieves a feature flag stored in the database (super admin only)
+func (h *FeatureFlagHandler) GetFlag(c *gin.Context) {
+	flag, err := h.flagService.GetFlag(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		if err.Error() == "feature flag not found" {
+			response.NotFound(c, "Feature flag not found")
+			return
+		}
+
+		h.logger.WithError(err).Error("Failed to get feature flag")
+		response.InternalServerError(c, "Failed to get feature flag")
+		return
+	}
+
+	response.Success(c, "Feature flag retrieved successfully", flag)
+}
+
+// SaveFlag creates or replaces a feature flag (super admin only)
+func (h *FeatureFlagHandler) SaveFlag(c *gin.Context) {
+	key := c.Param("key")
+	currentUserID := c.GetString("user_id")
+
+	var req model.FeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid feature flag request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	flag, err := h.flagService.SaveFlag(c.Request.Context(), key, &req, currentUserID)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to save feature flag")
+
+		if strings.HasPrefix(err.Error(), "invalid feature flag") {
+			response.BadRequest(c, err.Error())
+			return
+		}
+
+		response.InternalServerError(c, "Failed to save feature flag")
+		return
+	}
+
+	response.Success(c, "Feature flag saved successfully", flag)
+}
+
+// DeleteFlag removes a feature flag (super admin only)
+func (h *FeatureFlagHandler) DeleteFlag(c *gin.Context) {
+	key := c.Param("key")
+	currentUserID := c.GetString("user_id")
+
+	if err := h.flagService.DeleteFlag(c.Request.Context(), key, currentUserID); err != nil {
+		h.logger.WithError(err).Warn("Failed to delete feature flag")
+
+		if err.Error() == "feature flag not found" {
+			response.NotFound(c, "Feature flag not found")
+			return
+		}
+
+		response.InternalServerError(c, "Failed to delete feature flag")
+		return
+	}
+
+	response.Success(c, "Feature flag deleted successfully", nil)
+}
@@ -5,32 +5,42 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/dev-mayanktiwari/api-server/pkg/maintenance"
 	"github.com/dev-mayanktiwari/api-server/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
 // HealthHandler handles health-related endpoints
 type HealthHandler struct {
-	startTime time.Time
-	version   string
+	startTime   time.Time
+	version     string
+	maintenance *maintenance.Switch
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(version string) *HealthHandler {
+func NewHealthHandler(version string, maintenance *maintenance.Switch) *HealthHandler {
 	return &HealthHandler{
-		startTime: time.Now(),
-		version:   version,
+		startTime:   time.Now(),
+		version:     version,
+		maintenance: maintenance,
 	}
 }
 
 // HealthResponse represents the health check response structure
 type HealthResponse struct {
-	Status    string            `json:"status"`
-	Version   string            `json:"version"`
-	Timestamp time.Time         `json:"timestamp"`
-	Uptime    string            `json:"uptime"`
-	System    SystemInfo        `json:"system"`
-	Checks    map[string]string `json:"checks"`
+	Status      string            `json:"status"`
+	Version     string            `json:"version"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Uptime      string            `json:"uptime"`
+	System      SystemInfo        `json:"system"`
+	Checks      map[string]string `json:"checks"`
+	Maintenance MaintenanceStatus `json:"maintenance"`
+}
+
+// MaintenanceStatus is the maintenance state shown by the public health checks. Who
+// switched the mode and the message are only shown to super admins.
+type MaintenanceStatus struct {
+	Mode maintenance.Mode `json:"mode"`
 }
 
 // SystemInfo represents system information
@@ -69,6 +79,7 @@ func (h *HealthHandler) Health(c *gin.Context) {
 			"api": "healthy",
 			// We'll add database check later
 		},
+		Maintenance: h.maintenanceStatus(),
 	}
 
 	response.Success(c, "Application is healthy", healthResp)
@@ -101,10 +112,15 @@ func (h *HealthHandler) Ready(c *gin.Context) {
 
 	// TODO: Add other dependency checks (Redis, external APIs, etc.)
 
+	// Maintenance doesn't make the instance unready: taken out of the load balancer,
+	// it couldn't answer with 503 and Retry-After
+	state := h.maintenanceStatus()
+
 	if allReady {
 		response.Success(c, "Application is ready", gin.H{
-			"status": "ready",
-			"checks": checks,
+			"status":      "ready",
+			"checks":      checks,
+			"maintenance": state,
 		})
 	} else {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
@@ -115,14 +131,20 @@ func (h *HealthHandler) Ready(c *gin.Context) {
 				"message": "One or more dependencies are not ready",
 			},
 			"data": gin.H{
-				"status": "not ready",
-				"checks": checks,
+				"status":      "not ready",
+				"checks":      checks,
+				"maintenance": state,
 			},
 			"timestamp": time.Now(),
 		})
 	}
 }
 
+// maintenanceStatus returns the maintenance mode in effect
+func (h *HealthHandler) maintenanceStatus() MaintenanceStatus {
+	return MaintenanceStatus{Mode: h.maintenance.State().Mode}
+}
+
 // Liveness returns the liveness status of the application
 // @Summary Liveness check
 // @Description Get the liveness status of the application (for Kubernetes)
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/maintenance"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceHandler handles maintenance mode HTTP requests
+type MaintenanceHandler struct {
+	maintenance *maintenance.Switch
+	logger      *logger.Logger
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(maintenance *maintenance.Switch, logger *logger.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		maintenance: maintenance,
+		logger:      logger,
+	}
+}
+
+// GetMaintenance returns the maintenance mode in effect (super admin only)
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	response.Success(c, "Maintenance mode retrieved successfully", h.maintenance.State())
+}
+
+// SetMaintenance switches the maintenance mode (super admin only)
+func (h *MaintenanceHandler) SetMaintenance(c *gin.Context) {
+	currentUserID := c.GetString("user_id")
+
+	var req model.MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid maintenance request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	now := time.Now()
+	state := maintenance.State{
+		Mode:      maintenance.Mode(req.Mode),
+		Message:   req.Message,
+		UpdatedAt: &now,
+		UpdatedBy: currentUserID,
+	}
+	if err := h.maintenance.Set(state); err != nil {
+		h.logger.WithError(err).Error("Failed to switch maintenance mode")
+		response.InternalServerError(c, "Failed to switch maintenance mode")
+		return
+	}
+
+	h.logger.LogUserAction(currentUserID, "set_maintenance_mode", "maintenance", map[string]interface{}{
+		"mode":    state.Mode,
+		"message": state.Message,
+	})
+
+	response.Success(c, "Maintenance mode updated successfully", state)
+}
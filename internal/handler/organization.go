@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHandler handles organization (tenant) management HTTP requests
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+	logger     *logger.Logger
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService *service.OrganizationService, logger *logger.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+		logger:     logger,
+	}
+}
+
+// ListOrganizations lists every organization (super admin only)
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.orgService.ListOrganizations()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list organizations")
+		response.InternalServerError(c, "Failed to list organizations")
+		return
+	}
+
+	response.Success(c, "Organizations retrieved successfully", orgs)
+}
+
+// GetOrganization retrieves an organization (super admin only)
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	org, err := h.orgService.GetOrganization(c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get organization")
+		return
+	}
+
+	response.Success(c, "Organization retrieved successfully", org)
+}
+
+// CreateOrganization creates an organization (super admin only)
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req model.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid create organization request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	org, err := h.orgService.CreateOrganization(&req, c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to create organization")
+		return
+	}
+
+	response.Created(c, "Organization created successfully", org)
+}
+
+// UpdateOrganization updates an organization (super admin only)
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	var req model.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid update organization request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	org, err := h.orgService.UpdateOrganization(c.Param("id"), &req, c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to update organization")
+		return
+	}
+
+	response.Success(c, "Organization updated successfully", org)
+}
+
+// respondError maps organization service errors to HTTP responses
+func (h *OrganizationHandler) respondError(c *gin.Context, err error, fallback string) {
+	h.logger.WithError(err).Warn(fallback)
+
+	switch err.Error() {
+	case "organization not found":
+		response.Error(c, http.StatusNotFound, "ORGANIZATION_NOT_FOUND", "Organization not found")
+	case "invalid organization slug":
+		response.BadRequest(c, "Slug must be lowercase letters, digits and hyphens, and cannot start or end with a hyphen")
+	case "organization slug is already taken":
+		response.Error(c, http.StatusConflict, "SLUG_ALREADY_TAKEN", "Organization slug is already taken")
+	default:
+		response.InternalServerError(c, fallback)
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// PreferenceHandler handles user preference and preference schema HTTP requests
+type PreferenceHandler struct {
+	preferenceService *service.PreferenceService
+	logger            *logger.Logger
+}
+
+// NewPreferenceHandler creates a new preference handler
+func NewPreferenceHandler(preferenceService *service.PreferenceService, logger *logger.Logger) *PreferenceHandler {
+	return &PreferenceHandler{
+		preferenceService: preferenceService,
+		logger:            logger,
+	}
+}
+
+// GetPreferences gets the current user's preferences
+func (h *PreferenceHandler) GetPreferences(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	preferences, err := h.preferenceService.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get preferences")
+
+		if err.Error() == "user not found" {
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			return
+		}
+
+		response.InternalServerError(c, "Failed to get preferences")
+		return
+	}
+
+	response.Success(c, "Preferences retrieved successfully", preferences)
+}
+
+// UpdatePreferences replaces the current user's preferences
+func (h *PreferenceHandler) UpdatePreferences(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid update preferences request")
+		response.BadRequest(c, "Request body must be a JSON object")
+		return
+	}
+
+	preferences, err := h.preferenceService.UpdatePreferences(c.Request.Context(), userID, req)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to update preferences")
+
+		switch {
+		case strings.HasPrefix(err.Error(), "unknown preference"):
+			response.Error(c, http.StatusUnprocessableEntity, "UNKNOWN_PREFERENCE", err.Error())
+		case strings.HasPrefix(err.Error(), "invalid preference value"):
+			response.Error(c, http.StatusUnprocessableEntity, "INVALID_PREFERENCE", err.Error())
+		case err.Error() == "version mismatch":
+			response.Error(c, http.StatusConflict, "CONFLICT", "The profile was modified concurrently, please retry")
+		case err.Error() == "user not found":
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, "UPDATE_FAILED", "Failed to update preferences")
+		}
+		return
+	}
+
+	response.Success(c, "Preferences updated successfully", preferences)
+}
+
+// ListSchemas lists every defined preference key (super admin only)
+func (h *PreferenceHandler) ListSchemas(c *gin.Context) {
+	schemas, err := h.preferenceService.ListSchemas()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list preference schemas")
+		response.InternalServerError(c, "Failed to list preference schemas")
+		return
+	}
+
+	response.Success(c, "Preference schemas retrieved successfully", schemas)
+}
+
+// SaveSchema defines or replaces a preference key's schema (super admin only)
+func (h *PreferenceHandler) SaveSchema(c *gin.Context) {
+	key := c.Param("key")
+	currentUserID := c.GetString("user_id")
+
+	var req model.PreferenceSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid preference schema request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	schema, err := h.preferenceService.SaveSchema(key, &req, currentUserID)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to save preference schema")
+
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid preference key"):
+			response.BadRequest(c, err.Error())
+		case strings.HasPrefix(err.Error(), "invalid schema"):
+			response.Error(c, http.StatusUnprocessableEntity, "INVALID_SCHEMA", err.Error())
+		default:
+			response.InternalServerError(c, "Failed to save preference schema")
+		}
+		return
+	}
+
+	response.Success(c, "Preference schema saved successfully", schema)
+}
+
+// DeleteSchema removes a preference key (super admin only)
+func (h *PreferenceHandler) DeleteSchema(c *gin.Context) {
+	key := c.Param("key")
+	currentUserID := c.GetString("user_id")
+
+	if err := h.preferenceService.DeleteSchema(key, currentUserID); err != nil {
+		h.logger.WithError(err).Warn("Failed to delete preference schema")
+
+		if err.Error() == "preference schema not found" {
+			response.NotFound(c, "Preference schema not found")
+			return
+		}
+
+		response.InternalServerError(c, "Failed to delete preference schema")
+		return
+	}
+
+	response.Success(c, "Preference schema deleted successfully", nil)
+}
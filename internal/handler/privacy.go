@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyHandler handles data-subject requests (export and erasure)
+type PrivacyHandler struct {
+	privacyService *service.PrivacyService
+	logger         *logger.Logger
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(privacyService *service.PrivacyService, logger *logger.Logger) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+		logger:         logger,
+	}
+}
+
+// ExportProfileData exports everything stored about the current user
+func (h *PrivacyHandler) ExportProfileData(c *gin.Context) {
+	userID := c.GetString("user_id")
+	h.exportUserData(c, userID)
+}
+
+// EraseProfile anonymizes the current user's personal data
+func (h *PrivacyHandler) EraseProfile(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req model.EraseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid erase account request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	if err := h.privacyService.EraseOwnAccount(c.Request.Context(), userID, &req); err != nil {
+		h.logger.WithError(err).Warn("Failed to erase account")
+
+		if err.Error() == "password is incorrect" {
+			response.Error(c, http.StatusBadRequest, "INCORRECT_PASSWORD", "Password is incorrect")
+			return
+		}
+
+		h.handleEraseError(c, err)
+		return
+	}
+
+	response.Success(c, "Account data erased successfully", nil)
+}
+
+// Admin endpoints
+
+// ExportUserData exports everything stored about a user (admin only)
+func (h *PrivacyHandler) ExportUserData(c *gin.Context) {
+	h.exportUserData(c, c.Param("id"))
+}
+
+// EraseUser anonymizes a user's personal data (admin only)
+func (h *PrivacyHandler) EraseUser(c *gin.Context) {
+	userID := c.Param("id")
+	currentUserID := c.GetString("user_id")
+	currentUserRole := c.GetString("user_role")
+
+	if err := h.privacyService.EraseUser(c.Request.Context(), userID, currentUserID, currentUserRole); err != nil {
+		h.logger.WithError(err).Error("Failed to erase user data")
+
+		if err.Error() == "insufficient permissions to erase user data" {
+			response.Error(c, http.StatusForbidden, "INSUFFICIENT_PERMISSIONS", "You don't have permission to erase user data")
+			return
+		}
+
+		h.handleEraseError(c, err)
+		return
+	}
+
+	response.Success(c, "User data erased successfully", nil)
+}
+
+// exportUserData writes the export as JSON, or as a ZIP archive when format=zip
+func (h *PrivacyHandler) exportUserData(c *gin.Context, userID string) {
+	currentUserID := c.GetString("user_id")
+	currentUserRole := c.GetString("user_role")
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		response.BadRequest(c, "format must be one of: json, zip")
+		return
+	}
+
+	export, err := h.privacyService.ExportUserData(c.Request.Context(), userID, currentUserID, currentUserRole)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to export user data")
+
+		if err.Error() == "insufficient permissions to access user data" {
+			response.Error(c, http.StatusForbidden, "INSUFFICIENT_PERMISSIONS", "You don't have permission to export this user's data")
+			return
+		}
+
+		if err.Error() == "user not found" {
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, "EXPORT_FAILED", "Failed to export user data")
+		return
+	}
+
+	if format == "json" {
+		response.Success(c, "User data exported successfully", export)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-data-%s.zip\"", userID))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+
+	if err := writeExportArchive(c.Writer, export); err != nil {
+		// Headers are already sent, so all we can do is log and abort
+		h.logger.WithError(err).Error("Failed to write user data archive")
+		c.Abort()
+	}
+}
+
+// handleEraseError maps erasure errors shared by the self-service and admin endpoints
+func (h *PrivacyHandler) handleEraseError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+	case "user data already erased":
+		response.Error(c, http.StatusConflict, "ALREADY_ERASED", "User data has already been erased")
+	default:
+		response.Error(c, http.StatusInternalServerError, "ERASE_FAILED", "Failed to erase user data")
+	}
+}
+
+// writeExportArchive writes a ZIP with a manifest and one JSON file per data category
+func writeExportArchive(w http.ResponseWriter, export *model.DataExport) error {
+	zw := zip.NewWriter(w)
+
+	categories := make([]string, 0, len(export.Data))
+	for name := range export.Data {
+		categories = append(categories, name)
+	}
+	sort.Strings(categories)
+
+	manifest := gin.H{
+		"user_id":      export.UserID,
+		"generated_at": export.GeneratedAt,
+		"categories":   categories,
+	}
+	if err := writeZipJSON(zw, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	for _, name := range categories {
+		if err := writeZipJSON(zw, name+".json", export.Data[name]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeZipJSON adds an indented JSON file to the archive
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
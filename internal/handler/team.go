@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// TeamHandler handles team, membership and invitation HTTP requests
+type TeamHandler struct {
+	teamService *service.TeamService
+	logger      *logger.Logger
+}
+
+// NewTeamHandler creates a new team handler
+func NewTeamHandler(teamService *service.TeamService, logger *logger.Logger) *TeamHandler {
+	return &TeamHandler{
+		teamService: teamService,
+		logger:      logger,
+	}
+}
+
+// CreateTeam creates a team owned by the current user
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	currentUserID := c.GetString("user_id")
+
+	var req model.CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid create team request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	team, err := h.teamService.CreateTeam(c.Request.Context(), &req, currentUserID)
+	if err != nil {
+		h.respondError(c, err, "Failed to create team")
+		return
+	}
+
+	response.Created(c, "Team created successfully", team)
+}
+
+// ListTeams lists the teams the current user belongs to
+func (h *TeamHandler) ListTeams(c *gin.Context) {
+	teams, err := h.teamService.ListTeams(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to list teams")
+		return
+	}
+
+	response.Success(c, "Teams retrieved successfully", teams)
+}
+
+// GetTeam retrieves a team (member)
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	team, err := h.teamService.GetTeam(c.Request.Context(), c.Param("team_id"), c.GetString("team_role"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get team")
+		return
+	}
+
+	response.Success(c, "Team retrieved successfully", team)
+}
+
+// UpdateTeam updates a team (admin)
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	var req model.UpdateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid update team request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	team, err := h.teamService.UpdateTeam(c.Request.Context(), c.Param("team_id"), &req, c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to update team")
+		return
+	}
+
+	response.Success(c, "Team updated successfully", team)
+}
+
+// DeleteTeam deletes a team (owner)
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	if err := h.teamService.DeleteTeam(c.Request.Context(), c.Param("team_id"), c.GetString("user_id")); err != nil {
+		h.respondError(c, err, "Failed to delete team")
+		return
+	}
+
+	response.Success(c, "Team deleted successfully", nil)
+}
+
+// ListMembers lists a team's members (member)
+func (h *TeamHandler) ListMembers(c *gin.Context) {
+	members, err := h.teamService.ListMembers(c.Request.Context(), c.Param("team_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to list team members")
+		return
+	}
+
+	response.Success(c, "Team members retrieved successfully", members)
+}
+
+// UpdateMember changes a member's role (admin)
+func (h *TeamHandler) UpdateMember(c *gin.Context) {
+	var req model.UpdateTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid update team member request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	err := h.teamService.UpdateMemberRole(c.Request.Context(), c.Param("team_id"), c.Param("user_id"), &req,
+		c.GetString("user_id"), c.GetString("team_role"))
+	if err != nil {
+		h.respondError(c, err, "Failed to update team member")
+		return
+	}
+
+	response.Success(c, "Team member updated successfully", nil)
+}
+
+// RemoveMember removes a member from a team, or lets the current user leave (member)
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	err := h.teamService.RemoveMember(c.Request.Context(), c.Param("team_id"), c.Param("user_id"),
+		c.GetString("user_id"), c.GetString("team_role"))
+	if err != nil {
+		h.respondError(c, err, "Failed to remove team member")
+		return
+	}
+
+	response.Success(c, "Team member removed successfully", nil)
+}
+
+// TransferOwnership hands the team to another member (owner)
+func (h *TeamHandler) TransferOwnership(c *gin.Context) {
+	var req model.TransferTeamOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid transfer ownership request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	if err := h.teamService.TransferOwnership(c.Request.Context(), c.Param("team_id"), &req, c.GetString("user_id")); err != nil {
+		h.respondError(c, err, "Failed to transfer team ownership")
+		return
+	}
+
+	response.Success(c, "Team ownership transferred successfully", nil)
+}
+
+// InviteMember invites an email address to the team (admin)
+func (h *TeamHandler) InviteMember(c *gin.Context) {
+	var req model.InviteTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid team invitation request")
+		c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(err))
+		return
+	}
+
+	invitation, err := h.teamService.InviteMember(c.Request.Context(), c.Param("team_id"), &req,
+		c.GetString("user_id"), c.GetString("team_role"))
+	if err != nil {
+		h.respondError(c, err, "Failed to invite team member")
+		return
+	}
+
+	response.Created(c, "Invitation sent successfully", invitation)
+}
+
+// ListTeamInvitations lists a team's open invitations (admin)
+func (h *TeamHandler) ListTeamInvitations(c *gin.Context) {
+	invitations, err := h.teamService.ListTeamInvitations(c.Request.Context(), c.Param("team_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to list invitations")
+		return
+	}
+
+	response.Success(c, "Invitations retrieved successfully", invitations)
+}
+
+// RevokeInvitation revokes an open invitation (admin)
+func (h *TeamHandler) RevokeInvitation(c *gin.Context) {
+	err := h.teamService.RevokeInvitation(c.Request.Context(), c.Param("team_id"), c.Param("invitation_id"), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to revoke invitation")
+		return
+	}
+
+	response.Success(c, "Invitation revoked successfully", nil)
+}
+
+// ListMyInvitations lists the open invitations sent to the current user
+func (h *TeamHandler) ListMyInvitations(c *gin.Context) {
+	invitations, err := h.teamService.ListMyInvitations(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to list invitations")
+		return
+	}
+
+	response.Success(c, "Invitations retrieved successfully", invitations)
+}
+
+// AcceptInvitation joins the team of an invitation sent to the current user
+func (h *TeamHandler) AcceptInvitation(c *gin.Context) {
+	if err := h.teamService.RespondToInvitation(c.Request.Context(), c.Param("id"), true, c.GetString("user_id")); err != nil {
+		h.respondError(c, err, "Failed to accept invitation")
+		return
+	}
+
+	response.Success(c, "Invitation accepted successfully", nil)
+}
+
+// DeclineInvitation declines an invitation sent to the current user
+func (h *TeamHandler) DeclineInvitation(c *gin.Context) {
+	if err := h.teamService.RespondToInvitation(c.Request.Context(), c.Param("id"), false, c.GetString("user_id")); err != nil {
+		h.respondError(c, err, "Failed to decline invitation")
+		return
+	}
+
+	response.Success(c, "Invitation declined successfully", nil)
+}
+
+// respondError maps team service errors to HTTP responses
+func (h *TeamHandler) respondError(c *gin.Context, err error, fallback string) {
+	h.logger.WithError(err).Warn(fallback)
+
+	switch err.Error() {
+	case "team not found":
+		response.Error(c, http.StatusNotFound, "TEAM_NOT_FOUND", "Team not found")
+	case "membership not found":
+		response.Error(c, http.StatusNotFound, "TEAM_MEMBER_NOT_FOUND", "Team member not found")
+	case "invitation not found":
+		response.Error(c, http.StatusNotFound, "INVITATION_NOT_FOUND", "Invitation not found")
+	case "user not found":
+		response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+	case "team name is required":
+		response.BadRequest(c, "Team name is required")
+	case "invalid team role":
+		response.Error(c, http.StatusBadRequest, "INVALID_TEAM_ROLE", "Team role must be one of member, admin or owner")
+	case "insufficient team role":
+		response.Error(c, http.StatusForbidden, "INSUFFICIENT_TEAM_ROLE", "Your team role doesn't allow this change")
+	case "cannot change your own team role":
+		response.Error(c, http.StatusBadRequest, "CANNOT_CHANGE_OWN_ROLE", "You cannot change your own team role")
+	case "cannot transfer ownership to yourself":
+		response.BadRequest(c, "You cannot transfer ownership to yourself")
+	case "team must have at least one owner":
+		response.Error(c, http.StatusConflict, "LAST_TEAM_OWNER", "A team must have at least one owner; transfer ownership first")
+	case "user is already a team member":
+		response.Error(c, http.StatusConflict, "ALREADY_TEAM_MEMBER", "User is already a team member")
+	case "invitation already pending":
+		response.Error(c, http.StatusConflict, "INVITATION_PENDING", "An invitation is already pending for this email")
+	case "invitation is no longer pending":
+		response.Error(c, http.StatusConflict, "INVITATION_CLOSED", "Invitation has expired or was already answered")
+	default:
+		response.InternalServerError(c, fallback)
+	}
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/dev-mayanktiwari/api-server/internal/service"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler handles request usage HTTP requests
+type UsageHandler struct {
+	usageService *service.UsageService
+	logger       *logger.Logger
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(usageService *service.UsageService, logger *logger.Logger) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+		logger:       logger,
+	}
+}
+
+// GetUsage reports the current user's plan and how much of its quotas they have used
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	usage, err := h.usageService.GetUsage(c.Request.Context(), c.GetString("user_id"), c.GetString("user_role"), c.GetString("user_plan"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get usage")
+		response.InternalServerError(c, "Failed to get usage")
+		return
+	}
+
+	response.Success(c, "Usage retrieved successfully", usage)
+}
@@ -1,27 +1,35 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dev-mayanktiwari/api-server/internal/middleware"
 	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
 	"github.com/dev-mayanktiwari/api-server/internal/service"
 	"github.com/dev-mayanktiwari/api-server/pkg/logger"
 	"github.com/dev-mayanktiwari/api-server/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 // UserHandler handles user-related HTTP requests
 type UserHandler struct {
 	userService *service.UserService
+	expanders   response.Expanders // Relations clients can embed with ?expand=
 	logger      *logger.Logger
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(userService *service.UserService, logger *logger.Logger) *UserHandler {
+func NewUserHandler(userService *service.UserService, expanders response.Expanders, logger *logger.Logger) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		expanders:   expanders,
 		logger:      logger,
 	}
 }
@@ -36,7 +44,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to create user")
 
@@ -63,7 +71,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	loginResponse, err := h.userService.Login(&req)
+	loginResponse, err := h.userService.Login(c.Request.Context(), &req, middleware.ClientIP(c))
 	if err != nil {
 		h.logger.WithError(err).Warn("Login failed")
 
@@ -79,14 +87,14 @@ func (h *UserHandler) Login(c *gin.Context) {
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get user profile")
 		response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
 		return
 	}
 
-	response.Success(c, "Profile retrieved successfully", user)
+	h.respondShaped(c, "Profile retrieved successfully", user)
 }
 
 // UpdateProfile updates the current user's profile
@@ -94,6 +102,13 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID := c.GetString("user_id")
 	userRole := c.GetString("user_role")
 
+	// If-Match is optional for the caller's own profile
+	expectedVersion, err := response.ParseIfMatch(c)
+	if err != nil {
+		response.PreconditionFailed(c, err.Error())
+		return
+	}
+
 	var req model.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.WithError(err).Warn("Invalid update profile request")
@@ -101,10 +116,15 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(userID, &req, userID, userRole)
+	user, err := h.userService.UpdateUser(c.Request.Context(), userID, &req, expectedVersion, userID, userRole)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to update user profile")
 
+		if err.Error() == "version mismatch" {
+			response.PreconditionFailed(c, "The profile has been modified since it was last retrieved")
+			return
+		}
+
 		if err.Error() == "email is already taken" {
 			response.Error(c, http.StatusConflict, "EMAIL_ALREADY_TAKEN", "This email is already taken")
 			return
@@ -114,9 +134,16 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	response.SetETag(c, user.Version)
 	response.Success(c, "Profile updated successfully", user)
 }
 
+// PatchProfile partially updates the current user's profile using JSON Merge Patch or JSON Patch
+func (h *UserHandler) PatchProfile(c *gin.Context) {
+	userID := c.GetString("user_id")
+	h.patchUser(c, userID, false)
+}
+
 // ChangePassword changes the current user's password
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -128,7 +155,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.ChangePassword(userID, &req)
+	err := h.userService.ChangePassword(c.Request.Context(), userID, &req)
 	if err != nil {
 		h.logger.WithError(err).Warn("Failed to change password")
 
@@ -150,14 +177,14 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 func (h *UserHandler) GetUser(c *gin.Context) {
 	userID := c.Param("id")
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get user")
 		response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
 		return
 	}
 
-	response.Success(c, "User retrieved successfully", user)
+	h.respondShaped(c, "User retrieved successfully", user)
 }
 
 // UpdateUser updates a user (admin only)
@@ -166,6 +193,18 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	currentUserID := c.GetString("user_id")
 	currentUserRole := c.GetString("user_role")
 
+	// Admins must send If-Match so concurrent edits can't overwrite each other
+	if c.GetHeader("If-Match") == "" {
+		response.PreconditionRequired(c, "If-Match header is required when updating a user")
+		return
+	}
+
+	expectedVersion, err := response.ParseIfMatch(c)
+	if err != nil {
+		response.PreconditionFailed(c, err.Error())
+		return
+	}
+
 	var req model.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.WithError(err).Warn("Invalid update user request")
@@ -173,10 +212,15 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(userID, &req, currentUserID, currentUserRole)
+	user, err := h.userService.UpdateUser(c.Request.Context(), userID, &req, expectedVersion, currentUserID, currentUserRole)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to update user")
 
+		if err.Error() == "version mismatch" {
+			response.PreconditionFailed(c, "The user has been modified since it was last retrieved")
+			return
+		}
+
 		if err.Error() == "insufficient permissions to update user" {
 			response.Error(c, http.StatusForbidden, "INSUFFICIENT_PERMISSIONS", "You don't have permission to update this user")
 			return
@@ -187,10 +231,89 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 			return
 		}
 
+		if strings.HasPrefix(err.Error(), "invalid plan") {
+			response.Error(c, http.StatusUnprocessableEntity, "INVALID_PLAN", err.Error())
+			return
+		}
+
 		response.Error(c, http.StatusInternalServerError, "UPDATE_FAILED", "Failed to update user")
 		return
 	}
 
+	response.SetETag(c, user.Version)
+	response.Success(c, "User updated successfully", user)
+}
+
+// PatchUser partially updates a user using JSON Merge Patch or JSON Patch (admin only)
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	h.patchUser(c, c.Param("id"), true)
+}
+
+// patchUser applies the request's patch document to a user. Admin requests must send If-Match.
+func (h *UserHandler) patchUser(c *gin.Context, userID string, requireIfMatch bool) {
+	currentUserID := c.GetString("user_id")
+	currentUserRole := c.GetString("user_role")
+
+	if requireIfMatch && c.GetHeader("If-Match") == "" {
+		response.PreconditionRequired(c, "If-Match header is required when updating a user")
+		return
+	}
+
+	expectedVersion, err := response.ParseIfMatch(c)
+	if err != nil {
+		response.PreconditionFailed(c, err.Error())
+		return
+	}
+
+	mediaType := c.ContentType()
+	if mediaType != service.MergePatchMediaType && mediaType != service.JSONPatchMediaType {
+		c.Header("Accept-Patch", service.MergePatchMediaType+", "+service.JSONPatchMediaType)
+		response.Error(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Content-Type must be application/merge-patch+json or application/json-patch+json")
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.BadRequest(c, "Failed to read request body")
+		return
+	}
+
+	user, err := h.userService.PatchUser(c.Request.Context(), userID, mediaType, patch, expectedVersion, currentUserID, currentUserRole)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to patch user")
+
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, middleware.HandleValidationErrors(validationErrs))
+			return
+		}
+
+		switch {
+		case err.Error() == "insufficient permissions to update user":
+			response.Error(c, http.StatusForbidden, "INSUFFICIENT_PERMISSIONS", "You don't have permission to update this user")
+		case err.Error() == "version mismatch":
+			response.PreconditionFailed(c, "The user has been modified since it was last retrieved")
+		case err.Error() == "email is already taken":
+			response.Error(c, http.StatusConflict, "EMAIL_ALREADY_TAKEN", "This email is already taken")
+		case err.Error() == "patch test failed":
+			response.Error(c, http.StatusConflict, "PATCH_TEST_FAILED", "A test operation in the patch did not match")
+		case strings.HasPrefix(err.Error(), "field is not patchable"):
+			response.Error(c, http.StatusUnprocessableEntity, "FIELD_NOT_PATCHABLE", err.Error())
+		case strings.HasPrefix(err.Error(), "invalid patch document"):
+			response.Error(c, http.StatusBadRequest, "INVALID_PATCH", err.Error())
+		case strings.HasPrefix(err.Error(), "invalid role"):
+			response.Error(c, http.StatusUnprocessableEntity, "INVALID_ROLE", err.Error())
+		case strings.HasPrefix(err.Error(), "invalid plan"):
+			response.Error(c, http.StatusUnprocessableEntity, "INVALID_PLAN", err.Error())
+		case strings.HasSuffix(err.Error(), "user not found"):
+			response.Error(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, "UPDATE_FAILED", "Failed to update user")
+		}
+		return
+	}
+
+	response.SetETag(c, user.Version)
 	response.Success(c, "User updated successfully", user)
 }
 
@@ -200,7 +323,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	currentUserID := c.GetString("user_id")
 	currentUserRole := c.GetString("user_role")
 
-	err := h.userService.DeleteUser(userID, currentUserID, currentUserRole)
+	err := h.userService.DeleteUser(c.Request.Context(), userID, currentUserID, currentUserRole)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to delete user")
 
@@ -246,7 +369,29 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		}
 	}
 
-	users, total, err := h.userService.ListUsers(page, limit, currentUserRole)
+	// Filter to accounts without a login in the last N days
+	filter := repository.UserFilter{}
+	if daysStr := c.Query("inactive_days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 {
+			response.Error(c, http.StatusBadRequest, "INVALID_FILTER", "inactive_days must be a positive integer")
+			return
+		}
+		since := time.Now().UTC().AddDate(0, 0, -days)
+		filter.InactiveSince = &since
+	}
+
+	// Preference filters are passed as pref.<key>=<value>
+	for param, values := range c.Request.URL.Query() {
+		if key, ok := strings.CutPrefix(param, "pref."); ok && len(values) > 0 {
+			if filter.Preferences == nil {
+				filter.Preferences = map[string]string{}
+			}
+			filter.Preferences[key] = values[0]
+		}
+	}
+
+	users, total, err := h.userService.ListUsers(c.Request.Context(), page, limit, filter, currentUserRole)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to list users")
 
@@ -255,15 +400,27 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 			return
 		}
 
+		if strings.HasPrefix(err.Error(), "preference is not filterable") {
+			response.Error(c, http.StatusBadRequest, "INVALID_FILTER", "Only indexed preference keys can be used as filters: "+strings.TrimPrefix(err.Error(), "preference is not filterable: "))
+			return
+		}
+
 		response.Error(c, http.StatusInternalServerError, "LIST_FAILED", "Failed to list users")
 		return
 	}
 
+	shaped, err := response.ParseShape(c).Apply(c.Request.Context(), users, h.expanders)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to shape users")
+		response.ShapeFailed(c, err)
+		return
+	}
+
 	// Calculate pagination info
 	totalPages := (int(total) + limit - 1) / limit
 
 	response.Success(c, "Users retrieved successfully", gin.H{
-		"users": users,
+		"users": shaped,
 		"pagination": gin.H{
 			"current_page": page,
 			"total_pages":  totalPages,
@@ -272,3 +429,52 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		},
 	})
 }
+
+// SearchUsers ranks users by how well their name or email matches the q parameter (admin only)
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	results, err := h.userService.SearchUsers(c.Request.Context(), c.Query("q"), limit, c.GetString("user_role"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search users")
+
+		switch err.Error() {
+		case "insufficient permissions to search users":
+			response.Error(c, http.StatusForbidden, "INSUFFICIENT_PERMISSIONS", "You don't have permission to search users")
+		case "search query is too short":
+			response.Error(c, http.StatusBadRequest, "INVALID_SEARCH_QUERY", "Search query must be at least 2 characters")
+		default:
+			response.Error(c, http.StatusInternalServerError, "SEARCH_FAILED", "Failed to search users")
+		}
+		return
+	}
+
+	response.Success(c, "Users retrieved successfully", gin.H{
+		"query":   c.Query("q"),
+		"results": results,
+	})
+}
+
+// respondShaped sends a user shaped by the fields and expand query parameters. The
+// version ETag only covers the user itself, so it isn't used when relations are expanded.
+func (h *UserHandler) respondShaped(c *gin.Context, message string, user *model.SafeUser) {
+	shape := response.ParseShape(c)
+
+	shaped, err := shape.Apply(c.Request.Context(), user, h.expanders)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to shape user")
+		response.ShapeFailed(c, err)
+		return
+	}
+
+	if len(shape.Expand) == 0 && response.NotModified(c, user.Version) {
+		return
+	}
+
+	response.Success(c, message, shaped)
+}
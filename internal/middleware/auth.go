@@ -1,18 +1,35 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/dev-mayanktiwari/api-server/internal/model"
 	"github.com/dev-mayanktiwari/api-server/pkg/auth"
 	"github.com/dev-mayanktiwari/api-server/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware creates JWT authentication middleware
-func AuthMiddleware(jwtManager *auth.JWTManager, logger *logger.Logger) gin.HandlerFunc {
+// APIKeyHeader is the request header clients send an API key in
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates API keys. It returns the claims of the key's user,
+// with the key ID as the claims' ID.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*auth.Claims, error)
+}
+
+// AuthMiddleware creates authentication middleware accepting a JWT in the Authorization
+// header or, if apiKeys is set, an API key in the X-API-Key header
+func AuthMiddleware(jwtManager *auth.JWTManager, apiKeys APIKeyAuthenticator, logger *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" && apiKeys != nil {
+			authenticateAPIKey(c, apiKeys, key, logger)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			logger.WithRequestID(c.GetString("request_id")).
@@ -75,10 +92,7 @@ func AuthMiddleware(jwtManager *auth.JWTManager, logger *logger.Logger) gin.Hand
 		}
 
 		// Set user information in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
-		c.Set("jwt_claims", claims)
+		setAuthContext(c, claims)
 
 		// Log successful authentication
 		logger.WithRequestID(c.GetString("request_id")).
@@ -121,15 +135,63 @@ func OptionalAuthMiddleware(jwtManager *auth.JWTManager, logger *logger.Logger)
 		}
 
 		// Set user information in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
-		c.Set("jwt_claims", claims)
+		setAuthContext(c, claims)
 
 		c.Next()
 	}
 }
 
+// authenticateAPIKey authenticates a request by API key. The key's claims are stored
+// like a token's, so later middleware doesn't need to tell the two apart.
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, key string, logger *logger.Logger) {
+	claims, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), key)
+	if err != nil {
+		logger.WithRequestID(c.GetString("request_id")).
+			WithError(err).
+			Warn("Invalid API key")
+
+		status, message := http.StatusInternalServerError, "Failed to authenticate the API key"
+		switch err.Error() {
+		case "invalid api key", "api key has expired", "account is deactivated":
+			status, message = http.StatusUnauthorized, "The provided API key is invalid, expired or revoked"
+		}
+
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "Invalid API key",
+			"error": gin.H{
+				"code":    "INVALID_API_KEY",
+				"message": message,
+			},
+			"timestamp":  time.Now(),
+			"request_id": c.GetString("request_id"),
+		})
+		c.Abort()
+		return
+	}
+
+	setAuthContext(c, claims)
+	c.Set("api_key_id", claims.ID)
+
+	logger.WithRequestID(c.GetString("request_id")).
+		WithFields(map[string]interface{}{
+			"user_id":    claims.UserID,
+			"api_key_id": claims.ID,
+		}).
+		Debug("API key authenticated successfully")
+
+	c.Next()
+}
+
+// setAuthContext stores the authenticated user's information in the request context
+func setAuthContext(c *gin.Context, claims *auth.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_role", claims.Role)
+	c.Set("user_plan", claims.Plan)
+	c.Set("jwt_claims", claims)
+}
+
 // RoleMiddleware creates role-based authorization middleware
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -190,10 +252,15 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 
 // AdminMiddleware creates admin-only authorization middleware
 func AdminMiddleware() gin.HandlerFunc {
-	return RoleMiddleware("admin")
+	return RoleMiddleware(model.RoleAdmin, model.RoleSuperAdmin)
+}
+
+// SuperAdminMiddleware creates super-admin-only authorization middleware
+func SuperAdminMiddleware() gin.HandlerFunc {
+	return RoleMiddleware(model.RoleSuperAdmin)
 }
 
-// UserMiddleware creates user+ authorization middleware (user, admin)
+// UserMiddleware creates user+ authorization middleware (user, admin, super admin)
 func UserMiddleware() gin.HandlerFunc {
-	return RoleMiddleware("user", "admin")
+	return RoleMiddleware(model.RoleUser, model.RoleAdmin, model.RoleSuperAdmin)
 }
@@ -0,0 +1,273 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// redacted replaces the values of redacted fields and headers
+const redacted = "[REDACTED]"
+
+// defaultRedactedFields are JSON and form fields that are always redacted, names at any
+// depth and dotted paths from the top. data.key is the API key in the response that
+// creates it (model.CreatedAPIKey).
+var defaultRedactedFields = []string{
+	"password", "current_password", "new_password", "confirm_password",
+	"token", "access_token", "refresh_token", "api_key", "secret", "client_secret",
+	"data.key",
+}
+
+// defaultRedactedHeaders are headers that are always redacted
+var defaultRedactedHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key",
+}
+
+// BodyLoggingMiddleware logs the request and response bodies, and headers, of routes
+// under the configured path prefixes, for a sample of cfg.SampleRate of their requests.
+// The first cfg.MaxSize bytes of each body are captured as the handler reads and
+// writes them. Default and configured fields are redacted from JSON and form bodies:
+// names match at any depth, dotted paths such as "data.key" from the top. Bodies of
+// other types are logged only if they are text. Register it after
+// DecompressionMiddleware and inside CompressionMiddleware, so bodies are plain.
+func BodyLoggingMiddleware(cfg config.BodyLogConfig, logger *logger.Logger) gin.HandlerFunc {
+	prefixes := make(map[string]bool, len(cfg.Routes))
+	for _, prefix := range cfg.Routes {
+		prefixes[prefix] = true
+	}
+	routes := newPrefixValues(prefixes)
+	redactor := newRedactor(append(defaultRedactedFields, cfg.RedactFields...),
+		append(defaultRedactedHeaders, cfg.RedactHeaders...))
+
+	return func(c *gin.Context) {
+		if _, ok := routes.match(c.Request.URL.Path); !ok || rand.Float64() >= cfg.SampleRate {
+			c.Next()
+			return
+		}
+
+		var request *bodyCapture
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			request = &bodyCapture{max: cfg.MaxSize}
+			c.Request.Body = &captureReader{ReadCloser: c.Request.Body, capture: request}
+		}
+		response := &bodyCapture{max: cfg.MaxSize}
+		c.Writer = &captureWriter{ResponseWriter: c.Writer, capture: response}
+
+		c.Next()
+
+		fields := map[string]interface{}{
+			"method":           c.Request.Method,
+			"path":             c.Request.URL.Path,
+			"status_code":      c.Writer.Status(),
+			"request_headers":  redactor.headers(c.Request.Header),
+			"response_headers": redactor.headers(c.Writer.Header()),
+			"response_body":    redactor.body(c.Writer.Header().Get("Content-Type"), response),
+		}
+		if request != nil {
+			fields["request_body"] = redactor.body(c.GetHeader("Content-Type"), request)
+		}
+
+		logger.WithRequestID(c.GetString("request_id")).WithFields(fields).Info("HTTP request bodies")
+	}
+}
+
+// bodyCapture keeps the first max bytes of a body and counts the rest
+type bodyCapture struct {
+	max   int
+	data  []byte
+	total int
+}
+
+func (b *bodyCapture) write(p []byte) {
+	b.total += len(p)
+	if room := b.max - len(b.data); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		b.data = append(b.data, p...)
+	}
+}
+
+func (b *bodyCapture) truncated() bool {
+	return b.total > len(b.data)
+}
+
+// captureReader captures a request body as the handler reads it
+type captureReader struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.capture.write(p[:n])
+	return n, err
+}
+
+// captureWriter captures a response body as the handler writes it
+type captureWriter struct {
+	gin.ResponseWriter
+	capture *bodyCapture
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.capture.write(data[:n])
+	return n, err
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.capture.write([]byte(s[:n]))
+	return n, err
+}
+
+// redactor removes secrets from logged headers and bodies
+type redactor struct {
+	fields      map[string]bool // lower-cased names, matched at any depth
+	paths       map[string]bool // lower-cased dotted paths, matched from the top
+	headerNames map[string]bool // canonical header names
+
+	// jsonField matches string values of the redacted names in JSON that can't be
+	// parsed, such as truncated bodies
+	jsonField *regexp.Regexp
+}
+
+func newRedactor(fields, headers []string) *redactor {
+	r := &redactor{
+		fields:      make(map[string]bool),
+		paths:       make(map[string]bool),
+		headerNames: make(map[string]bool),
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(field)
+		if strings.Contains(field, ".") {
+			r.paths[field] = true
+			field = field[strings.LastIndexByte(field, '.')+1:]
+		} else {
+			r.fields[field] = true
+		}
+		names = append(names, regexp.QuoteMeta(field))
+	}
+	for _, header := range headers {
+		r.headerNames[http.CanonicalHeaderKey(header)] = true
+	}
+
+	// Without a parsed document paths can't be told apart, so their last name is used
+	r.jsonField = regexp.MustCompile(`(?i)("(?:` + strings.Join(names, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*("|$)`)
+	return r
+}
+
+// headers returns the headers with redacted values replaced
+func (r *redactor) headers(header http.Header) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		if r.headerNames[http.CanonicalHeaderKey(name)] {
+			result[name] = redacted
+			continue
+		}
+		result[name] = strings.Join(values, ", ")
+	}
+	return result
+}
+
+// body returns a captured body for the log with redacted fields replaced
+func (r *redactor) body(contentType string, capture *bodyCapture) string {
+	if capture.total == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	mediaType = strings.ToLower(mediaType)
+
+	var body string
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		body = r.json(capture)
+	case mediaType == "application/x-www-form-urlencoded":
+		body = r.form(capture)
+	case strings.HasPrefix(mediaType, "text/") && mediaType != "text/event-stream":
+		body = string(capture.data)
+	default:
+		return "[" + strconv.Itoa(capture.total) + " bytes of " + contentTypeOrUnknown(mediaType) + "]"
+	}
+
+	if capture.truncated() {
+		body += "... [truncated, " + strconv.Itoa(capture.total) + " bytes]"
+	}
+	return body
+}
+
+// json redacts a JSON body, by pattern if it can't be parsed
+func (r *redactor) json(capture *bodyCapture) string {
+	var document interface{}
+	if !capture.truncated() {
+		decoder := json.NewDecoder(bytes.NewReader(capture.data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err == nil {
+			if data, err := json.Marshal(r.redactValue(document, "")); err == nil {
+				return string(data)
+			}
+		}
+	}
+	return r.jsonField.ReplaceAllString(string(capture.data), `$1"`+redacted+`"`)
+}
+
+// redactValue replaces redacted fields of a decoded JSON value; path is the dotted path
+// of value
+func (r *redactor) redactValue(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := strings.ToLower(key)
+			if path != "" {
+				childPath = path + "." + childPath
+			}
+			if r.fields[strings.ToLower(key)] || r.paths[childPath] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = r.redactValue(child, childPath)
+		}
+	case []interface{}:
+		// Array elements share the path of the array
+		for i, child := range v {
+			v[i] = r.redactValue(child, path)
+		}
+	}
+	return value
+}
+
+// form redacts a URL-encoded form body
+func (r *redactor) form(capture *bodyCapture) string {
+	values, err := url.ParseQuery(string(capture.data))
+	if err != nil {
+		return "[" + strconv.Itoa(capture.total) + " bytes of unparseable form]"
+	}
+	for key := range values {
+		if r.fields[strings.ToLower(key)] || r.paths[strings.ToLower(key)] {
+			values[key] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
+
+func contentTypeOrUnknown(mediaType string) string {
+	if mediaType == "" {
+		return "unknown content"
+	}
+	return mediaType
+}
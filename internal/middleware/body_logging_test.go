@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestBodyLoggingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(cfg config.BodyLogConfig) (*gin.Engine, *observer.ObservedLogs) {
+		core, logs := observer.New(zap.InfoLevel)
+		router := gin.New()
+		router.Use(BodyLoggingMiddleware(cfg, &logger.Logger{Logger: zap.New(core)}))
+		router.POST("/api/v1/auth/login", func(c *gin.Context) {
+			body, _ := io.ReadAll(c.Request.Body)
+			c.Header("Set-Cookie", "session=secret")
+			c.Data(http.StatusOK, "application/json", body)
+		})
+		router.POST("/api/v1/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+		router.POST("/api/v1/profile/api-keys", func(c *gin.Context) {
+			response.Created(c, "API key created successfully", &model.CreatedAPIKey{
+				APIKey: model.APIKey{ID: "key-1", Name: "CI", Prefix: "ak_live_S"},
+				Key:    "ak_live_SECRET",
+			})
+		})
+		return router, logs
+	}
+	request := func(router *gin.Engine, path, contentType, body string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer secret-token")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	field := func(logs *observer.ObservedLogs, name string) interface{} {
+		entries := logs.All()
+		if len(entries) != 1 {
+			t.Fatalf("Expected one log entry, got %d", len(entries))
+		}
+		return entries[0].ContextMap()[name]
+	}
+
+	cfg := config.BodyLogConfig{
+		Routes:       []string{"/api/v1/auth"},
+		MaxSize:      1024,
+		SampleRate:   1,
+		RedactFields: []string{"profile.ssn"},
+	}
+
+	t.Run("JSON fields and headers are redacted", func(t *testing.T) {
+		router, logs := newRouter(cfg)
+		request(router, "/api/v1/auth/login", "application/json",
+			`{"email":"jane@example.com","Password":"hunter2","profile":{"ssn":"123","name":"Jane"},"devices":[{"token":"abc"}]}`)
+
+		want := `{"Password":"[REDACTED]","devices":[{"token":"[REDACTED]"}],"email":"jane@example.com","profile":{"name":"Jane","ssn":"[REDACTED]"}}`
+		if got := field(logs, "request_body"); got != want {
+			t.Errorf("Expected %s, got %v", want, got)
+		}
+		if got := field(logs, "response_body"); got != want {
+			t.Errorf("Expected the response to be redacted too, got %v", got)
+		}
+		headers := field(logs, "request_headers").(map[string]string)
+		if headers["Authorization"] != "[REDACTED]" || headers["Content-Type"] != "application/json" {
+			t.Errorf("Expected Authorization to be redacted, got %v", headers)
+		}
+		if got := field(logs, "response_headers").(map[string]string)["Set-Cookie"]; got != "[REDACTED]" {
+			t.Errorf("Expected Set-Cookie to be redacted, got %v", got)
+		}
+	})
+
+	t.Run("truncated JSON is redacted by pattern", func(t *testing.T) {
+		small := cfg
+		small.MaxSize = 40
+		router, logs := newRouter(small)
+		request(router, "/api/v1/auth/login", "application/json",
+			`{"email":"jane@example.com","password":"hunter2","name":"Jane"}`)
+
+		got, _ := field(logs, "request_body").(string)
+		if strings.Contains(got, "hunt") || !strings.Contains(got, `"password":"[REDACTED]"`) || !strings.Contains(got, "[truncated, 63 bytes]") {
+			t.Errorf("Expected a redacted, truncated body, got %s", got)
+		}
+	})
+
+	t.Run("created API keys are redacted", func(t *testing.T) {
+		profile := cfg
+		profile.Routes = []string{"/api/v1/profile"}
+		router, logs := newRouter(profile)
+		request(router, "/api/v1/profile/api-keys", "application/json", `{"name":"CI"}`)
+
+		got, _ := field(logs, "response_body").(string)
+		if strings.Contains(got, "ak_live_SECRET") || !strings.Contains(got, `"key":"[REDACTED]"`) || !strings.Contains(got, `"prefix":"ak_live_S"`) {
+			t.Errorf("Expected the API key to be redacted, got %s", got)
+		}
+	})
+
+	t.Run("form fields are redacted", func(t *testing.T) {
+		router, logs := newRouter(cfg)
+		request(router, "/api/v1/auth/login", "application/x-www-form-urlencoded", "email=jane%40example.com&new_password=hunter2")
+
+		if got := field(logs, "request_body"); got != "email=jane%40example.com&new_password=%5BREDACTED%5D" {
+			t.Errorf("Expected the password to be redacted, got %v", got)
+		}
+	})
+
+	t.Run("other routes and unsampled requests are not logged", func(t *testing.T) {
+		router, logs := newRouter(cfg)
+		request(router, "/api/v1/ping", "application/json", `{}`)
+		if logs.Len() != 0 {
+			t.Errorf("Expected routes outside the prefixes not to be logged, got %d entries", logs.Len())
+		}
+
+		unsampled := cfg
+		unsampled.SampleRate = 0
+		router, logs = newRouter(unsampled)
+		request(router, "/api/v1/auth/login", "application/json", `{}`)
+		if logs.Len() != 0 {
+			t.Errorf("Expected a sample rate of 0 to log nothing, got %d entries", logs.Len())
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIPMiddleware resolves the IP of the client once per request and stores it in
+// the context, where ClientIP reads it. Forwarding headers are only believed when the
+// peer is one of trustedProxies (CIDRs or IPs), and only the remoteIPHeaders are read:
+// the first of them present in the request is used, like gin's RemoteIPHeaders. The
+// RFC 7239 Forwarded header must be listed explicitly, since a proxy that doesn't
+// overwrite it passes on whatever the client sent. Hops are read from the right,
+// skipping trusted proxies, so addresses a client puts in front of the chain are ignored.
+func ClientIPMiddleware(trustedProxies, remoteIPHeaders []string) gin.HandlerFunc {
+	trusted := parseNetworks(trustedProxies)
+
+	return func(c *gin.Context) {
+		c.Set("client_ip", resolveClientIP(c.Request.RemoteAddr, c.Request.Header, remoteIPHeaders, trusted))
+		c.Next()
+	}
+}
+
+// ClientIP returns the client IP resolved by ClientIPMiddleware. Use it instead of
+// c.ClientIP, so rate limits, logs and audit records agree on the client.
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString("client_ip"); ip != "" {
+		return ip
+	}
+	return c.ClientIP()
+}
+
+// parseNetworks parses CIDRs and IPs; invalid entries are rejected by config validation
+// and skipped here
+func parseNetworks(networks []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		if prefix, err := netip.ParsePrefix(network); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(network); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// inNetworks returns true if addr is in one of the prefixes
+func inNetworks(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveClientIP returns the client IP of a request from its peer address and the
+// first of remoteIPHeaders it has
+func resolveClientIP(remoteAddr string, header http.Header, remoteIPHeaders []string, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	peer = peer.Unmap()
+
+	if !inNetworks(peer, trusted) {
+		return peer.String()
+	}
+
+	var hops []string
+	for _, name := range remoteIPHeaders {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		if strings.EqualFold(name, "Forwarded") {
+			hops = forwardedForHops(values)
+		} else {
+			for _, value := range values {
+				hops = append(hops, strings.Split(value, ",")...)
+			}
+		}
+		break
+	}
+
+	// Walk from the nearest hop; the first untrusted one is the client. A hop that isn't
+	// an IP (obfuscated, "unknown" or garbage) ends the walk at the last address known.
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseHop(hops[i])
+		if !ok {
+			break
+		}
+		client = hop
+		if !inNetworks(hop, trusted) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// forwardedForHops returns the for= values of the elements of Forwarded headers, in
+// order. Elements without for= are kept as empty hops, which end the walk.
+func forwardedForHops(headers []string) []string {
+	var hops []string
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					hop = value
+					break
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseHop parses an address of a forwarding header: a bare IP, or as Forwarded has
+// them, quoted and with a port ("192.0.2.1:8080", "[2001:db8::1]:8080")
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+
+	if addr, err := netip.ParseAddr(hop); err == nil {
+		return addr.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if strings.HasPrefix(hop, "[") && strings.HasSuffix(hop, "]") {
+		if addr, err := netip.ParseAddr(hop[1 : len(hop)-1]); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
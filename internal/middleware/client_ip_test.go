@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	xff := []string{"X-Forwarded-For"}
+	forwarded := []string{"Forwarded"}
+
+	tests := []struct {
+		name       string
+		headers    []string // Remote IP headers the middleware reads
+		remoteAddr string
+		request    map[string][]string
+		want       string
+	}{
+		{"direct clients are their peer address", xff, "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted peers can't spoof headers", []string{"Forwarded", "X-Forwarded-For"}, "203.0.113.5:1234",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "Forwarded": {"for=198.51.100.1"}}, "203.0.113.5"},
+		{"trusted proxies report the client", xff, "10.0.0.2:1234",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+		{"addresses put in front by the client are ignored", xff, "10.0.0.2:1234",
+			map[string][]string{"X-Forwarded-For": {"192.0.2.66, 198.51.100.1, 10.0.0.3"}}, "198.51.100.1"},
+		{"X-Real-IP is ignored unless configured", xff, "10.0.0.2:1234",
+			map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, "10.0.0.2"},
+		{"X-Real-IP is used without X-Forwarded-For", []string{"X-Forwarded-For", "X-Real-IP"}, "10.0.0.2:1234",
+			map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, "198.51.100.1"},
+		{"Forwarded is ignored unless configured", xff, "10.0.0.2:1234",
+			map[string][]string{"Forwarded": {"for=198.51.100.66"}, "X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+		{"the first configured header present wins", []string{"Forwarded", "X-Forwarded-For"}, "10.0.0.2:1234",
+			map[string][]string{"Forwarded": {`for="198.51.100.7:4711";proto=https`}, "X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.7"},
+		{"later headers are used when earlier ones are missing", []string{"Forwarded", "X-Forwarded-For"}, "10.0.0.2:1234",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+		{"Forwarded elements span headers", forwarded, "10.0.0.2:1234",
+			map[string][]string{"Forwarded": {"for=192.0.2.66", "for=198.51.100.7, for=10.0.0.3;by=10.0.0.2"}}, "198.51.100.7"},
+		{"Forwarded IPv6 addresses are bracketed", forwarded, "[2001:db8::1]:1234",
+			map[string][]string{"Forwarded": {`For="[2001:db8:cafe::17]:4711"`}}, "2001:db8:cafe::17"},
+		{"obfuscated hops end the walk at the proxy", forwarded, "10.0.0.2:1234",
+			map[string][]string{"Forwarded": {"for=198.51.100.7, for=_hidden"}}, "10.0.0.2"},
+		{"a chain of trusted proxies ends at the first of them", xff, "10.0.0.2:1234",
+			map[string][]string{"X-Forwarded-For": {"10.0.0.4, 10.0.0.3"}}, "10.0.0.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ClientIPMiddleware([]string{"10.0.0.0/8", "2001:db8::1"}, tt.headers))
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, ClientIP(c)) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.request {
+				req.Header[name] = values
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is a pooled response compressor
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoderPools hold reusable compressors by content coding
+var encoderPools = map[string]*sync.Pool{
+	"gzip": {New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	"br": {New: func() interface{} {
+		// Level 4 compresses about as well as gzip's default at a similar speed
+		return brotli.NewWriterLevel(nil, 4)
+	}},
+	"zstd": {New: func() interface{} {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return w
+	}},
+}
+
+// incompressibleTypes are content types that are compressed already; image/svg+xml is
+// text and stays compressible
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-brotli", "application/octet-stream", "application/pdf",
+}
+
+// CompressionMiddleware compresses responses with the encoding Accept-Encoding ranks
+// highest, the order of cfg.Encodings breaking ties. Responses are held back until
+// cfg.MinSize bytes have been written and sent uncompressed if they end up smaller.
+// A Flush, as streaming responses and server-sent events do, starts compression at
+// once and flushes the compressor, so every event reaches the client. Compressed
+// content types, partial content and Cache-Control: no-transform responses are left
+// alone; responses that could be compressed carry Vary: Accept-Encoding.
+func CompressionMiddleware(cfg config.CompressionConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       negotiateEncoding(c.Request.Header.Values("Accept-Encoding"), cfg.Encodings),
+			minSize:        cfg.MinSize,
+			status:         c.Writer.Status(),
+		}
+		c.Writer = writer
+		defer func() {
+			writer.close()
+			c.Writer = writer.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the encoding of a response from the Accept-Encoding headers,
+// by the client's q-values and then by the order of preferred, or "" for none
+func negotiateEncoding(headers []string, preferred []string) string {
+	accepted := make(map[string]float64)
+	for _, header := range headers {
+		for _, part := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if name == "x-gzip" {
+				name = "gzip"
+			}
+
+			q := 1.0
+			if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+			accepted[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range preferred {
+		q, ok := accepted[encoding]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the start of a response until it knows whether to compress
+// it, then writes the rest through the compressor, if any
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string // negotiated, "" if the client accepts none
+	minSize  int
+
+	status  int
+	written bool   // the handler has written the header or a body
+	pending []byte // body held back until decided
+	decided bool
+	enc     encoder
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code > 0 && !w.decided {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.written = true
+	if !w.decided {
+		w.pending = append(w.pending, data...)
+		if len(w.pending) >= w.minSize {
+			w.decide(false)
+		}
+		return len(data), nil
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Size() int {
+	if !w.decided {
+		if !w.written {
+			return -1
+		}
+		return len(w.pending)
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.written || w.decided
+}
+
+// Flush starts the response, compressed if it can be, and pushes out what has been
+// written so far
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide writes the header, compressing the response if it is eligible and either
+// streamed or large enough, and then the body held back
+func (w *compressWriter) decide(streaming bool) {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+
+	if w.compressible(header) {
+		addVary(header, "Accept-Encoding")
+
+		if w.encoding != "" && (streaming || (len(w.pending) > 0 && len(w.pending) >= w.minSize)) {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			w.enc = encoderPools[w.encoding].Get().(encoder)
+			w.enc.Reset(w.ResponseWriter)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.pending) == 0 {
+		return
+	}
+	if w.enc != nil {
+		w.enc.Write(w.pending)
+	} else {
+		w.ResponseWriter.Write(w.pending)
+	}
+	w.pending = nil
+}
+
+// compressible returns true if the response may be compressed. Responses without a
+// Content-Type get a sniffed one, so the compressed body isn't sniffed instead.
+func (w *compressWriter) compressible(header http.Header) bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified ||
+		w.status == http.StatusPartialContent {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" ||
+		strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-transform") {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		if len(w.pending) == 0 {
+			return false
+		}
+		contentType = http.DetectContentType(w.pending)
+		header.Set("Content-Type", contentType)
+	}
+
+	contentType = strings.ToLower(contentType)
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return true
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// close finishes the response once the handler has returned
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(nil)
+		encoderPools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// addVary adds a field to the Vary header unless it is listed already
+func addVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, listed := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(listed), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
+
+// DecompressionMiddleware decodes request bodies sent with Content-Encoding: gzip.
+// Bodies that decompress to more than maxSize bytes are rejected with 413, which defuses
+// compression bombs, and other encodings with 415. Register it after
+// BodyLimitMiddleware, which limits the compressed size.
+func DecompressionMiddleware(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contentEncoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if contentEncoding == "" || contentEncoding == "identity" || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if contentEncoding != "gzip" && contentEncoding != "x-gzip" {
+			c.Header("Accept-Encoding", "gzip")
+			abortRequest(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_CONTENT_ENCODING",
+				"Request bodies must be uncompressed or gzip encoded")
+			return
+		}
+
+		reader, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			abortRequest(c, http.StatusBadRequest, "INVALID_CONTENT_ENCODING", "Request body is not valid gzip")
+			return
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				abortBodyTooLarge(c, maxBytesErr.Limit)
+				return
+			}
+			abortRequest(c, http.StatusBadRequest, "INVALID_CONTENT_ENCODING", "Request body is not valid gzip")
+			return
+		}
+		if int64(len(data)) > maxSize {
+			abortRequest(c, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+				"Decompressed request body must be at most "+strconv.FormatInt(maxSize, 10)+" bytes")
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		c.Request.ContentLength = int64(len(data))
+		c.Request.Header.Del("Content-Encoding")
+
+		c.Next()
+	}
+}
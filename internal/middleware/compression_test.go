@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	preferred := []string{"zstd", "br", "gzip"}
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"no header", "", ""},
+		{"server preference breaks ties", "gzip, br, zstd", "zstd"},
+		{"q-values win over preference", "zstd;q=0.5, gzip", "gzip"},
+		{"q=0 refuses an encoding", "zstd;q=0, br;q=0, gzip", "gzip"},
+		{"x-gzip is gzip", "x-gzip", "gzip"},
+		{"wildcard", "*", "zstd"},
+		{"wildcard with exclusions", "*, zstd;q=0", "br"},
+		{"unknown encodings", "deflate, compress", ""},
+		{"identity only", "identity", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateEncoding([]string{tt.header}, preferred); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	large := strings.Repeat(`{"name":"compressible"}`, 100)
+
+	router := gin.New()
+	router.Use(CompressionMiddleware(config.CompressionConfig{Encodings: []string{"zstd", "br", "gzip"}, MinSize: 256}))
+	router.GET("/large", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(large)) })
+	router.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	router.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) })
+	router.GET("/svg", func(c *gin.Context) { c.Data(http.StatusOK, "image/svg+xml", []byte(large)) })
+	router.GET("/no-transform", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-transform")
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	router.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			c.SSEvent("message", i)
+			c.Writer.Flush()
+		}
+	})
+
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for encoding, decode := range decoders {
+		t.Run("large responses are compressed with "+encoding, func(t *testing.T) {
+			w := request("/large", encoding)
+			if w.Header().Get("Content-Encoding") != encoding || w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Fatalf("Expected %s and Vary, got %v", encoding, w.Header())
+			}
+			if w.Body.Len() >= len(large) {
+				t.Errorf("Expected a smaller body, got %d bytes", w.Body.Len())
+			}
+
+			reader, err := decode(w.Body)
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil || string(body) != large {
+				t.Errorf("Expected the original body back, got %d bytes, %v", len(body), err)
+			}
+		})
+	}
+
+	if w := request("/large", ""); w.Header().Get("Content-Encoding") != "" || w.Body.String() != large ||
+		w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected an uncompressed body that varies on Accept-Encoding, got %v", w.Header())
+	}
+
+	w := request("/small", "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{"ok":true}` || w.Code != http.StatusOK {
+		t.Errorf("Expected small bodies to be sent as they are, got %d %v", w.Code, w.Header())
+	}
+
+	for _, path := range []string{"/image", "/no-transform"} {
+		if w := request(path, "gzip"); w.Header().Get("Content-Encoding") != "" || w.Header().Get("Vary") != "" {
+			t.Errorf("Expected %s to be left alone, got %v", path, w.Header())
+		}
+	}
+	if w := request("/svg", "gzip"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("Expected SVG to be compressed, got %v", w.Header())
+	}
+	if w := request("/empty", "gzip"); w.Code != http.StatusNoContent || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected 204 without a body, got %d %v", w.Code, w.Header())
+	}
+
+	// Each flush reaches the client, even below the minimum size
+	w = request("/events", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || !w.Flushed {
+		t.Fatalf("Expected a flushed gzip stream, got %v", w.Header())
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if strings.Count(string(body), "event:message") != 3 {
+		t.Errorf("Expected three events, got %q", body)
+	}
+}
+
+func TestDecompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(DecompressionMiddleware(100))
+	router.POST("/items", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	gzipped := func(data string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(data))
+		zw.Close()
+		return buf.Bytes()
+	}
+	request := func(body []byte, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(body))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request(gzipped(`{"name":"item"}`), "gzip"); w.Code != http.StatusOK || w.Body.String() != `{"name":"item"}` {
+		t.Errorf("Expected the decompressed body, got %d %q", w.Code, w.Body.String())
+	}
+	if w := request([]byte(`{"name":"item"}`), ""); w.Code != http.StatusOK || w.Body.String() != `{"name":"item"}` {
+		t.Errorf("Expected uncompressed bodies to pass, got %d", w.Code)
+	}
+
+	// A small body that inflates past the limit
+	if w := request(gzipped(strings.Repeat("a", 10000)), "gzip"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", w.Code)
+	}
+	if w := request([]byte("not gzip"), "gzip"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_CONTENT_ENCODING") {
+		t.Errorf("Expected 400 INVALID_CONTENT_ENCODING, got %d", w.Code)
+	}
+	w := request([]byte("data"), "br")
+	if w.Code != http.StatusUnsupportedMediaType || w.Header().Get("Accept-Encoding") != "gzip" {
+		t.Errorf("Expected 415 advertising gzip, got %d %v", w.Code, w.Header())
+	}
+}
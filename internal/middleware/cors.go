@@ -8,13 +8,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// exposedHeaders are the response headers browsers let clients read
+var exposedHeaders = []string{
+	"X-Request-ID", "X-Total-Count", "ETag",
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+	"Idempotent-Replayed", "X-Maintenance-Mode",
+}
+
 // CORSMiddleware configures CORS based on application configuration
 func CORSMiddleware(cfg *config.Config) gin.HandlerFunc {
 	corsConfig := cors.Config{
 		AllowOrigins:     cfg.CORS.AllowedOrigins,
 		AllowMethods:     cfg.CORS.AllowedMethods,
 		AllowHeaders:     cfg.CORS.AllowedHeaders,
-		ExposeHeaders:    []string{"X-Request-ID", "X-Total-Count"},
+		ExposeHeaders:    exposedHeaders,
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
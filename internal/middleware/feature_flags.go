@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/featureflag"
+	"github.com/gin-gonic/gin"
+)
+
+// FlagEvaluator evaluates feature flags
+type FlagEvaluator interface {
+	Enabled(key string, subject featureflag.Subject) bool
+	Evaluate(subject featureflag.Subject) map[string]bool
+}
+
+// FeatureFlagsMiddleware makes the feature flags available to handlers through
+// FlagEnabled, FeatureFlags and RequireFlag. Flags are evaluated when asked for, so
+// they see the user authenticated by middleware that runs later.
+func FeatureFlagsMiddleware(flags FlagEvaluator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("feature_flags", flags)
+		c.Next()
+	}
+}
+
+// FlagSubject returns who flags are evaluated for: the authenticated user, or an
+// anonymous caller
+func FlagSubject(c *gin.Context) featureflag.Subject {
+	return featureflag.Subject{
+		UserID: c.GetString("user_id"),
+		Role:   c.GetString("user_role"),
+		Email:  c.GetString("user_email"),
+	}
+}
+
+// FlagEnabled returns whether a feature flag is on for the caller; unknown flags are off
+func FlagEnabled(c *gin.Context, key string) bool {
+	value, _ := c.Get("feature_flags")
+	flags, ok := value.(FlagEvaluator)
+	return ok && flags.Enabled(key, FlagSubject(c))
+}
+
+// FeatureFlags returns every feature flag evaluated for the caller
+func FeatureFlags(c *gin.Context) map[string]bool {
+	value, _ := c.Get("feature_flags")
+	flags, ok := value.(FlagEvaluator)
+	if !ok {
+		return map[string]bool{}
+	}
+	return flags.Evaluate(FlagSubject(c))
+}
+
+// RequireFlag responds 404 while a feature flag is off for the caller, so routes of
+// features that are not rolled out to them don't exist for them. Register it after
+// AuthMiddleware to target users.
+func RequireFlag(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if FlagEnabled(c, key) {
+			c.Next()
+			return
+		}
+
+		abortRequest(c, http.StatusNotFound, "NOT_FOUND", "The requested endpoint was not found")
+	}
+}
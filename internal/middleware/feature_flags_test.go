@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/featureflag"
+	"github.com/gin-gonic/gin"
+)
+
+func TestFeatureFlagsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	flags := featureflag.NewSet(featureflag.SourceFunc(func(ctx context.Context) ([]featureflag.Flag, error) {
+		return []featureflag.Flag{
+			{Key: "beta_search", Enabled: true, Roles: []string{"admin"}},
+			{Key: "new_dashboard", Enabled: true, EmailDomains: []string{"example.com"}},
+		}, nil
+	}))
+	if err := flags.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh flags: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(FeatureFlagsMiddleware(flags))
+	// Stands in for AuthMiddleware, which runs after the flags middleware
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User-ID"))
+		c.Set("user_role", c.GetHeader("X-User-Role"))
+		c.Set("user_email", c.GetHeader("X-User-Email"))
+		c.Next()
+	})
+	router.GET("/flags", func(c *gin.Context) { c.JSON(http.StatusOK, FeatureFlags(c)) })
+	router.GET("/search", RequireFlag("beta_search"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path, role, email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User-ID", "user-1")
+		req.Header.Set("X-User-Role", role)
+		req.Header.Set("X-User-Email", email)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var evaluated map[string]bool
+	if err := json.Unmarshal(request("/flags", "user", "jane@example.com").Body.Bytes(), &evaluated); err != nil {
+		t.Fatalf("Failed to decode flags: %v", err)
+	}
+	if len(evaluated) != 2 || evaluated["beta_search"] || !evaluated["new_dashboard"] {
+		t.Errorf("Expected the flags evaluated for the user, got %v", evaluated)
+	}
+
+	if w := request("/search", "admin", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the flagged route to be reachable, got %d", w.Code)
+	}
+	if w := request("/search", "user", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 while the flag is off, got %d", w.Code)
+	}
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/pkg/idempotency"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header clients send an idempotency key in
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength caps the length of idempotency keys
+const maxIdempotencyKeyLength = 255
+
+// unreplayedHeaders are response headers that describe the original request rather
+// than the response, or how outer middleware such as CompressionMiddleware encoded the
+// body, so replays don't repeat them. The stored body is the one the handler wrote.
+var unreplayedHeaders = []string{"X-Request-Id", "Ratelimit-", "Retry-After", "Date", "Access-Control-",
+	"Content-Encoding", "Content-Length", "Vary"}
+
+// idempotencyWriter records the body of a response as it is written
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes POST and PATCH requests sent with an Idempotency-Key
+// header safe to retry. The response of the first request with a key is stored for
+// the configured TTL and replayed to retries. A key reused for a different request is
+// rejected with 422, and a retry while the first request is still in flight with 409.
+// Server errors aren't stored, so those requests can be retried. Routes listed in
+// secretRoutes (by their registered path) respond with secrets such as new API keys;
+// only the status of their successful responses is stored, and replays say the secret
+// can't be shown again. Keys are scoped to the tenant and the user, or the client IP for
+// anonymous requests; register it after TenantMiddleware and AuthMiddleware.
+func IdempotencyMiddleware(store idempotency.Store, cfg config.IdempotencyConfig, logger *logger.Logger, secretRoutes ...string) gin.HandlerFunc {
+	secret := make(map[string]bool, len(secretRoutes))
+	for _, route := range secretRoutes {
+		secret[route] = true
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			abortIdempotency(c, http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY", "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortIdempotency(c, http.StatusBadRequest, "BAD_REQUEST", "Failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := idempotencyScope(c) + ":" + key
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+
+		record, reserved, err := store.Begin(c.Request.Context(), storeKey, fingerprint, cfg.LockTimeout)
+		if err != nil {
+			// Don't take writes down with the store; the request just isn't protected
+			logger.WithError(err).WithRequestID(c.GetString("request_id")).Warn("Idempotency check failed")
+			c.Next()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.Fingerprint != fingerprint:
+				abortIdempotency(c, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+					"Idempotency-Key was already used for a different request")
+			case record.InFlight():
+				c.Header("Retry-After", "1")
+				abortIdempotency(c, http.StatusConflict, "IDEMPOTENCY_KEY_IN_USE",
+					"A request with this Idempotency-Key is still being processed")
+			case secret[c.FullPath()] && record.Status < http.StatusBadRequest:
+				replaySecretResponse(c, record)
+			default:
+				replayResponse(c, record)
+			}
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// The outcome is stored even if the client has gone away, so its retry finds it
+		ctx := context.WithoutCancel(c.Request.Context())
+
+		// Release the key after server errors and panics, so the request can be retried
+		completed := false
+		defer func() {
+			if !completed {
+				if err := store.Release(ctx, storeKey); err != nil {
+					logger.WithError(err).Warn("Failed to release idempotency key")
+				}
+			}
+		}()
+
+		c.Next()
+
+		if writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		completed = true
+		response := &idempotency.Record{
+			Fingerprint: fingerprint,
+			Status:      writer.Status(),
+			Header:      replayableHeaders(writer.Header()),
+			Body:        writer.body.Bytes(),
+		}
+		if secret[c.FullPath()] && response.Status < http.StatusBadRequest {
+			response.Header = nil
+			response.Body = nil
+		}
+		if err := store.Complete(ctx, storeKey, response, cfg.TTL); err != nil {
+			logger.WithError(err).WithRequestID(c.GetString("request_id")).Error("Failed to store idempotent response")
+		}
+	}
+}
+
+// idempotencyScope returns the scope of a request's idempotency keys, so clients can't
+// replay each other's responses
+func idempotencyScope(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return c.GetString("tenant_id") + ":user:" + userID
+	}
+	return c.GetString("tenant_id") + ":ip:" + ClientIP(c)
+}
+
+// replayableHeaders returns the response headers that are stored for replays
+func replayableHeaders(header http.Header) http.Header {
+	replayable := http.Header{}
+	for name, values := range header {
+		if !isUnreplayedHeader(name) {
+			replayable[name] = values
+		}
+	}
+	return replayable
+}
+
+// isUnreplayedHeader returns true if a canonical header name is in unreplayedHeaders
+func isUnreplayedHeader(name string) bool {
+	for _, unreplayed := range unreplayedHeaders {
+		if strings.HasPrefix(name, unreplayed) {
+			return true
+		}
+	}
+	return false
+}
+
+// replayResponse answers a request with a stored response
+func replayResponse(c *gin.Context, record *idempotency.Record) {
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(record.Status)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// replaySecretResponse answers a request to a secret route with the stored status only,
+// since the secret in the original response wasn't stored
+func replaySecretResponse(c *gin.Context, record *idempotency.Record) {
+	message := "Request already processed; the secret in its response can't be shown again"
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(record.Status, gin.H{
+		"success":    true,
+		"message":    message,
+		"timestamp":  time.Now(),
+		"request_id": c.GetString("request_id"),
+	})
+	c.Abort()
+}
+
+// abortIdempotency aborts the request with an idempotency error
+func abortIdempotency(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+		"timestamp":  time.Now(),
+		"request_id": c.GetString("request_id"),
+	})
+	c.Abort()
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/pkg/idempotency"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := idempotency.NewRedisStore(client, "idempotency:")
+
+	// Each POST creates an item; ?fail makes the handler fail, ?hold makes it wait for release
+	created := 0
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-Id", c.GetHeader("X-Test-Request"))
+		c.Set("user_id", c.GetHeader("X-User"))
+	})
+	router.Use(IdempotencyMiddleware(store, config.IdempotencyConfig{TTL: time.Hour, LockTimeout: time.Minute}, log))
+	router.POST("/items", func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false})
+			return
+		}
+		if c.Query("hold") != "" {
+			close(started)
+			<-release
+		}
+		created++
+		c.Header("Location", "/items/1")
+		c.JSON(http.StatusCreated, gin.H{"created": created})
+	})
+
+	request := func(url, key, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Test-Request", "req-"+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Retries replay the first response without running the handler again
+	first := request("/items", "k1", "u1", `{"name":"a"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", first.Code)
+	}
+	replay := request("/items", "k1", "u1", `{"name":"a"}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() || created != 1 {
+		t.Errorf("Expected the first response to be replayed, got %d %s after %d creations", replay.Code, replay.Body.String(), created)
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" || replay.Header().Get("Location") != "/items/1" {
+		t.Errorf("Expected the replay to be marked and carry the stored headers, got %v", replay.Header())
+	}
+
+	// Keys are per user
+	if w := request("/items", "k1", "u2", `{"name":"a"}`); w.Header().Get("Idempotent-Replayed") != "" || created != 2 {
+		t.Errorf("Expected another user's key to be separate, got %v", w.Header())
+	}
+
+	// A different payload under the same key is rejected
+	if w := request("/items", "k1", "u1", `{"name":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a reused key, got %d", w.Code)
+	}
+
+	// Server errors release the key, so the retry runs the handler
+	if w := request("/items?fail=1", "k2", "u1", ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", w.Code)
+	}
+	if w := request("/items?fail=1", "k2", "u1", ""); w.Code != http.StatusInternalServerError || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected the failed request to run again, got %d", w.Code)
+	}
+
+	// A duplicate of a request in flight gets 409
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- request("/items?hold=1", "k3", "u1", "") }()
+	<-started
+	if w := request("/items?hold=1", "k3", "u1", ""); w.Code != http.StatusConflict || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected 409 with Retry-After for a request in flight, got %d", w.Code)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("Expected the original request to complete, got %d", w.Code)
+	}
+
+	// Requests without a key aren't affected
+	before := created
+	request("/items", "", "u1", "")
+	request("/items", "", "u1", "")
+	if created != before+2 {
+		t.Errorf("Expected requests without a key to run every time, got %d creations", created-before)
+	}
+
+	if w := request("/items", strings.Repeat("k", 256), "u1", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an overlong key, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareBehindCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := idempotency.NewRedisStore(client, "idempotency:")
+
+	large := strings.Repeat(`{"name":"compressible"}`, 100)
+
+	router := gin.New()
+	router.Use(CompressionMiddleware(config.CompressionConfig{Encodings: []string{"gzip"}, MinSize: 256}))
+	router.Use(IdempotencyMiddleware(store, config.IdempotencyConfig{TTL: time.Hour, LockTimeout: time.Minute}, log))
+	router.POST("/items", func(c *gin.Context) { c.Data(http.StatusCreated, "application/json", []byte(large)) })
+
+	request := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items", nil)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) string {
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			return w.Body.String()
+		}
+		reader, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Failed to decode the response: %v", err)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to decode the response: %v", err)
+		}
+		return string(body)
+	}
+
+	// The stored body is the handler's, encoded again for each replay
+	if first := request("gzip"); decode(first) != large {
+		t.Fatalf("Expected the first response to decode to the body, got %q", first.Body.String())
+	}
+	replay := request("gzip")
+	if replay.Header().Get("Idempotent-Replayed") != "true" || decode(replay) != large {
+		t.Errorf("Expected the replay to decode to the body, got %q", replay.Body.String())
+	}
+	plain := request("identity")
+	if plain.Header().Get("Content-Encoding") != "" || plain.Body.String() != large {
+		t.Errorf("Expected an uncompressed replay for a client without gzip, got %v", plain.Header())
+	}
+}
+
+func TestIdempotencyMiddlewareSecretRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := idempotency.NewRedisStore(client, "idempotency:")
+
+	created := 0
+	router := gin.New()
+	router.Use(IdempotencyMiddleware(store, config.IdempotencyConfig{TTL: time.Hour, LockTimeout: time.Minute}, log, "/keys"))
+	router.POST("/keys", func(c *gin.Context) {
+		if c.Query("invalid") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "invalid"})
+			return
+		}
+		created++
+		c.JSON(http.StatusCreated, gin.H{"key": "sk_secret"})
+	})
+
+	request := func(url, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, nil)
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if first := request("/keys", "k1"); !strings.Contains(first.Body.String(), "sk_secret") {
+		t.Fatalf("Expected the first response to carry the secret, got %s", first.Body.String())
+	}
+
+	// The secret is neither stored nor replayed, only the status
+	stored, err := server.Get("idempotency::ip:192.0.2.1:k1")
+	if err != nil {
+		t.Fatalf("Failed to read the stored record: %v", err)
+	}
+	var record idempotency.Record
+	if err := json.Unmarshal([]byte(stored), &record); err != nil {
+		t.Fatalf("Failed to decode the stored record: %v", err)
+	}
+	if record.Status != http.StatusCreated || len(record.Body) != 0 {
+		t.Errorf("Expected only the status to be stored, got %s", stored)
+	}
+	replay := request("/keys", "k1")
+	if replay.Code != http.StatusCreated || strings.Contains(replay.Body.String(), "sk_secret") || created != 1 {
+		t.Errorf("Expected a 201 replay without the secret, got %d %s after %d creations", replay.Code, replay.Body.String(), created)
+	}
+	if !strings.Contains(replay.Body.String(), "can't be shown again") || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the replay to say the secret can't be shown again, got %v %s", replay.Header(), replay.Body.String())
+	}
+
+	// Errors carry no secret and are replayed as they were
+	invalid := request("/keys?invalid=1", "k2")
+	if replay := request("/keys?invalid=1", "k2"); replay.Code != http.StatusBadRequest || replay.Body.String() != invalid.Body.String() {
+		t.Errorf("Expected the error to be replayed, got %d %s", replay.Code, replay.Body.String())
+	}
+}
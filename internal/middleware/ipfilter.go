@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/ipfilter"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// IPChecker decides whether a client may reach a route group
+type IPChecker interface {
+	Check(group string, ip netip.Addr) ipfilter.Decision
+}
+
+// IPFilterMiddleware rejects clients the filter denies for the route group with 403 and
+// logs the rule that denied them. Register it after ClientIPMiddleware.
+func IPFilterMiddleware(filter IPChecker, group string, logger *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientIP := ClientIP(c)
+
+		// An unparseable IP matches no network, so only allow lists reject it
+		ip, _ := netip.ParseAddr(clientIP)
+
+		decision := filter.Check(group, ip)
+		if decision.Allowed {
+			c.Next()
+			return
+		}
+
+		logger.WithRequestID(c.GetString("request_id")).WithFields(map[string]interface{}{
+			"group":     group,
+			"rule":      decision.Rule,
+			"country":   decision.Country,
+			"client_ip": clientIP,
+			"method":    c.Request.Method,
+			"path":      c.Request.URL.Path,
+		}).Warn("Request denied by IP filter")
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Access denied",
+			"error": gin.H{
+				"code":    "IP_NOT_ALLOWED",
+				"message": "Requests from your network are not allowed",
+			},
+			"timestamp":  time.Now(),
+			"request_id": c.GetString("request_id"),
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/ipfilter"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// denyAdminFilter denies 192.0.2.0/24 from the admin group
+type denyAdminFilter struct{}
+
+func (denyAdminFilter) Check(group string, ip netip.Addr) ipfilter.Decision {
+	if group == "admin" && netip.MustParsePrefix("192.0.2.0/24").Contains(ip) {
+		return ipfilter.Decision{Rule: "deny 192.0.2.0/24"}
+	}
+	return ipfilter.Decision{Allowed: true}
+}
+
+func TestIPFilterMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(ClientIPMiddleware(nil, nil))
+	router.GET("/admin", IPFilterMiddleware(denyAdminFilter{}, "admin", log), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api", IPFilterMiddleware(denyAdminFilter{}, "api", log), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("/admin", "192.0.2.1:1234"); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a denied network, got %d", code)
+	}
+	if code := request("/admin", "198.51.100.1:1234"); code != http.StatusOK {
+		t.Errorf("Expected other networks to pass, got %d", code)
+	}
+	if code := request("/api", "192.0.2.1:1234"); code != http.StatusOK {
+		t.Errorf("Expected the rule to apply to its group only, got %d", code)
+	}
+}
@@ -10,12 +10,16 @@ import (
 // LoggingMiddleware logs HTTP requests with structured logging
 func LoggingMiddleware(logger *logger.Logger) gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		// Get request ID from context
+		// Get request ID and client IP from context
 		requestID := ""
+		clientIP := param.ClientIP
 		if param.Keys != nil {
 			if id, exists := param.Keys["request_id"]; exists {
 				requestID = id.(string)
 			}
+			if ip, exists := param.Keys["client_ip"]; exists {
+				clientIP = ip.(string)
+			}
 		}
 
 		// Create a contextual logger with request ID
@@ -26,7 +30,7 @@ func LoggingMiddleware(logger *logger.Logger) gin.HandlerFunc {
 			param.Method,
 			param.Path,
 			param.Request.UserAgent(),
-			param.ClientIP,
+			clientIP,
 			param.StatusCode,
 			param.Latency.Nanoseconds()/1e6, // Convert to milliseconds
 		)
@@ -37,7 +41,7 @@ func LoggingMiddleware(logger *logger.Logger) gin.HandlerFunc {
 				"method":      param.Method,
 				"path":        param.Path,
 				"status_code": param.StatusCode,
-				"client_ip":   param.ClientIP,
+				"client_ip":   clientIP,
 				"user_agent":  param.Request.UserAgent(),
 				"error":       param.ErrorMessage,
 			}).Error("HTTP request failed")
@@ -63,7 +67,7 @@ func RecoveryMiddleware(logger *logger.Logger) gin.HandlerFunc {
 		contextLogger.WithFields(map[string]interface{}{
 			"method":    c.Request.Method,
 			"path":      c.Request.URL.Path,
-			"client_ip": c.ClientIP(),
+			"client_ip": ClientIP(c),
 			"panic":     recovered,
 		}).Error("Panic recovered")
 
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/maintenance"
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceSwitch reports the maintenance mode in effect
+type MaintenanceSwitch interface {
+	State() maintenance.State
+}
+
+// MaintenanceMiddleware enforces the maintenance mode of the switch. Full maintenance
+// answers every request with 503 and Retry-After; read-only mode does so for requests
+// other than GET, HEAD and OPTIONS. The exempt paths, such as health checks, and
+// clients in allowedIPs (CIDRs or IPs) get through. While a mode is on, responses carry
+// it in X-Maintenance-Mode. Register it after ClientIPMiddleware.
+func MaintenanceMiddleware(sw MaintenanceSwitch, allowedIPs []string, retryAfter time.Duration, exempt ...string) gin.HandlerFunc {
+	allowed := parseNetworks(allowedIPs)
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+	retryAfterValue := strconv.Itoa(int(retryAfter.Seconds()))
+
+	return func(c *gin.Context) {
+		state := sw.State()
+		if state.Mode == maintenance.ModeOff || state.Mode == "" {
+			c.Next()
+			return
+		}
+		c.Header("X-Maintenance-Mode", string(state.Mode))
+
+		if exemptPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		if state.Mode == maintenance.ModeReadOnly {
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				c.Next()
+				return
+			}
+		}
+		if ip, err := netip.ParseAddr(ClientIP(c)); err == nil && inNetworks(ip.Unmap(), allowed) {
+			c.Next()
+			return
+		}
+
+		code, message := "MAINTENANCE_MODE", "The service is down for maintenance"
+		if state.Mode == maintenance.ModeReadOnly {
+			code, message = "READ_ONLY_MODE", "The service is read-only during maintenance"
+		}
+		if state.Message != "" {
+			message = state.Message
+		}
+
+		c.Header("Retry-After", retryAfterValue)
+		abortRequest(c, http.StatusServiceUnavailable, code, message)
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/maintenance"
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceState is a MaintenanceSwitch for tests
+type maintenanceState struct {
+	state maintenance.State
+}
+
+func (m *maintenanceState) State() maintenance.State {
+	return m.state
+}
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sw := &maintenanceState{}
+	router := gin.New()
+	router.Use(ClientIPMiddleware(nil, nil))
+	router.Use(MaintenanceMiddleware(sw, []string{"10.0.0.0/8"}, 5*time.Minute, "/health"))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/items", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	request := func(method, path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	const client, admin = "192.0.2.1:1234", "10.1.2.3:1234"
+
+	tests := []struct {
+		mode       maintenance.Mode
+		method     string
+		path       string
+		remoteAddr string
+		want       int
+	}{
+		{maintenance.ModeOff, http.MethodPost, "/items", client, http.StatusCreated},
+		{maintenance.ModeReadOnly, http.MethodGet, "/items", client, http.StatusOK},
+		{maintenance.ModeReadOnly, http.MethodPost, "/items", client, http.StatusServiceUnavailable},
+		{maintenance.ModeReadOnly, http.MethodPost, "/items", admin, http.StatusCreated},
+		{maintenance.ModeFull, http.MethodGet, "/items", client, http.StatusServiceUnavailable},
+		{maintenance.ModeFull, http.MethodGet, "/health", client, http.StatusOK},
+		{maintenance.ModeFull, http.MethodPost, "/items", admin, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+" "+tt.method+" "+tt.path+" from "+tt.remoteAddr, func(t *testing.T) {
+			sw.state = maintenance.State{Mode: tt.mode}
+			w := request(tt.method, tt.path, tt.remoteAddr)
+			if w.Code != tt.want {
+				t.Fatalf("Expected %d, got %d", tt.want, w.Code)
+			}
+
+			wantHeader := string(tt.mode)
+			if tt.mode == maintenance.ModeOff {
+				wantHeader = ""
+			}
+			if got := w.Header().Get("X-Maintenance-Mode"); got != wantHeader {
+				t.Errorf("Expected X-Maintenance-Mode %q, got %q", wantHeader, got)
+			}
+			if w.Code == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "300" {
+				t.Errorf("Expected Retry-After: 300, got %q", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+
+	sw.state = maintenance.State{Mode: maintenance.ModeReadOnly, Message: "Back at 14:00 UTC"}
+	w := request(http.MethodPost, "/items", client)
+	if !strings.Contains(w.Body.String(), "READ_ONLY_MODE") || !strings.Contains(w.Body.String(), "Back at 14:00 UTC") {
+		t.Errorf("Expected READ_ONLY_MODE with the configured message, got %s", w.Body.String())
+	}
+}
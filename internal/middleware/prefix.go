@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+)
+
+// prefixValues holds values by path prefix, the longest matching prefix winning
+type prefixValues[T any] struct {
+	prefixes []string // longest first
+	values   map[string]T
+}
+
+// newPrefixValues indexes values by path prefix; trailing slashes are ignored
+func newPrefixValues[T any](values map[string]T) prefixValues[T] {
+	p := prefixValues[T]{values: make(map[string]T, len(values))}
+	for prefix, value := range values {
+		prefix = strings.TrimSuffix(prefix, "/")
+		p.prefixes = append(p.prefixes, prefix)
+		p.values[prefix] = value
+	}
+	sort.Slice(p.prefixes, func(i, j int) bool {
+		return len(p.prefixes[i]) > len(p.prefixes[j])
+	})
+	return p
+}
+
+// match returns the value of the longest prefix path is at or below
+func (p prefixValues[T]) match(path string) (T, bool) {
+	for _, prefix := range p.prefixes {
+		if pathHasPrefix(path, prefix) {
+			return p.values[prefix], true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// pathHasPrefix returns true if path is prefix or below it
+func pathHasPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/") || prefix == ""
+}
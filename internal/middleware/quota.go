@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaConsumer counts requests against the daily and monthly quotas of a user's plan.
+// It returns the period of an exhausted quota and when it resets, or an empty period if
+// the request is within quota.
+type QuotaConsumer interface {
+	ConsumeQuota(ctx context.Context, userID, role, plan string) (string, time.Time, error)
+}
+
+// QuotaMiddleware counts every authenticated request against the quotas of the user's
+// plan and rejects requests once a quota is used up, until it resets. Routes listed in
+// unmeteredRoutes (by their registered path) are neither counted nor rejected. Errors
+// of the consumer let requests pass unless failClosed is set. Register it after
+// AuthMiddleware.
+func QuotaMiddleware(quotas QuotaConsumer, failClosed bool, logger *logger.Logger, unmeteredRoutes ...string) gin.HandlerFunc {
+	unmetered := make(map[string]bool, len(unmeteredRoutes))
+	for _, route := range unmeteredRoutes {
+		unmetered[route] = true
+	}
+
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" || unmetered[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		period, resetsAt, err := quotas.ConsumeQuota(c.Request.Context(), userID, c.GetString("user_role"), c.GetString("user_plan"))
+		if err != nil {
+			logger.WithError(err).WithField("user_id", userID).Warn("Quota check failed")
+
+			if failClosed {
+				abortRateLimit(c, http.StatusServiceUnavailable, "RATE_LIMIT_UNAVAILABLE",
+					"Rate limiting is unavailable, please try again later")
+				return
+			}
+
+			c.Next()
+			return
+		}
+
+		if period != "" {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(time.Until(resetsAt), 1)))
+			abortRateLimit(c, http.StatusTooManyRequests, "QUOTA_EXCEEDED",
+				"The "+quotaPeriodNames[period]+" request quota of your plan is used up")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// quotaPeriodNames names quota periods in error messages
+var quotaPeriodNames = map[string]string{
+	model.UsagePeriodDay:   "daily",
+	model.UsagePeriodMonth: "monthly",
+}
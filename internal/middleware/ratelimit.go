@@ -1,110 +1,364 @@
 package middleware
 
 import (
+	"fmt"
+	"math"
 	"net/http"
-	"sync"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
-// RateLimiter holds the rate limiter instances for different clients
-type RateLimiter struct {
-	clients map[string]*rate.Limiter
-	mu      sync.Mutex
-	rate    rate.Limit
-	burst   int
-	cleanup time.Duration
+// rateLimitMiddleware rejects requests once the client identified by key has used up
+// the limit. Errors of the limiter let requests pass unless failClosed is set.
+func rateLimitMiddleware(limiter ratelimit.Limiter, name string, limit ratelimit.Limit,
+	key func(c *gin.Context) string, failClosed bool, logger *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := limiter.Allow(c.Request.Context(), name+":"+key(c), limit)
+		if err != nil {
+			logger.WithError(err).WithField("policy", name).Warn("Rate limit check failed")
+
+			if failClosed {
+				abortRateLimit(c, http.StatusServiceUnavailable, "RATE_LIMIT_UNAVAILABLE",
+					"Rate limiting is unavailable, please try again later")
+				return
+			}
+
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, result)
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter, 1)))
+			abortRateLimit(c, http.StatusTooManyRequests, "RATE_LIMIT_EXCEEDED", "Too many requests, please try again later")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setRateLimitHeaders adds the policy to RateLimit-Policy and, when it is the most
+// restrictive limit applied to the request so far, reports it in the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers (IETF draft-ietf-httpapi-ratelimit-headers)
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	c.Writer.Header().Add("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d",
+		result.Limit.Requests, ceilSeconds(result.Limit.Period, 1), result.Limit.Burst))
+
+	if value, exists := c.Get("rate_limit"); exists {
+		current := value.(ratelimit.Result)
+		if result.Remaining > current.Remaining ||
+			(result.Remaining == current.Remaining && result.ResetAfter <= current.ResetAfter) {
+			return
+		}
+	}
+	c.Set("rate_limit", result)
+
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter, 0)))
+}
+
+// ceilSeconds rounds a duration up to whole seconds, and to at least min
+func ceilSeconds(d time.Duration, min int) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < min {
+		return min
+	}
+	return seconds
+}
+
+// abortRateLimit aborts the request with a rate limit error
+func abortRateLimit(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": "Rate limit exceeded",
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+		"timestamp":  time.Now(),
+		"request_id": c.GetString("request_id"),
+	})
+	c.Abort()
+}
+
+// ipRateLimitKey identifies clients by IP
+func ipRateLimitKey(c *gin.Context) string {
+	return "ip:" + ClientIP(c)
+}
+
+// userRateLimitKey identifies authenticated clients by user ID and anonymous ones by IP
+func userRateLimitKey(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return ipRateLimitKey(c)
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(rps rate.Limit, burst int) *RateLimiter {
-	rl := &RateLimiter{
-		clients: make(map[string]*rate.Limiter),
-		rate:    rps,
-		burst:   burst,
-		cleanup: 5 * time.Minute,
+// apiKeyRateLimitKey identifies requests made with an API key by the key, so each of a
+// user's keys has its own allowance, and other requests like userRateLimitKey
+func apiKeyRateLimitKey(c *gin.Context) string {
+	if keyID := c.GetString("api_key_id"); keyID != "" {
+		return "key:" + keyID
 	}
+	return userRateLimitKey(c)
+}
+
+// RateLimits builds rate limiting middleware from the configured policies. Group
+// policies are added with Group where a route group is declared; route policies are
+// added to matching routes as they are registered through a RateLimitedGroup.
+type RateLimits struct {
+	cfg        config.RateLimitConfig
+	limiter    ratelimit.Limiter
+	failClosed bool
+	logger     *logger.Logger
+	enabled    bool
+	groups     map[string]config.RateLimitPolicy
+	routes     map[string]config.RateLimitPolicy // keyed by "METHOD /path" or "/path"
+	used       map[string]bool
+}
 
-	// Start cleanup goroutine
-	go rl.cleanupWorker()
+// NewRateLimits creates the rate limits for a rate limit configuration, counting
+// requests with the given limiter
+func NewRateLimits(cfg config.RateLimitConfig, limiter ratelimit.Limiter, logger *logger.Logger) *RateLimits {
+	rl := &RateLimits{
+		cfg:        cfg,
+		limiter:    limiter,
+		failClosed: cfg.FailureMode == "closed",
+		logger:     logger,
+		enabled:    cfg.Enabled,
+		groups:     map[string]config.RateLimitPolicy{},
+		routes:     map[string]config.RateLimitPolicy{},
+		used:       map[string]bool{},
+	}
+
+	for name, policy := range cfg.Policies {
+		if pattern, ok := normalizeRoutePattern(name); ok {
+			rl.routes[pattern] = policy
+		} else {
+			rl.groups[strings.ToLower(name)] = policy
+		}
+	}
 
 	return rl
 }
 
-// getLimiter returns the rate limiter for a client
-func (rl *RateLimiter) getLimiter(clientID string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// normalizeRoutePattern recognizes route pattern policy names. Config keys may have been
+// lowercased, so the method is upper-cased again.
+func normalizeRoutePattern(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "/") {
+		return name, true
+	}
 
-	limiter, exists := rl.clients[clientID]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.clients[clientID] = limiter
+	method, path, ok := strings.Cut(name, " ")
+	if !ok || !strings.HasPrefix(strings.TrimSpace(path), "/") {
+		return "", false
 	}
 
-	return limiter
+	return strings.ToUpper(method) + " " + strings.TrimSpace(path), true
 }
 
-// cleanupWorker removes inactive rate limiters
-func (rl *RateLimiter) cleanupWorker() {
-	ticker := time.NewTicker(rl.cleanup)
-	defer ticker.Stop()
+// Group returns the middleware enforcing the policy of a route group, if one is configured
+func (rl *RateLimits) Group(name string) []gin.HandlerFunc {
+	policy, ok := rl.groups[name]
+	if !rl.enabled || !ok {
+		return nil
+	}
+
+	rl.used[name] = true
+	return []gin.HandlerFunc{rl.policyMiddleware(name, policy)}
+}
 
-	for range ticker.C {
-		rl.mu.Lock()
-		for clientID, limiter := range rl.clients {
-			// Remove limiter if it hasn't been used recently
-			if limiter.Allow() {
-				// If limiter allows immediately, it's likely inactive
-				// We use a simple heuristic here
-				delete(rl.clients, clientID)
-			}
+// route returns the middleware enforcing the policy of a route, if one is configured.
+// A policy for the method and path wins over one for the path alone.
+func (rl *RateLimits) route(method, path string) []gin.HandlerFunc {
+	if !rl.enabled {
+		return nil
+	}
+
+	for _, pattern := range []string{method + " " + path, path} {
+		if policy, ok := rl.routes[pattern]; ok {
+			rl.used[pattern] = true
+			return []gin.HandlerFunc{rl.policyMiddleware(pattern, policy)}
 		}
-		rl.mu.Unlock()
 	}
+
+	return nil
 }
 
-// RateLimitMiddleware creates a rate limiting middleware
-func RateLimitMiddleware(rps float64, burst int) gin.HandlerFunc {
-	rl := NewRateLimiter(rate.Limit(rps), burst)
+// Unused lists the configured policies that didn't match any group or route, which
+// usually means a typo in the configuration
+func (rl *RateLimits) Unused() []string {
+	if !rl.enabled {
+		return nil
+	}
+
+	var unused []string
+	for name := range rl.groups {
+		if !rl.used[name] {
+			unused = append(unused, name)
+		}
+	}
+	for pattern := range rl.routes {
+		if !rl.used[pattern] {
+			unused = append(unused, pattern)
+		}
+	}
+	sort.Strings(unused)
+
+	return unused
+}
+
+// Routes wraps a route group so the routes registered on it, and on the groups created
+// from it, get their route policies
+func (rl *RateLimits) Routes(group *gin.RouterGroup) *RateLimitedGroup {
+	return &RateLimitedGroup{group: group, limits: rl}
+}
+
+// policyMiddleware creates the middleware enforcing a rate limit policy. The policy
+// name is part of the limiter key, so every policy counts separately.
+func (rl *RateLimits) policyMiddleware(name string, policy config.RateLimitPolicy) gin.HandlerFunc {
+	key := ipRateLimitKey
+	switch policy.Key {
+	case "user":
+		key = userRateLimitKey
+	case "api_key":
+		key = apiKeyRateLimitKey
+	}
+
+	limit := ratelimit.Limit{
+		Requests: policy.Requests,
+		Period:   policy.Period,
+		Burst:    policy.Burst,
+	}
+
+	anonymous := rateLimitMiddleware(rl.limiter, name, limit, key, rl.failClosed, rl.logger)
+	if !policy.UsePlan || len(rl.cfg.Plans) == 0 {
+		return anonymous
+	}
+
+	// Authenticated requests get the rate of their plan. Plans count separately, so a
+	// client moved to another plan starts with that plan's full allowance.
+	planned := make(map[string]gin.HandlerFunc, len(rl.cfg.Plans))
+	for planName, plan := range rl.cfg.Plans {
+		planLimit := ratelimit.Limit{
+			Requests: plan.Requests,
+			Period:   plan.Period,
+			Burst:    plan.Burst,
+		}
+		planned[planName] = rateLimitMiddleware(rl.limiter, name+":"+planName, planLimit, key, rl.failClosed, rl.logger)
+	}
 
 	return func(c *gin.Context) {
-		// Use client IP as the identifier
-		clientID := c.ClientIP()
-
-		// Get the rate limiter for this client
-		limiter := rl.getLimiter(clientID)
-
-		// Check if request is allowed
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"success": false,
-				"message": "Rate limit exceeded",
-				"error": gin.H{
-					"code":    "RATE_LIMIT_EXCEEDED",
-					"message": "Too many requests, please try again later",
-				},
-				"timestamp":  time.Now(),
-				"request_id": c.GetString("request_id"),
-			})
-			c.Abort()
-			return
+		if c.GetString("user_id") != "" {
+			if planName, _, ok := rl.cfg.Plan(c.GetString("user_role"), c.GetString("user_plan")); ok {
+				planned[planName](c)
+				return
+			}
 		}
+		anonymous(c)
+	}
+}
 
-		c.Next()
+// RateLimitedGroup is a route group that adds the configured route policy in front of
+// the handlers of each route registered on it. Route policies run after the group's
+// middleware, so they can rely on authentication. It doesn't embed the gin group, so
+// there is no way to register a route that skips its policy.
+type RateLimitedGroup struct {
+	group  *gin.RouterGroup
+	limits *RateLimits
+}
+
+// anyMethods are the methods Any registers, the same as gin's RouterGroup.Any
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+// Use adds middleware to the group
+func (g *RateLimitedGroup) Use(middleware ...gin.HandlerFunc) *RateLimitedGroup {
+	g.group.Use(middleware...)
+	return g
+}
+
+// BasePath returns the group's base path
+func (g *RateLimitedGroup) BasePath() string {
+	return g.group.BasePath()
+}
+
+// Group creates a rate limited child group
+func (g *RateLimitedGroup) Group(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.limits.Routes(g.group.Group(relativePath, handlers...))
+}
+
+// Handle registers a route with its route policy
+func (g *RateLimitedGroup) Handle(method, relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	fullPath := path.Join(g.BasePath(), relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(fullPath, "/") {
+		fullPath += "/"
+	}
+
+	handlers = append(g.limits.route(method, fullPath), handlers...)
+	g.group.Handle(method, relativePath, handlers...)
+	return g
+}
+
+// Match registers a route for each of the given methods with that method's route policy
+func (g *RateLimitedGroup) Match(methods []string, relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	for _, method := range methods {
+		g.Handle(method, relativePath, handlers...)
 	}
+	return g
+}
+
+// Any registers a route for every method gin's Any does, each with its route policy
+func (g *RateLimitedGroup) Any(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Match(anyMethods, relativePath, handlers...)
+}
+
+// GET registers a GET route with its route policy
+func (g *RateLimitedGroup) GET(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+// POST registers a POST route with its route policy
+func (g *RateLimitedGroup) POST(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+// PUT registers a PUT route with its route policy
+func (g *RateLimitedGroup) PUT(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+// PATCH registers a PATCH route with its route policy
+func (g *RateLimitedGroup) PATCH(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Handle(http.MethodPatch, relativePath, handlers...)
+}
+
+// DELETE registers a DELETE route with its route policy
+func (g *RateLimitedGroup) DELETE(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Handle(http.MethodDelete, relativePath, handlers...)
 }
 
-// AuthRateLimitMiddleware creates a more restrictive rate limiter for auth endpoints
-func AuthRateLimitMiddleware() gin.HandlerFunc {
-	// More restrictive for auth endpoints: 5 requests per minute
-	return RateLimitMiddleware(5.0/60.0, 5)
+// HEAD registers a HEAD route with its route policy
+func (g *RateLimitedGroup) HEAD(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Handle(http.MethodHead, relativePath, handlers...)
 }
 
-// APIRateLimitMiddleware creates a general rate limiter for API endpoints
-func APIRateLimitMiddleware() gin.HandlerFunc {
-	// General API: 100 requests per minute
-	return RateLimitMiddleware(100.0/60.0, 10)
+// OPTIONS registers an OPTIONS route with its route policy
+func (g *RateLimitedGroup) OPTIONS(relativePath string, handlers ...gin.HandlerFunc) *RateLimitedGroup {
+	return g.Handle(http.MethodOptions, relativePath, handlers...)
 }
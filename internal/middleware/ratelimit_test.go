@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// unavailableLimiter fails every check, like a Redis limiter without Redis
+type unavailableLimiter struct{}
+
+func (unavailableLimiter) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+// newRateLimitedRouter registers GET and POST /api/v1/items/:id with an api group
+// policy of 100 per minute and a route policy of 2 per minute for POST
+func newRateLimitedRouter(t *testing.T, limiter ratelimit.Limiter, failureMode string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	limits := NewRateLimits(config.RateLimitConfig{
+		Enabled:     true,
+		FailureMode: failureMode,
+		Policies: map[string]config.RateLimitPolicy{
+			"api":                    {Requests: 100, Period: time.Minute, Burst: 10, Key: "ip"},
+			"post /api/v1/items/:id": {Requests: 2, Period: time.Minute, Key: "user"},
+			"/api/v1/not-registered": {Requests: 1, Period: time.Minute, Key: "ip"},
+		},
+	}, limiter, log)
+
+	router := gin.New()
+	api := limits.Routes(router.Group("/api"))
+	api.Use(limits.Group("api")...)
+
+	items := api.Group("/v1/items")
+	items.GET("/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	items.POST("/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if unused := limits.Unused(); len(unused) != 1 || unused[0] != "/api/v1/not-registered" {
+		t.Errorf("Expected the unregistered route policy to be reported, got %v", unused)
+	}
+
+	return router
+}
+
+func serve(router *gin.Engine, method string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/items/1", nil))
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	router := newRateLimitedRouter(t, ratelimit.NewMemoryLimiter(0), "open")
+
+	// Only the group policy applies to GET
+	w := serve(router, http.MethodGet)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "10" {
+		t.Errorf("Expected RateLimit-Limit 10, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "9" {
+		t.Errorf("Expected RateLimit-Remaining 9, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Reset"); got != "1" {
+		t.Errorf("Expected RateLimit-Reset 1, got %q", got)
+	}
+
+	// The stricter route policy is reported for POST, and both policies are listed
+	w = serve(router, http.MethodPost)
+	if got := w.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("Expected RateLimit-Remaining 1, got %q", got)
+	}
+	if got := w.Header().Values("RateLimit-Policy"); len(got) != 2 || got[0] != "100;w=60;burst=10" || got[1] != "2;w=60;burst=2" {
+		t.Errorf("Expected both policies, got %v", got)
+	}
+
+	serve(router, http.MethodPost)
+	w = serve(router, http.MethodPost)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected RateLimit-Remaining 0, got %q", got)
+	}
+}
+
+func TestRateLimitFailureMode(t *testing.T) {
+	if w := serve(newRateLimitedRouter(t, unavailableLimiter{}, "open"), http.MethodGet); w.Code != http.StatusOK {
+		t.Errorf("Expected requests to pass when failing open, got %d", w.Code)
+	}
+
+	if w := serve(newRateLimitedRouter(t, unavailableLimiter{}, "closed"), http.MethodGet); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when failing closed, got %d", w.Code)
+	}
+}
+
+func TestRateLimitPlans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	limits := NewRateLimits(config.RateLimitConfig{
+		Enabled:     true,
+		FailureMode: "open",
+		DefaultPlan: "free",
+		Plans: map[string]config.RateLimitPlan{
+			"free":  {Requests: 1, Period: time.Minute},
+			"pro":   {Requests: 3, Period: time.Minute},
+			"admin": {Requests: 5, Period: time.Minute},
+		},
+		Policies: map[string]config.RateLimitPolicy{
+			"protected": {Requests: 10, Period: time.Minute, Key: "api_key", UsePlan: true},
+		},
+	}, ratelimit.NewMemoryLimiter(0), log)
+
+	// Stand-in for AuthMiddleware: the test headers carry the authenticated user
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User"))
+		c.Set("user_role", c.GetHeader("X-Role"))
+		c.Set("user_plan", c.GetHeader("X-Plan"))
+		c.Set("api_key_id", c.GetHeader("X-Key"))
+	})
+	router.Use(limits.Group("protected")...)
+	router.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"anonymous requests get the policy's rate", nil, "10"},
+		{"users without a plan get the default plan", map[string]string{"X-User": "u1", "X-Role": "user"}, "1"},
+		{"unknown plans fall back to the default plan", map[string]string{"X-User": "u2", "X-Role": "user", "X-Plan": "gold"}, "1"},
+		{"users get their plan", map[string]string{"X-User": "u3", "X-Role": "user", "X-Plan": "pro"}, "3"},
+		{"role plans win over the user's plan", map[string]string{"X-User": "u4", "X-Role": "admin", "X-Plan": "pro"}, "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := request(tt.headers).Header().Get("RateLimit-Limit"); got != tt.want {
+				t.Errorf("Expected RateLimit-Limit %s, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// Each API key has its own allowance, separate from the user's token requests
+	user := map[string]string{"X-User": "u5", "X-Role": "user"}
+	request(user)
+	if w := request(user); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the free plan to allow one request, got %d", w.Code)
+	}
+	if w := request(map[string]string{"X-User": "u5", "X-Role": "user", "X-Key": "k1"}); w.Code != http.StatusOK {
+		t.Errorf("Expected the API key to have its own allowance, got %d", w.Code)
+	}
+}
+
+func TestRateLimitedGroupAnyAndMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	limits := NewRateLimits(config.RateLimitConfig{
+		Enabled:     true,
+		FailureMode: "open",
+		Policies: map[string]config.RateLimitPolicy{
+			"/api/any":         {Requests: 1, Period: time.Minute, Key: "ip"},
+			"head /api/match":  {Requests: 1, Period: time.Minute, Key: "ip"},
+			"options /api/opt": {Requests: 1, Period: time.Minute, Key: "ip"},
+		},
+	}, ratelimit.NewMemoryLimiter(0), log)
+
+	router := gin.New()
+	api := limits.Routes(router.Group("/api"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.Any("/any", ok)
+	api.Match([]string{http.MethodGet, http.MethodHead}, "/match", ok)
+	api.OPTIONS("/opt", ok)
+
+	if unused := limits.Unused(); len(unused) != 0 {
+		t.Errorf("Expected every route policy to be used, got %v", unused)
+	}
+
+	// A path policy covers every method Any registered, so the second request is limited
+	for _, tt := range []struct{ path, first, second string }{
+		{"/api/any", http.MethodPut, http.MethodHead},
+		{"/api/match", http.MethodHead, http.MethodHead},
+		{"/api/opt", http.MethodOptions, http.MethodOptions},
+	} {
+		for i, method := range []string{tt.first, tt.second} {
+			want := http.StatusOK
+			if i > 0 {
+				want = http.StatusTooManyRequests
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, tt.path, nil))
+			if w.Code != want {
+				t.Errorf("%s %s: expected %d, got %d", method, tt.path, want, w.Code)
+			}
+		}
+	}
+
+	// GET /api/match has no route policy
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/match", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected GET /api/match to be unlimited, got %d", w.Code)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitMiddleware caps request bodies at maxSize bytes, or at the limit of the
+// longest path prefix in limits that matches; 0 lifts the cap. Oversized bodies are
+// rejected with 413 before handlers read them. Bodies of unknown length are read
+// up front for that, as JSON handlers would read them whole anyway.
+func BodyLimitMiddleware(maxSize int64, limits map[string]int64) gin.HandlerFunc {
+	routes := newPrefixValues(limits)
+
+	return func(c *gin.Context) {
+		limit, ok := routes.match(c.Request.URL.Path)
+		if !ok {
+			limit = maxSize
+		}
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			abortBodyTooLarge(c, limit)
+			return
+		}
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		if c.Request.ContentLength < 0 {
+			data, err := io.ReadAll(body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					abortBodyTooLarge(c, limit)
+					return
+				}
+				abortRequest(c, http.StatusBadRequest, "BAD_REQUEST", "Failed to read request body")
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(data))
+			c.Request.ContentLength = int64(len(data))
+		} else {
+			c.Request.Body = body
+		}
+
+		c.Next()
+	}
+}
+
+// abortBodyTooLarge aborts the request with 413
+func abortBodyTooLarge(c *gin.Context, limit int64) {
+	abortRequest(c, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+		"Request body must be at most "+strconv.FormatInt(limit, 10)+" bytes")
+}
+
+// timeoutWriter holds back the response of a handler until it is known whether the
+// handler finished in time, or until the handler flushes it. Once the deadline has
+// passed, whatever the handler writes is discarded. The handler's headers are kept apart
+// from the underlying writer's, so the timeout response can be written while the
+// handler is still running.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+
+	requestID string
+
+	mu        sync.Mutex
+	timedOut  bool // the timeout response was sent
+	committed bool // the handler flushed its response, which now goes straight through
+	done      bool // the handler returned in time
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code > 0 && !w.wroteHeader {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	if w.committed {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.committed {
+		return w.ResponseWriter.Size()
+	}
+	if !w.wroteHeader {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	return w.wroteHeader
+}
+
+// Flush commits the response: the header and what was written so far are sent, later
+// writes go straight through, and the timeout response can no longer replace it
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return
+	}
+	if !w.committed {
+		w.committed = true
+		w.writeResponse()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// timeout sends the timeout response, unless the handler has returned in time. It runs
+// on its own goroutine at the deadline, so it only touches the writers, never the
+// request's gin.Context, which the handler is still using.
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.done && !w.timedOut && !w.committed {
+		w.writeTimeout()
+	}
+}
+
+// writeTimeout writes the timeout response to the underlying writer; w.mu must be held
+func (w *timeoutWriter) writeTimeout() {
+	w.timedOut = true
+
+	message := "The request took too long to process"
+	body, _ := json.Marshal(gin.H{
+		"success": false,
+		"message": message,
+		"error": gin.H{
+			"code":    "REQUEST_TIMEOUT",
+			"message": message,
+		},
+		"timestamp":  time.Now(),
+		"request_id": w.requestID,
+	})
+
+	// The handler hasn't touched these headers, so only those of earlier middleware are
+	// there. With a length and no-transform, which keeps CompressionMiddleware from
+	// re-encoding it, the response is complete once flushed.
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Set("Cache-Control", "no-store, no-transform")
+	w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+	w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
+
+// finish writes the handler's response once it has returned, or the timeout response
+// if the deadline passed before the handler returned
+func (w *timeoutWriter) finish(deadlineExceeded bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return
+	}
+	if w.committed {
+		w.done = true
+		return
+	}
+	if deadlineExceeded {
+		w.writeTimeout()
+		return
+	}
+	w.done = true
+
+	if w.wroteHeader || w.status != http.StatusOK {
+		w.writeResponse()
+	} else {
+		w.copyHeader()
+	}
+}
+
+// writeResponse writes the handler's header and body so far to the underlying writer;
+// w.mu must be held
+func (w *timeoutWriter) writeResponse() {
+	w.copyHeader()
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+}
+
+// copyHeader replaces the underlying writer's header with the handler's
+func (w *timeoutWriter) copyHeader() {
+	header := w.ResponseWriter.Header()
+	for name := range header {
+		delete(header, name)
+	}
+	for name, values := range w.header {
+		header[name] = values
+	}
+}
+
+// TimeoutMiddleware cancels the request context once a handler has run for timeout,
+// or for the timeout of the longest path prefix in timeouts that matches; 0 means no
+// timeout. Responses are held back until the handler returns. At the deadline the
+// client is sent 503 REQUEST_TIMEOUT right away and anything the handler writes later
+// is discarded; the handler itself runs on until it honors the context. A handler that
+// flushes, as streaming responses do, commits its response: it is sent as written and
+// can't be replaced by the timeout response any more, though the context is still
+// cancelled at the deadline. Routes streaming for longer should have no timeout.
+func TimeoutMiddleware(timeout time.Duration, timeouts map[string]time.Duration) gin.HandlerFunc {
+	routes := newPrefixValues(timeouts)
+
+	return func(c *gin.Context) {
+		d, ok := routes.match(c.Request.URL.Path)
+		if !ok {
+			d = timeout
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		writer := &timeoutWriter{
+			ResponseWriter: original,
+			header:         original.Header().Clone(),
+			status:         http.StatusOK,
+			requestID:      c.GetString("request_id"),
+		}
+		c.Writer = writer
+
+		stop := context.AfterFunc(ctx, func() {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				writer.timeout()
+			}
+		})
+		defer stop()
+
+		c.Next()
+
+		c.Writer = original
+		writer.finish(errors.Is(ctx.Err(), context.DeadlineExceeded))
+	}
+}
+
+// abortRequest aborts the request with an error
+func abortRequest(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+		"timestamp":  time.Now(),
+		"request_id": c.GetString("request_id"),
+	})
+	c.Abort()
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(BodyLimitMiddleware(10, map[string]int64{"/upload": 0, "/api/v1/admin": 20}))
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	}
+	router.POST("/items", echo)
+	router.POST("/upload", echo)
+	router.POST("/api/v1/admin/users", echo)
+
+	request := func(path, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool
+		want    int
+	}{
+		{"bodies within the limit pass", "/items", 10, false, http.StatusOK},
+		{"oversized bodies are rejected", "/items", 11, false, http.StatusRequestEntityTooLarge},
+		{"oversized bodies of unknown length are rejected", "/items", 11, true, http.StatusRequestEntityTooLarge},
+		{"bodies of unknown length within the limit pass", "/items", 10, true, http.StatusOK},
+		{"prefixes raise the limit", "/api/v1/admin/users", 20, false, http.StatusOK},
+		{"a limit of 0 lifts the cap", "/upload", 1000, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.Repeat("a", tt.size)
+			w := request(tt.path, body, tt.chunked)
+			if w.Code != tt.want {
+				t.Fatalf("Expected %d, got %d", tt.want, w.Code)
+			}
+			if w.Code == http.StatusOK && w.Body.String() != body {
+				t.Errorf("Expected the handler to read the whole body, got %d bytes", w.Body.Len())
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", "req-1")
+		c.Next()
+	})
+	router.Use(TimeoutMiddleware(20*time.Millisecond, map[string]time.Duration{"/stream": 0}))
+
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.Header("ETag", `"1"`)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": c.Request.Context().Err().Error()})
+		case <-time.After(time.Second):
+			c.Status(http.StatusOK)
+		}
+	}
+	router.GET("/slow", slow)
+	router.GET("/stream", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, "streamed")
+	})
+	router.GET("/fast", func(c *gin.Context) {
+		c.Header("ETag", `"2"`)
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	router.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	request := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := request("/slow")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "REQUEST_TIMEOUT") {
+		t.Errorf("Expected 503 REQUEST_TIMEOUT, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != "" || w.Header().Get("X-Request-ID") != "req-1" {
+		t.Errorf("Expected the handler's headers to be dropped and earlier ones kept, got %v", w.Header())
+	}
+
+	if w := request("/fast"); w.Code != http.StatusCreated || w.Header().Get("ETag") != `"2"` || w.Body.String() != `{"ok":true}` {
+		t.Errorf("Expected the handler's response, got %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+	if w := request("/empty"); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", w.Code)
+	}
+	if w := request("/stream"); w.Code != http.StatusOK || w.Body.String() != "streamed" {
+		t.Errorf("Expected no deadline where the timeout is lifted, got %d", w.Code)
+	}
+}
+
+func TestTimeoutMiddlewareRespondsAtDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The handler ignores its context and only returns once the test is done
+	release := make(chan struct{})
+	router := gin.New()
+	// As in the server, compression wraps the timeout; it must not hold the response back
+	router.Use(CompressionMiddleware(config.CompressionConfig{Encodings: []string{"gzip"}, MinSize: 16}))
+	router.Use(TimeoutMiddleware(20*time.Millisecond, nil))
+	router.GET("/stuck", func(c *gin.Context) {
+		<-release
+		c.JSON(http.StatusOK, gin.H{"late": true})
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+	defer close(release)
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(server.URL + "/stuck")
+	if err != nil {
+		t.Fatalf("Expected the timeout response before the handler returns, got %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to read the response: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(body), "REQUEST_TIMEOUT") {
+		t.Errorf("Expected 503 REQUEST_TIMEOUT, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestTimeoutMiddlewareFlush(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(TimeoutMiddleware(20*time.Millisecond, nil))
+	router.GET("/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, "data: 1\n\n")
+		c.Writer.Flush()
+
+		// A flushed response is the handler's to finish, even past the deadline
+		<-c.Request.Context().Done()
+		c.String(http.StatusOK, "data: 2\n\n")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if !w.Flushed || w.Code != http.StatusOK || w.Body.String() != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("Expected the flushed stream, got %d %q (flushed %v)", w.Code, w.Body.String(), w.Flushed)
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected the handler's headers, got %v", w.Header())
+	}
+}
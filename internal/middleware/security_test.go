@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.SecurityConfig{
+		Headers: config.SecurityHeaders{
+			ContentSecurityPolicy:     "default-src 'none'",
+			FrameOptions:              "DENY",
+			CrossOriginResourcePolicy: "same-origin",
+		},
+		Routes: map[string]config.SecurityHeaders{
+			"/docs":       {ContentSecurityPolicy: "script-src 'nonce-{nonce}'", FrameOptions: "-"},
+			"/docs/embed": {CrossOriginResourcePolicy: "cross-origin"},
+		},
+		HSTS: config.HSTSConfig{Enabled: true, MaxAge: 365 * 24 * time.Hour, IncludeSubdomains: true},
+	}
+
+	newRouter := func(hsts bool) *gin.Engine {
+		router := gin.New()
+		router.Use(SecurityHeadersMiddleware(cfg, hsts))
+		router.GET("/*path", func(c *gin.Context) { c.String(http.StatusOK, CSPNonce(c)) })
+		return router
+	}
+	request := func(router *gin.Engine, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	router := newRouter(false)
+
+	w := request(router, "/api/v1/profile")
+	if got := w.Header().Get("Content-Security-Policy"); got != "default-src 'none'" {
+		t.Errorf("Expected the default CSP, got %q", got)
+	}
+	if w.Header().Get("X-Frame-Options") != "DENY" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Expected the default headers, got %v", w.Header())
+	}
+	if w.Header().Get("X-XSS-Protection") != "" || w.Header().Get("Strict-Transport-Security") != "" {
+		t.Errorf("Expected no X-XSS-Protection and no HSTS outside release mode, got %v", w.Header())
+	}
+
+	// Overrides replace the values they set and keep the rest
+	w = request(router, "/docs/index.html")
+	nonce := w.Body.String()
+	if nonce == "" || w.Header().Get("Content-Security-Policy") != "script-src 'nonce-"+nonce+"'" {
+		t.Errorf("Expected the CSP to carry the request's nonce %q, got %q", nonce, w.Header().Get("Content-Security-Policy"))
+	}
+	if w.Header().Get("X-Frame-Options") != "" || w.Header().Get("Cross-Origin-Resource-Policy") != "same-origin" {
+		t.Errorf("Expected the override to drop X-Frame-Options only, got %v", w.Header())
+	}
+	if again := request(router, "/docs").Body.String(); again == nonce {
+		t.Error("Expected a new nonce for every request")
+	}
+
+	// The longest prefix wins, and prefixes match whole path segments
+	if got := request(router, "/docs/embed/x").Header().Get("Cross-Origin-Resource-Policy"); got != "cross-origin" {
+		t.Errorf("Expected the longest prefix to win, got %q", got)
+	}
+	if got := request(router, "/docsearch").Header().Get("Content-Security-Policy"); got != "default-src 'none'" {
+		t.Errorf("Expected /docsearch not to match /docs, got %q", got)
+	}
+
+	if got := request(newRouter(true), "/").Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("Expected HSTS in release mode, got %q", got)
+	}
+}
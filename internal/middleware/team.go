@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// TeamRoleChecker looks up a user's role in a team
+type TeamRoleChecker interface {
+	GetMemberRole(ctx context.Context, teamID, userID string) (string, error)
+}
+
+// TeamRoleMiddleware checks that the current user belongs to the team in the :team_id
+// path parameter with at least minRole, and stores their role as "team_role".
+// Non-members get 404 so team IDs can't be probed.
+func TeamRoleMiddleware(checker TeamRoleChecker, minRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := checker.GetMemberRole(c.Request.Context(), c.Param("team_id"), c.GetString("user_id"))
+		if err != nil {
+			if err.Error() == "membership not found" {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Team not found",
+					"error": gin.H{
+						"code":    "TEAM_NOT_FOUND",
+						"message": "Team not found",
+					},
+					"timestamp":  time.Now(),
+					"request_id": c.GetString("request_id"),
+				})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Internal server error",
+					"error": gin.H{
+						"code":    "INTERNAL_ERROR",
+						"message": "Failed to check team membership",
+					},
+					"timestamp":  time.Now(),
+					"request_id": c.GetString("request_id"),
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		if !model.TeamRoleAtLeast(role, minRole) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Insufficient team role",
+				"error": gin.H{
+					"code":    "INSUFFICIENT_TEAM_ROLE",
+					"message": "This action requires the " + minRole + " team role",
+				},
+				"timestamp":  time.Now(),
+				"request_id": c.GetString("request_id"),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("team_role", role)
+		c.Next()
+	}
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/auth"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// allTenantsRef is the tenant header value super admins send to operate across tenants
+const allTenantsRef = "*"
+
+// TenantResolver looks up an organization by ID or slug
+type TenantResolver interface {
+	ResolveTenant(ref string) (*model.Organization, error)
+}
+
+// TenantMiddleware resolves the tenant of a request and scopes the request context to
+// it, so every tenant-scoped query only sees that tenant's data. The tenant comes from
+// the token's tenant claim for authenticated users, otherwise from the tenant header,
+// the subdomain under the configured base domain, or the default tenant, in that order.
+// A header or subdomain naming another tenant than the token is rejected, except for
+// super admins, who may pick any tenant or send "*" to span all of them.
+// Register it after AuthMiddleware on protected routes.
+func TenantMiddleware(resolver TenantResolver, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ref := requestedTenant(c, cfg)
+
+		var claims *auth.Claims
+		if value, exists := c.Get("jwt_claims"); exists {
+			claims, _ = value.(*auth.Claims)
+		}
+		superAdmin := claims != nil && claims.Role == model.RoleSuperAdmin
+
+		if ref == allTenantsRef {
+			if !superAdmin {
+				abortTenant(c, http.StatusForbidden, "TENANT_MISMATCH", "Only super admins can operate across tenants")
+				return
+			}
+			c.Set("tenant_id", allTenantsRef)
+			c.Request = c.Request.WithContext(tenant.WithAllTenants(c.Request.Context()))
+			c.Next()
+			return
+		}
+
+		if ref == "" && claims != nil {
+			ref = claims.TenantID
+		}
+		if ref == "" {
+			ref = cfg.Tenant.DefaultSlug
+		}
+		if ref == "" {
+			abortTenant(c, http.StatusBadRequest, "TENANT_REQUIRED", "The request must name a tenant")
+			return
+		}
+
+		org, err := resolver.ResolveTenant(ref)
+		if err != nil {
+			if err.Error() == "organization not found" {
+				abortTenant(c, http.StatusNotFound, "TENANT_NOT_FOUND", "Tenant not found")
+			} else {
+				abortTenant(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to resolve tenant")
+			}
+			return
+		}
+
+		// Authenticated users are bound to their token's tenant unless they are super admins
+		if claims != nil && !superAdmin && claims.TenantID != "" && claims.TenantID != org.ID {
+			abortTenant(c, http.StatusForbidden, "TENANT_MISMATCH", "The token belongs to another tenant")
+			return
+		}
+
+		if !org.IsActive && !superAdmin {
+			abortTenant(c, http.StatusForbidden, "TENANT_INACTIVE", "Tenant is inactive")
+			return
+		}
+
+		c.Set("tenant_id", org.ID)
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), org.ID))
+		c.Next()
+	}
+}
+
+// requestedTenant returns the tenant a request explicitly names through the tenant
+// header or its subdomain, or an empty string
+func requestedTenant(c *gin.Context, cfg *config.Config) string {
+	if ref := strings.TrimSpace(c.GetHeader(cfg.Tenant.Header)); ref != "" {
+		return ref
+	}
+
+	if cfg.Tenant.BaseDomain == "" {
+		return ""
+	}
+
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	suffix := "." + strings.ToLower(cfg.Tenant.BaseDomain)
+	label, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+	if !ok || label == "" || strings.Contains(label, ".") {
+		return ""
+	}
+
+	return label
+}
+
+// abortTenant aborts the request with a tenant resolution error
+func abortTenant(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+		"timestamp":  time.Now(),
+		"request_id": c.GetString("request_id"),
+	})
+	c.Abort()
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"mime"
 	"net/http"
 	"strings"
 	"time"
@@ -93,19 +94,34 @@ func getValidationMessage(fieldErr validator.FieldError) string {
 	}
 }
 
-// ValidateJSON middleware for JSON payload validation
-func ValidateJSON() gin.HandlerFunc {
+// jsonMediaTypes are the JSON media types accepted for request bodies
+var jsonMediaTypes = map[string]bool{
+	"application/json":             true,
+	"application/merge-patch+json": true, // RFC 7386 JSON Merge Patch
+	"application/json-patch+json":  true, // RFC 6902 JSON Patch
+}
+
+// ValidateJSON middleware for JSON payload validation. Routes listed in multipartRoutes
+// (by their registered path, e.g. "/api/v1/profile/avatar") also accept multipart/form-data.
+func ValidateJSON(multipartRoutes ...string) gin.HandlerFunc {
+	multipart := make(map[string]bool, len(multipartRoutes))
+	for _, route := range multipartRoutes {
+		multipart[route] = true
+	}
+
 	return func(c *gin.Context) {
-		// Check if content-type is JSON for POST, PUT, PATCH requests
-		if c.Request.Method == "POST" || c.Request.Method == "PUT" || c.Request.Method == "PATCH" {
-			contentType := c.GetHeader("Content-Type")
-			if !strings.Contains(contentType, "application/json") {
+		// Check if content-type is JSON for POST, PUT, PATCH requests that carry a body
+		if (c.Request.Method == "POST" || c.Request.Method == "PUT" || c.Request.Method == "PATCH") && c.Request.ContentLength != 0 {
+			mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+			mediaType = strings.ToLower(mediaType)
+			allowed := jsonMediaTypes[mediaType] || (mediaType == "multipart/form-data" && multipart[c.FullPath()])
+			if err != nil || !allowed {
 				c.JSON(http.StatusBadRequest, gin.H{
 					"success": false,
 					"message": "Invalid content type",
 					"error": gin.H{
 						"code":    "INVALID_CONTENT_TYPE",
-						"message": "Content-Type must be application/json",
+						"message": "Content-Type must be application/json, application/merge-patch+json or application/json-patch+json",
 					},
 					"timestamp":  time.Now(),
 					"request_id": c.GetString("request_id"),
@@ -0,0 +1,17 @@
+-- Full-text and fuzzy search over users
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Names weigh more than the email. The 'simple' configuration keeps names and emails
+-- unstemmed, and an email address stays a single lexeme so prefix queries match it.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(email, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+
+-- Trigram indexes for similarity matching of misspelled names and emails
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN ((first_name || ' ' || last_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
@@ -0,0 +1,6 @@
+-- Emails are unique per tenant (idx_users_tenant_email) since organizations were
+-- added. AutoMigrate never drops indexes, so databases created before that still have
+-- the global unique index on users.email, which rejects an email registered in a
+-- second tenant.
+
+DROP INDEX IF EXISTS idx_users_email;
@@ -0,0 +1,21 @@
+-- Teams, memberships, invitations, activity, API keys and usage counters are
+-- tenant-scoped since organizations were added. Rows created before that take the
+-- tenant of the user or team they belong to.
+
+UPDATE teams SET tenant_id = users.tenant_id
+    FROM users WHERE users.id = teams.created_by AND teams.tenant_id IS NULL;
+
+UPDATE team_memberships SET tenant_id = teams.tenant_id
+    FROM teams WHERE teams.id = team_memberships.team_id AND team_memberships.tenant_id IS NULL;
+
+UPDATE team_invitations SET tenant_id = teams.tenant_id
+    FROM teams WHERE teams.id = team_invitations.team_id AND team_invitations.tenant_id IS NULL;
+
+UPDATE user_activities SET tenant_id = users.tenant_id
+    FROM users WHERE users.id = user_activities.user_id AND user_activities.tenant_id IS NULL;
+
+UPDATE api_keys SET tenant_id = users.tenant_id
+    FROM users WHERE users.id = api_keys.user_id AND api_keys.tenant_id IS NULL;
+
+UPDATE usage_counters SET tenant_id = users.tenant_id
+    FROM users WHERE users.id = usage_counters.user_id AND usage_counters.tenant_id IS NULL;
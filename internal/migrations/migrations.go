@@ -0,0 +1,10 @@
+// Package migrations holds the versioned SQL migrations applied at startup by
+// database.MigrateSQL, for schema changes AutoMigrate can't express.
+package migrations
+
+import "embed"
+
+// FS contains the SQL migration files, named <version>_<description>.sql
+//
+//go:embed *.sql
+var FS embed.FS
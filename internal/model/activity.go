@@ -0,0 +1,61 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Activity actions recorded in a user's timeline
+const (
+	ActivityLogin              = "login"
+	ActivityProfileUpdated     = "profile_updated"
+	ActivityPasswordChanged    = "password_changed"
+	ActivityAvatarUpdated      = "avatar_updated"
+	ActivityAvatarDeleted      = "avatar_deleted"
+	ActivityPreferencesUpdated = "preferences_updated"
+	ActivityUserUpdated        = "admin_user_updated"
+	ActivityUserDeleted        = "admin_user_deleted"
+	ActivityUserErased         = "user_erased"
+	ActivityDataExported       = "data_exported"
+	ActivityDeletionScheduled  = "deletion_scheduled"
+	ActivityDeletionCancelled  = "deletion_cancelled"
+	ActivityAccountDeleted     = "account_deleted"
+)
+
+// errActivityImmutable is returned when code tries to modify or delete recorded activity
+var errActivityImmutable = errors.New("user activity is append-only")
+
+// UserActivity is an entry in a user's append-only activity timeline
+type UserActivity struct {
+	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    string    `json:"user_id" gorm:"type:uuid;not null;index:idx_user_activities_user_id_id,priority:1"`
+	TenantID  string    `json:"-" gorm:"type:uuid;index"` // The user's tenant
+	ActorID   string    `json:"actor_id" gorm:"not null"` // User who performed the action, "system" for automated actions
+	Action    string    `json:"action" gorm:"not null;index"`
+	IP        string    `json:"ip,omitempty"`
+	Details   JSONMap   `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName returns the table name for UserActivity model
+func (UserActivity) TableName() string {
+	return "user_activities"
+}
+
+// BeforeUpdate is a GORM hook that keeps recorded activity immutable
+func (a *UserActivity) BeforeUpdate(tx *gorm.DB) error {
+	return errActivityImmutable
+}
+
+// BeforeDelete is a GORM hook that keeps recorded activity immutable
+func (a *UserActivity) BeforeDelete(tx *gorm.DB) error {
+	return errActivityImmutable
+}
+
+// ActivityPage is one page of a user's activity timeline, newest first
+type ActivityPage struct {
+	Activities []UserActivity `json:"activities"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Pass as ?cursor= to fetch older entries; empty on the last page
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognize
+const APIKeyPrefix = "ak_"
+
+// APIKey authenticates requests on behalf of a user without a JWT. Only a hash of the
+// key is stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID     string     `json:"-" gorm:"type:uuid;not null;index"`
+	TenantID   string     `json:"-" gorm:"type:uuid;index"` // The owner's tenant
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`            // Start of the key, shown to tell keys apart
+	KeyHash    string     `json:"-" gorm:"not null;uniqueIndex"`     // SHA-256 of the key, hex encoded
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`            // Updated at most once a minute
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" gorm:"index"` // Never expires when nil
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for APIKey model
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsExpired returns true if the key has expired at the given time
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// CreateAPIKeyRequest represents the request payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name          string `json:"name" binding:"required,min=1,max=100"`
+	ExpiresInDays int    `json:"expires_in_days,omitempty" binding:"omitempty,min=1,max=3650"`
+}
+
+// CreatedAPIKey is a newly created API key along with the key itself, which is not
+// retrievable later
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/featureflag"
+)
+
+// StringList is a list of strings stored in a jsonb column
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+
+	result := StringList{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	*l = result
+	return nil
+}
+
+// GormDataType returns the column type used for StringList fields
+func (StringList) GormDataType() string {
+	return "jsonb"
+}
+
+// FeatureFlag is a feature flag managed through the admin API. Flags stored here
+// replace flags with the same key in the flags file.
+type FeatureFlag struct {
+	Key          string     `json:"key" gorm:"primaryKey"`
+	Description  string     `json:"description"`
+	Enabled      bool       `json:"enabled" gorm:"default:false;not null"`
+	Rollout      int        `json:"rollout" gorm:"default:0;not null"` // percent of users, 0-100
+	Users        StringList `json:"users" gorm:"not null"`
+	Roles        StringList `json:"roles" gorm:"not null"`
+	EmailDomains StringList `json:"email_domains" gorm:"not null"`
+	UpdatedBy    string     `json:"updated_by,omitempty" gorm:"type:uuid"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for FeatureFlag model
+func (FeatureFlag) TableName() string {
+	return "feature_flags"
+}
+
+// Flag returns the flag for evaluation
+func (f *FeatureFlag) Flag() featureflag.Flag {
+	return featureflag.Flag{
+		Key:          f.Key,
+		Description:  f.Description,
+		Enabled:      f.Enabled,
+		Rollout:      f.Rollout,
+		Users:        f.Users,
+		Roles:        f.Roles,
+		EmailDomains: f.EmailDomains,
+	}
+}
+
+// FeatureFlagRequest represents the request payload for defining a feature flag
+type FeatureFlagRequest struct {
+	Description  string   `json:"description" binding:"max=500"`
+	Enabled      bool     `json:"enabled"`
+	Rollout      int      `json:"rollout" binding:"min=0,max=100"`
+	Users        []string `json:"users" binding:"max=1000"`
+	Roles        []string `json:"roles" binding:"max=20"`
+	EmailDomains []string `json:"email_domains" binding:"max=100"`
+}
@@ -0,0 +1,7 @@
+package model
+
+// MaintenanceRequest represents the request to switch the maintenance mode
+type MaintenanceRequest struct {
+	Mode    string `json:"mode" binding:"required,oneof=off read_only full"`
+	Message string `json:"message,omitempty" binding:"max=500"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultOrganizationSlug is the slug of the organization created at startup for
+// single-tenant deployments and for users that predate multi-tenancy
+const DefaultOrganizationSlug = "default"
+
+// organizationSlugPattern restricts slugs to values usable as a subdomain label
+var organizationSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// IsValidOrganizationSlug returns true if slug can identify an organization
+func IsValidOrganizationSlug(slug string) bool {
+	return organizationSlugPattern.MatchString(slug)
+}
+
+// Organization is a tenant: a customer whose users and data are isolated from other tenants
+type Organization struct {
+	ID        string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Name      string         `json:"name" gorm:"not null"`
+	Slug      string         `json:"slug" gorm:"uniqueIndex;not null"` // Used for subdomain and header resolution
+	IsActive  bool           `json:"is_active" gorm:"default:true;not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName returns the table name for Organization model
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// CreateOrganizationRequest represents the request payload for creating an organization
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+	Slug string `json:"slug" binding:"required,min=1,max=63"`
+}
+
+// UpdateOrganizationRequest represents the request payload for updating an organization
+type UpdateOrganizationRequest struct {
+	Name     string `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	IsActive *bool  `json:"is_active,omitempty"`
+}
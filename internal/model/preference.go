@@ -0,0 +1,90 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// JSONMap is a JSON object stored in a jsonb column
+type JSONMap map[string]interface{}
+
+// Value implements driver.Valuer
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (m *JSONMap) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = JSONMap{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONMap", value)
+	}
+
+	result := JSONMap{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	*m = result
+	return nil
+}
+
+// GormDataType returns the column type used for JSONMap fields
+func (JSONMap) GormDataType() string {
+	return "jsonb"
+}
+
+// preferenceKeyPattern restricts preference keys to identifiers that are safe to use
+// in index names and SQL expressions
+var preferenceKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,47}$`)
+
+// IsValidPreferenceKey returns true if the key can be used as a preference key
+func IsValidPreferenceKey(key string) bool {
+	return preferenceKeyPattern.MatchString(key)
+}
+
+// PreferenceSchema defines an allowed user preference key and the JSON Schema its value must satisfy
+type PreferenceSchema struct {
+	Key         string    `json:"key" gorm:"primaryKey"`
+	Description string    `json:"description"`
+	Schema      JSONMap   `json:"schema" gorm:"not null"`
+	Indexed     bool      `json:"indexed" gorm:"default:false;not null"` // Indexed keys can be used to filter the user list
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for PreferenceSchema model
+func (PreferenceSchema) TableName() string {
+	return "preference_schemas"
+}
+
+// Default returns the schema's default value, if it declares one
+func (p *PreferenceSchema) Default() (interface{}, bool) {
+	value, ok := p.Schema["default"]
+	return value, ok
+}
+
+// PreferenceSchemaRequest represents the request payload for defining a preference key
+type PreferenceSchemaRequest struct {
+	Description string  `json:"description"`
+	Schema      JSONMap `json:"schema" binding:"required"`
+	Indexed     bool    `json:"indexed"`
+}
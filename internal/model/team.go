@@ -0,0 +1,139 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Team membership roles, from least to most privileged
+const (
+	TeamRoleMember = "member"
+	TeamRoleAdmin  = "admin"
+	TeamRoleOwner  = "owner"
+)
+
+// teamRoleRanks orders team roles by privilege
+var teamRoleRanks = map[string]int{
+	TeamRoleMember: 1,
+	TeamRoleAdmin:  2,
+	TeamRoleOwner:  3,
+}
+
+// IsValidTeamRole returns true if the role is a known team role
+func IsValidTeamRole(role string) bool {
+	return teamRoleRanks[role] > 0
+}
+
+// TeamRoleAtLeast returns true if role grants at least the privileges of minRole
+func TeamRoleAtLeast(role, minRole string) bool {
+	return teamRoleRanks[role] > 0 && teamRoleRanks[role] >= teamRoleRanks[minRole]
+}
+
+// Team invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+// Team represents a group of users
+type Team struct {
+	ID          string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TenantID    string         `json:"-" gorm:"type:uuid;index"` // Organization the team belongs to; set from the request's tenant on create
+	Name        string         `json:"name" gorm:"not null"`
+	Description string         `json:"description"`
+	CreatedBy   string         `json:"created_by" gorm:"type:uuid;not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName returns the table name for Team model
+func (Team) TableName() string {
+	return "teams"
+}
+
+// TeamMembership links a user to a team with a per-team role
+type TeamMembership struct {
+	TeamID    string    `json:"team_id" gorm:"type:uuid;primaryKey"`
+	UserID    string    `json:"user_id" gorm:"type:uuid;primaryKey;index"`
+	TenantID  string    `json:"-" gorm:"type:uuid;index"` // Always the team's tenant
+	Role      string    `json:"role" gorm:"not null"`
+	CreatedAt time.Time `json:"joined_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for TeamMembership model
+func (TeamMembership) TableName() string {
+	return "team_memberships"
+}
+
+// TeamInvitation is an invitation for an email address to join a team
+type TeamInvitation struct {
+	ID          string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TeamID      string     `json:"team_id" gorm:"type:uuid;not null;index"`
+	TenantID    string     `json:"-" gorm:"type:uuid;index"` // Always the team's tenant; only users of that tenant can accept
+	Email       string     `json:"email" gorm:"not null;index"`
+	Role        string     `json:"role" gorm:"not null"`
+	Status      string     `json:"status" gorm:"default:pending;not null"`
+	InvitedBy   string     `json:"invited_by" gorm:"type:uuid;not null"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for TeamInvitation model
+func (TeamInvitation) TableName() string {
+	return "team_invitations"
+}
+
+// IsOpen returns true if the invitation can still be accepted or declined
+func (i *TeamInvitation) IsOpen() bool {
+	return i.Status == InvitationPending && time.Now().Before(i.ExpiresAt)
+}
+
+// TeamWithRole is a team together with the caller's role in it
+type TeamWithRole struct {
+	Team
+	Role string `json:"role"`
+}
+
+// TeamMember is a team member with the user details shown in member lists
+type TeamMember struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Role      string    `json:"role"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+// CreateTeamRequest represents the request payload for creating a team
+type CreateTeamRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=100"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+// UpdateTeamRequest represents the request payload for updating a team
+type UpdateTeamRequest struct {
+	Name        string  `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=500"`
+}
+
+// InviteTeamMemberRequest represents the request payload for inviting a user to a team
+type InviteTeamMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role,omitempty"` // Defaults to member
+}
+
+// UpdateTeamMemberRequest represents the request payload for changing a member's role
+type UpdateTeamMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// TransferTeamOwnershipRequest represents the request payload for handing a team to another member
+type TransferTeamOwnershipRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+)
+
+// Quota windows requests are counted in
+const (
+	UsagePeriodDay   = "day"
+	UsagePeriodMonth = "month"
+)
+
+// UsageCounter counts a user's API requests in one quota window
+type UsageCounter struct {
+	UserID      string    `json:"-" gorm:"type:uuid;primaryKey"`
+	TenantID    string    `json:"-" gorm:"type:uuid;index"`       // The user's tenant
+	Period      string    `json:"period" gorm:"primaryKey"`       // day, month
+	PeriodStart time.Time `json:"period_start" gorm:"primaryKey"` // Start of the UTC day or month
+	Count       int64     `json:"count" gorm:"not null;default:0"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for UsageCounter model
+func (UsageCounter) TableName() string {
+	return "usage_counters"
+}
+
+// QuotaUsage reports how much of a quota window has been used. Limit and Remaining
+// are omitted for unlimited quotas.
+type QuotaUsage struct {
+	Used      int64     `json:"used"`
+	Limit     int64     `json:"limit,omitempty"`
+	Remaining *int64    `json:"remaining,omitempty"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// PlanRateLimit describes the request rate of a plan
+type PlanRateLimit struct {
+	Requests      int `json:"requests"`
+	PeriodSeconds int `json:"period_seconds"`
+	Burst         int `json:"burst"`
+}
+
+// UsageReport is a user's plan and their consumption of its quotas
+type UsageReport struct {
+	Plan      string        `json:"plan"`
+	RateLimit PlanRateLimit `json:"rate_limit"`
+	Daily     QuotaUsage    `json:"daily"`
+	Monthly   QuotaUsage    `json:"monthly"`
+}
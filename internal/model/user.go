@@ -7,18 +7,39 @@ import (
 	"gorm.io/gorm"
 )
 
+// User roles. Admins manage their own tenant; super admins can also operate across tenants.
+const (
+	RoleUser       = "user"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "super_admin"
+)
+
+// IsAdminRole returns true if the role grants tenant admin privileges
+func IsAdminRole(role string) bool {
+	return role == RoleAdmin || role == RoleSuperAdmin
+}
+
 // User represents a user in the system
 type User struct {
-	ID        string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"` // Never serialize password
-	FirstName string         `json:"first_name" gorm:"not null"`
-	LastName  string         `json:"last_name" gorm:"not null"`
-	Role      string         `json:"role" gorm:"default:user;not null"`
-	IsActive  bool           `json:"is_active" gorm:"default:true;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                  string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TenantID            string         `json:"tenant_id" gorm:"type:uuid;uniqueIndex:idx_users_tenant_email,priority:1"` // Organization the user belongs to; set from the request's tenant on create
+	Email               string         `json:"email" gorm:"uniqueIndex:idx_users_tenant_email,priority:2;not null"`      // Unique within a tenant
+	Password            string         `json:"-" gorm:"not null"`                                                        // Never serialize password
+	FirstName           string         `json:"first_name" gorm:"not null"`
+	LastName            string         `json:"last_name" gorm:"not null"`
+	Role                string         `json:"role" gorm:"default:user;not null"`
+	Plan                string         `json:"plan" gorm:"not null;default:''"` // Rate limit plan, empty for the default plan
+	IsActive            bool           `json:"is_active" gorm:"default:true;not null"`
+	ErasedAt            *time.Time     `json:"erased_at,omitempty"`
+	DeletionScheduledAt *time.Time     `json:"deletion_scheduled_at,omitempty" gorm:"index"` // When a pending self-service deletion will be carried out
+	LastLoginAt         *time.Time     `json:"last_login_at,omitempty" gorm:"index"`
+	LastLoginIP         string         `json:"last_login_ip,omitempty"`
+	AvatarKey           string         `json:"-"`                                        // Blob key prefix of the current avatar images, empty if none
+	Preferences         JSONMap        `json:"preferences" gorm:"not null;default:'{}'"` // Validated against the admin-defined preference schemas
+	Version             int            `json:"version" gorm:"default:1;not null"`        // Incremented on every write for optimistic locking
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // TableName returns the table name for User model
@@ -62,33 +83,108 @@ func (u *User) GetFullName() string {
 
 // IsAdmin returns true if the user has admin role
 func (u *User) IsAdmin() bool {
-	return u.Role == "admin"
+	return IsAdminRole(u.Role)
 }
 
 // ToSafeUser returns a user struct without sensitive information
 func (u *User) ToSafeUser() SafeUser {
 	return SafeUser{
-		ID:        u.ID,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:                  u.ID,
+		TenantID:            u.TenantID,
+		Email:               u.Email,
+		FirstName:           u.FirstName,
+		LastName:            u.LastName,
+		Role:                u.Role,
+		Plan:                u.Plan,
+		IsActive:            u.IsActive,
+		ErasedAt:            u.ErasedAt,
+		DeletionScheduledAt: u.DeletionScheduledAt,
+		LastLoginAt:         u.LastLoginAt,
+		LastLoginIP:         u.LastLoginIP,
+		Avatar:              u.AvatarURLs(),
+		Preferences:         u.Preferences,
+		Version:             u.Version,
+		CreatedAt:           u.CreatedAt,
+		UpdatedAt:           u.UpdatedAt,
 	}
 }
 
 // SafeUser represents user data safe for API responses
 type SafeUser struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Role      string    `json:"role"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                  string            `json:"id"`
+	TenantID            string            `json:"tenant_id"`
+	Email               string            `json:"email"`
+	FirstName           string            `json:"first_name"`
+	LastName            string            `json:"last_name"`
+	Role                string            `json:"role"`
+	Plan                string            `json:"plan,omitempty"`
+	IsActive            bool              `json:"is_active"`
+	ErasedAt            *time.Time        `json:"erased_at,omitempty"`
+	DeletionScheduledAt *time.Time        `json:"deletion_scheduled_at,omitempty"`
+	LastLoginAt         *time.Time        `json:"last_login_at,omitempty"`
+	LastLoginIP         string            `json:"last_login_ip,omitempty"`
+	Avatar              map[string]string `json:"avatar,omitempty"` // Signed, expiring links keyed by size name
+	Preferences         JSONMap           `json:"preferences,omitempty"`
+	Version             int               `json:"version"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+}
+
+// UserSearchResult is a user matched by a search, with its relevance score and the
+// name and email with the matched fragments wrapped in <mark> tags
+type UserSearchResult struct {
+	User       SafeUser          `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// IsErased returns true if the user's personal data has been erased
+func (u *User) IsErased() bool {
+	return u.ErasedAt != nil
+}
+
+// IsDeletionScheduled returns true if the user has asked for their account to be deleted
+func (u *User) IsDeletionScheduled() bool {
+	return u.DeletionScheduledAt != nil
+}
+
+// AvatarSizes maps avatar size names to their square dimensions in pixels
+var AvatarSizes = map[string]int{
+	"small":  64,
+	"medium": 256,
+	"large":  512,
+}
+
+// avatarURLFunc builds links to stored avatar images. It is set at startup because
+// links are signed with server configuration the model doesn't know about.
+var avatarURLFunc func(key string) string
+
+// SetAvatarURLFunc sets the function used to build avatar links for SafeUser
+func SetAvatarURLFunc(fn func(key string) string) {
+	avatarURLFunc = fn
+}
+
+// AvatarObjectKey returns the blob key of an avatar image at the given size
+func AvatarObjectKey(prefix, size string) string {
+	return prefix + "/" + size + ".jpg"
+}
+
+// HasAvatar returns true if the user has uploaded an avatar
+func (u *User) HasAvatar() bool {
+	return u.AvatarKey != ""
+}
+
+// AvatarURLs returns links to the user's avatar at every size, or nil if none is set
+func (u *User) AvatarURLs() map[string]string {
+	if !u.HasAvatar() || avatarURLFunc == nil {
+		return nil
+	}
+
+	urls := make(map[string]string, len(AvatarSizes))
+	for size := range AvatarSizes {
+		urls[size] = avatarURLFunc(AvatarObjectKey(u.AvatarKey, size))
+	}
+	return urls
 }
 
 // CreateUserRequest represents the request payload for creating a user
@@ -106,6 +202,18 @@ type UpdateUserRequest struct {
 	FirstName string `json:"first_name,omitempty" binding:"omitempty,min=1"`
 	LastName  string `json:"last_name,omitempty" binding:"omitempty,min=1"`
 	Role      string `json:"role,omitempty"`
+	Plan      string `json:"plan,omitempty"`
+	IsActive  *bool  `json:"is_active,omitempty"`
+}
+
+// PatchUserDocument represents the patchable view of a user that JSON Merge Patch
+// and JSON Patch documents are applied to. Fields the caller may not patch are absent.
+type PatchUserDocument struct {
+	Email     string `json:"email" binding:"required,email"`
+	FirstName string `json:"first_name" binding:"required,min=1"`
+	LastName  string `json:"last_name" binding:"required,min=1"`
+	Role      string `json:"role,omitempty"`
+	Plan      string `json:"plan,omitempty"`
 	IsActive  *bool  `json:"is_active,omitempty"`
 }
 
@@ -117,8 +225,15 @@ type LoginRequest struct {
 
 // LoginResponse represents the response payload for user login
 type LoginResponse struct {
-	User  SafeUser `json:"user"`
-	Token string   `json:"token"`
+	User            SafeUser         `json:"user"`
+	Token           string           `json:"token"`
+	PendingDeletion *PendingDeletion `json:"pending_deletion,omitempty"` // Set if the account is scheduled for deletion
+}
+
+// PendingDeletion describes a scheduled account deletion and how to cancel it
+type PendingDeletion struct {
+	ScheduledFor time.Time `json:"scheduled_for"`
+	CancelURL    string    `json:"cancel_url"`
 }
 
 // ChangePasswordRequest represents the request payload for changing password
@@ -126,3 +241,20 @@ type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required,min=8"`
 }
+
+// EraseAccountRequest represents the request payload for erasing the caller's own account
+type EraseAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteAccountRequest represents the request payload for deleting the caller's own account
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DataExport represents everything stored about a user, keyed by data category
+type DataExport struct {
+	UserID      string                 `json:"user_id"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Data        map[string]interface{} `json:"data"`
+}
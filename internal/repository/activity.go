@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// ActivityRepository handles user activity data operations. Activity is append-only:
+// the model's hooks reject updates and deletes.
+type ActivityRepository struct {
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+// NewActivityRepository creates a new activity repository
+func NewActivityRepository(db *gorm.DB, logger *logger.Logger) *ActivityRepository {
+	return &ActivityRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create appends an entry to a user's activity timeline
+func (r *ActivityRepository) Create(ctx context.Context, activity *model.UserActivity) error {
+	if err := r.db.WithContext(ctx).Create(activity).Error; err != nil {
+		r.logger.LogError("Failed to record user activity", err)
+		return fmt.Errorf("failed to record user activity: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser retrieves a user's activity newest first. If beforeID is non-zero only
+// entries older than it are returned; a limit of zero returns every entry.
+func (r *ActivityRepository) ListByUser(ctx context.Context, userID string, beforeID int64, limit int) ([]model.UserActivity, error) {
+	var activities []model.UserActivity
+
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Order("id DESC").Find(&activities).Error; err != nil {
+		r.logger.LogError("Failed to list user activity", err)
+		return nil, fmt.Errorf("failed to list user activity: %w", err)
+	}
+
+	return activities, nil
+}
+
+// AnonymizeByUser removes IP addresses and details from a user's activity for erasure
+// requests. This is the only permitted modification of recorded activity, so it skips
+// the hooks that otherwise keep the table append-only.
+func (r *ActivityRepository) AnonymizeByUser(ctx context.Context, userID string) error {
+	err := r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).
+		Model(&model.UserActivity{}).
+		Where("user_id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"ip":      "",
+			"details": model.JSONMap{},
+		}).Error
+
+	if err != nil {
+		r.logger.LogError("Failed to anonymize user activity", err)
+		return fmt.Errorf("failed to anonymize user activity: %w", err)
+	}
+
+	r.logger.WithField("user_id", userID).Info("User activity anonymized successfully")
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository handles API key data operations
+type APIKeyRepository struct {
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB, logger *logger.Logger) *APIKeyRepository {
+	return &APIKeyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create creates an API key
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		r.logger.LogError("Failed to create API key", err)
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"api_key_id": key.ID,
+		"user_id":    key.UserID,
+	}).Info("API key created successfully")
+
+	return nil
+}
+
+// GetByHash retrieves an API key by the hash of the key
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("api key not found")
+		}
+		r.logger.LogError("Failed to get API key by hash", err)
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// ListByUser retrieves a user's API keys, newest first
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID string) ([]model.APIKey, error) {
+	var keys []model.APIKey
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		r.logger.LogError("Failed to list API keys", err)
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// CountByUser counts a user's API keys
+func (r *APIKeyRepository) CountByUser(ctx context.Context, userID string) (int64, error) {
+	var count int64
+
+	if err := r.db.WithContext(ctx).Model(&model.APIKey{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		r.logger.LogError("Failed to count API keys", err)
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	return count, nil
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&model.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+	if err != nil {
+		r.logger.LogError("Failed to record API key use", err)
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+
+	return nil
+}
+
+// Delete revokes one of a user's API keys
+func (r *APIKeyRepository) Delete(ctx context.Context, userID, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.APIKey{})
+	if result.Error != nil {
+		r.logger.LogError("Failed to delete API key", result.Error)
+		return fmt.Errorf("failed to delete API key: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"api_key_id": id,
+		"user_id":    userID,
+	}).Info("API key deleted successfully")
+
+	return nil
+}
+
+// DeleteByUser revokes all of a user's API keys
+func (r *APIKeyRepository) DeleteByUser(ctx context.Context, userID string) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.APIKey{}).Error; err != nil {
+		r.logger.LogError("Failed to delete user API keys", err)
+		return fmt.Errorf("failed to delete API keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FeatureFlagRepository handles feature flag data operations
+type FeatureFlagRepository struct {
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+// NewFeatureFlagRepository creates a new feature flag repository
+func NewFeatureFlagRepository(db *gorm.DB, logger *logger.Logger) *FeatureFlagRepository {
+	return &FeatureFlagRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List retrieves all feature flags ordered by key
+func (r *FeatureFlagRepository) List(ctx context.Context) ([]model.FeatureFlag, error) {
+	var flags []model.FeatureFlag
+
+	if err := r.db.WithContext(ctx).Order("key").Find(&flags).Error; err != nil {
+		r.logger.LogError("Failed to list feature flags", err)
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+// GetByKey retrieves a feature flag by key
+func (r *FeatureFlagRepository) GetByKey(ctx context.Context, key string) (*model.FeatureFlag, error) {
+	var flag model.FeatureFlag
+	err := r.db.WithContext(ctx).Where("key = ?", key).First(&flag).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("feature flag not found")
+		}
+		r.logger.LogError("Failed to get feature flag", err)
+		return nil, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+
+	return &flag, nil
+}
+
+// Save creates or replaces a feature flag
+func (r *FeatureFlagRepository) Save(ctx context.Context, flag *model.FeatureFlag) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"description", "enabled", "rollout", "users", "roles", "email_domains", "updated_by", "updated_at",
+		}),
+	}).Create(flag).Error
+
+	if err != nil {
+		r.logger.LogError("Failed to save feature flag", err)
+		return fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"key":     flag.Key,
+		"enabled": flag.Enabled,
+		"rollout": flag.Rollout,
+	}).Info("Feature flag saved successfully")
+
+	return nil
+}
+
+// Delete removes a feature flag
+func (r *FeatureFlagRepository) Delete(ctx context.Context, key string) error {
+	result := r.db.WithContext(ctx).Where("key = ?", key).Delete(&model.FeatureFlag{})
+	if result.Error != nil {
+		r.logger.LogError("Failed to delete feature flag", result.Error)
+		return fmt.Errorf("failed to delete feature flag: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("feature flag not found")
+	}
+
+	r.logger.WithField("key", key).Info("Feature flag deleted successfully")
+	return nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrganizationRepository handles organization (tenant) data operations
+type OrganizationRepository struct {
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *gorm.DB, logger *logger.Logger) *OrganizationRepository {
+	return &OrganizationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create creates a new organization
+func (r *OrganizationRepository) Create(org *model.Organization) error {
+	if err := r.db.Create(org).Error; err != nil {
+		r.logger.LogError("Failed to create organization", err)
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"organization_id": org.ID,
+		"slug":            org.Slug,
+	}).Info("Organization created successfully")
+
+	return nil
+}
+
+// GetByID retrieves an organization by ID
+func (r *OrganizationRepository) GetByID(id string) (*model.Organization, error) {
+	return r.getBy("id = ?", id)
+}
+
+// GetBySlug retrieves an organization by slug
+func (r *OrganizationRepository) GetBySlug(slug string) (*model.Organization, error) {
+	return r.getBy("slug = ?", slug)
+}
+
+// getBy retrieves the organization matching a single condition
+func (r *OrganizationRepository) getBy(condition string, value string) (*model.Organization, error) {
+	var org model.Organization
+	err := r.db.Where(condition, value).First(&org).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("organization not found")
+		}
+		r.logger.LogError("Failed to get organization", err)
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+// List retrieves all organizations ordered by slug
+func (r *OrganizationRepository) List() ([]model.Organization, error) {
+	var orgs []model.Organization
+
+	if err := r.db.Order("slug").Find(&orgs).Error; err != nil {
+		r.logger.LogError("Failed to list organizations", err)
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// Update updates an organization
+func (r *OrganizationRepository) Update(org *model.Organization) error {
+	if err := r.db.Save(org).Error; err != nil {
+		r.logger.LogError("Failed to update organization", err)
+		return fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	r.logger.WithField("organization_id", org.ID).Info("Organization updated successfully")
+	return nil
+}
+
+// EnsureDefault returns the default organization, creating it if needed, and upgrades
+// single-tenant data: users created before multi-tenancy are assigned to it and the
+// old globally unique email index is dropped in favour of the per-tenant one
+func (r *OrganizationRepository) EnsureDefault() (*model.Organization, error) {
+	org := &model.Organization{
+		Name:     "Default",
+		Slug:     model.DefaultOrganizationSlug,
+		IsActive: true,
+	}
+
+	if err := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "slug"}}, DoNothing: true}).Create(org).Error; err != nil {
+		r.logger.LogError("Failed to create default organization", err)
+		return nil, fmt.Errorf("failed to create default organization: %w", err)
+	}
+
+	org, err := r.GetBySlug(model.DefaultOrganizationSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	// Backfilling spans tenants by definition
+	result := r.db.WithContext(tenant.WithAllTenants(context.Background())).
+		Unscoped().
+		Model(&model.User{}).
+		Where("tenant_id IS NULL").
+		UpdateColumn("tenant_id", org.ID)
+	if result.Error != nil {
+		r.logger.LogError("Failed to assign users to the default organization", result.Error)
+		return nil, fmt.Errorf("failed to backfill user tenants: %w", result.Error)
+	}
+
+	if result.RowsAffected > 0 {
+		r.logger.WithField("count", result.RowsAffected).Info("Assigned existing users to the default organization")
+	}
+
+	if r.db.Migrator().HasIndex(&model.User{}, "idx_users_email") {
+		if err := r.db.Migrator().DropIndex(&model.User{}, "idx_users_email"); err != nil {
+			r.logger.LogError("Failed to drop global email index", err)
+			return nil, fmt.Errorf("failed to drop global email index: %w", err)
+		}
+	}
+
+	return org, nil
+}
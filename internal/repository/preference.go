@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PreferenceRepository handles preference schema data operations
+type PreferenceRepository struct {
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+// NewPreferenceRepository creates a new preference repository
+func NewPreferenceRepository(db *gorm.DB, logger *logger.Logger) *PreferenceRepository {
+	return &PreferenceRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List retrieves all preference schemas ordered by key
+func (r *PreferenceRepository) List() ([]model.PreferenceSchema, error) {
+	var schemas []model.PreferenceSchema
+
+	if err := r.db.Order("key").Find(&schemas).Error; err != nil {
+		r.logger.LogError("Failed to list preference schemas", err)
+		return nil, fmt.Errorf("failed to list preference schemas: %w", err)
+	}
+
+	return schemas, nil
+}
+
+// GetByKey retrieves the schema of a preference key
+func (r *PreferenceRepository) GetByKey(key string) (*model.PreferenceSchema, error) {
+	var schema model.PreferenceSchema
+	err := r.db.Where("key = ?", key).First(&schema).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("preference schema not found")
+		}
+		r.logger.LogError("Failed to get preference schema", err)
+		return nil, fmt.Errorf("failed to get preference schema: %w", err)
+	}
+
+	return &schema, nil
+}
+
+// Save creates or replaces a preference schema and creates or drops the expression
+// index on users.preferences so it matches the schema's Indexed flag
+func (r *PreferenceRepository) Save(schema *model.PreferenceSchema) error {
+	if !model.IsValidPreferenceKey(schema.Key) {
+		return fmt.Errorf("invalid preference key")
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"description", "schema", "indexed", "updated_at"}),
+		}).Create(schema).Error; err != nil {
+			return err
+		}
+
+		if schema.Indexed {
+			return tx.Exec(fmt.Sprintf(
+				"CREATE INDEX IF NOT EXISTS %s ON users ((preferences->>'%s'))",
+				preferenceIndexName(schema.Key), schema.Key,
+			)).Error
+		}
+		return tx.Exec("DROP INDEX IF EXISTS " + preferenceIndexName(schema.Key)).Error
+	})
+
+	if err != nil {
+		r.logger.LogError("Failed to save preference schema", err)
+		return fmt.Errorf("failed to save preference schema: %w", err)
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"key":     schema.Key,
+		"indexed": schema.Indexed,
+	}).Info("Preference schema saved successfully")
+
+	return nil
+}
+
+// Delete removes a preference schema and its index. Values already stored under the
+// key are left in place and simply stop being returned.
+func (r *PreferenceRepository) Delete(key string) error {
+	if !model.IsValidPreferenceKey(key) {
+		return fmt.Errorf("preference schema not found")
+	}
+
+	var rowsAffected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("key = ?", key).Delete(&model.PreferenceSchema{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+
+		return tx.Exec("DROP INDEX IF EXISTS " + preferenceIndexName(key)).Error
+	})
+
+	if err != nil {
+		r.logger.LogError("Failed to delete preference schema", err)
+		return fmt.Errorf("failed to delete preference schema: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("preference schema not found")
+	}
+
+	r.logger.WithField("key", key).Info("Preference schema deleted successfully")
+	return nil
+}
+
+// preferenceIndexName returns the name of the expression index for a preference key
+func preferenceIndexName(key string) string {
+	return "idx_users_pref_" + key
+}
@@ -0,0 +1,489 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errLastOwner is returned when a change would leave a team without an owner
+var errLastOwner = errors.New("team must have at least one owner")
+
+// TeamRepository handles team, membership and invitation data operations
+type TeamRepository struct {
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+// NewTeamRepository creates a new team repository
+func NewTeamRepository(db *gorm.DB, logger *logger.Logger) *TeamRepository {
+	return &TeamRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create creates a team with the given user as its first owner
+func (r *TeamRepository) Create(ctx context.Context, team *model.Team, ownerID string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&model.TeamMembership{
+			TeamID:   team.ID,
+			UserID:   ownerID,
+			TenantID: team.TenantID,
+			Role:     model.TeamRoleOwner,
+		}).Error
+	})
+
+	if err != nil {
+		r.logger.LogError("Failed to create team", err)
+		return fmt.Errorf("failed to create team: %w", err)
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"team_id":  team.ID,
+		"owner_id": ownerID,
+	}).Info("Team created successfully")
+
+	return nil
+}
+
+// GetByID retrieves a team by ID
+func (r *TeamRepository) GetByID(ctx context.Context, id string) (*model.Team, error) {
+	var team model.Team
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&team).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("team not found")
+		}
+		r.logger.LogError("Failed to get team by ID", err)
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+
+	return &team, nil
+}
+
+// ListByUser retrieves the teams a user belongs to along with their role in each
+func (r *TeamRepository) ListByUser(ctx context.Context, userID string) ([]model.TeamWithRole, error) {
+	var teams []model.TeamWithRole
+
+	err := r.db.WithContext(ctx).Model(&model.Team{}).
+		Select("teams.*, team_memberships.role").
+		Joins("JOIN team_memberships ON team_memberships.team_id = teams.id").
+		Where("team_memberships.user_id = ?", userID).
+		Order("teams.name").
+		Scan(&teams).Error
+	if err != nil {
+		r.logger.LogError("Failed to list user teams", err)
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	return teams, nil
+}
+
+// Update updates a team
+func (r *TeamRepository) Update(ctx context.Context, team *model.Team) error {
+	if err := r.db.WithContext(ctx).Save(team).Error; err != nil {
+		r.logger.LogError("Failed to update team", err)
+		return fmt.Errorf("failed to update team: %w", err)
+	}
+
+	r.logger.WithField("team_id", team.ID).Info("Team updated successfully")
+	return nil
+}
+
+// Delete soft deletes a team and removes its memberships and pending invitations
+func (r *TeamRepository) Delete(ctx context.Context, id string) error {
+	var rowsAffected int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&model.Team{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+
+		if err := tx.Where("team_id = ?", id).Delete(&model.TeamMembership{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.TeamInvitation{}).
+			Where("team_id = ? AND status = ?", id, model.InvitationPending).
+			Update("status", model.InvitationRevoked).Error
+	})
+
+	if err != nil {
+		r.logger.LogError("Failed to delete team", err)
+		return fmt.Errorf("failed to delete team: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("team not found")
+	}
+
+	r.logger.WithField("team_id", id).Info("Team deleted successfully")
+	return nil
+}
+
+// GetMembership retrieves a user's membership in a team
+func (r *TeamRepository) GetMembership(ctx context.Context, teamID, userID string) (*model.TeamMembership, error) {
+	var membership model.TeamMembership
+	err := r.db.WithContext(ctx).Where("team_id = ? AND user_id = ?", teamID, userID).First(&membership).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("membership not found")
+		}
+		r.logger.LogError("Failed to get team membership", err)
+		return nil, fmt.Errorf("failed to get team membership: %w", err)
+	}
+
+	return &membership, nil
+}
+
+// ListMembers retrieves a team's members with their user details
+func (r *TeamRepository) ListMembers(ctx context.Context, teamID string) ([]model.TeamMember, error) {
+	var members []model.TeamMember
+
+	err := r.db.WithContext(ctx).Model(&model.TeamMembership{}).
+		Select("users.id AS user_id, users.email, users.first_name, users.last_name, team_memberships.role, team_memberships.created_at AS joined_at").
+		Joins("JOIN users ON users.id = team_memberships.user_id AND users.deleted_at IS NULL").
+		Where("team_memberships.team_id = ?", teamID).
+		Order("team_memberships.created_at").
+		Scan(&members).Error
+	if err != nil {
+		r.logger.LogError("Failed to list team members", err)
+		return nil, fmt.Errorf("failed to list team members: %w", err)
+	}
+
+	return members, nil
+}
+
+// ListMembershipsByUser retrieves every membership of a user
+func (r *TeamRepository) ListMembershipsByUser(ctx context.Context, userID string) ([]model.TeamMembership, error) {
+	var memberships []model.TeamMembership
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		r.logger.LogError("Failed to list user memberships", err)
+		return nil, fmt.Errorf("failed to list memberships: %w", err)
+	}
+
+	return memberships, nil
+}
+
+// UpdateMemberRole changes a member's role. The team's owner rows are locked so two
+// concurrent demotions can't both pass the last-owner check.
+func (r *TeamRepository) UpdateMemberRole(ctx context.Context, teamID, userID, role string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		membership, err := lockMembershipForChange(tx, teamID, userID)
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == model.TeamRoleOwner && role != model.TeamRoleOwner {
+			if err := ensureAnotherOwner(tx, teamID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(membership).Where("team_id = ? AND user_id = ?", teamID, userID).Update("role", role).Error
+	})
+
+	return r.membershipResult("update team member role", err, teamID, userID)
+}
+
+// RemoveMember removes a user from a team, refusing to remove the last owner
+func (r *TeamRepository) RemoveMember(ctx context.Context, teamID, userID string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		membership, err := lockMembershipForChange(tx, teamID, userID)
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == model.TeamRoleOwner {
+			if err := ensureAnotherOwner(tx, teamID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("team_id = ? AND user_id = ?", teamID, userID).Delete(&model.TeamMembership{}).Error
+	})
+
+	return r.membershipResult("remove team member", err, teamID, userID)
+}
+
+// TransferOwnership makes another member an owner and demotes the current owner to admin
+func (r *TeamRepository) TransferOwnership(ctx context.Context, teamID, fromUserID, toUserID string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockMembershipForChange(tx, teamID, toUserID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.TeamMembership{}).
+			Where("team_id = ? AND user_id = ?", teamID, toUserID).
+			Update("role", model.TeamRoleOwner).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.TeamMembership{}).
+			Where("team_id = ? AND user_id = ?", teamID, fromUserID).
+			Update("role", model.TeamRoleAdmin).Error
+	})
+
+	return r.membershipResult("transfer team ownership", err, teamID, toUserID)
+}
+
+// lockMembershipForChange locks the team's owner rows and returns the target membership
+func lockMembershipForChange(tx *gorm.DB, teamID, userID string) (*model.TeamMembership, error) {
+	var owners []model.TeamMembership
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("team_id = ? AND role = ?", teamID, model.TeamRoleOwner).
+		Find(&owners).Error; err != nil {
+		return nil, err
+	}
+
+	var membership model.TeamMembership
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("team_id = ? AND user_id = ?", teamID, userID).
+		First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("membership not found")
+	}
+
+	return &membership, err
+}
+
+// ensureAnotherOwner returns errLastOwner unless the team has more than one owner
+func ensureAnotherOwner(tx *gorm.DB, teamID string) error {
+	var owners int64
+	if err := tx.Model(&model.TeamMembership{}).
+		Where("team_id = ? AND role = ?", teamID, model.TeamRoleOwner).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+
+	if owners <= 1 {
+		return errLastOwner
+	}
+	return nil
+}
+
+// membershipResult logs and wraps the outcome of a membership change
+func (r *TeamRepository) membershipResult(operation string, err error, teamID, userID string) error {
+	if err == nil {
+		r.logger.WithFields(map[string]interface{}{
+			"team_id": teamID,
+			"user_id": userID,
+		}).Info("Team membership changed: " + operation)
+		return nil
+	}
+
+	if errors.Is(err, errLastOwner) || err.Error() == "membership not found" {
+		return err
+	}
+
+	r.logger.LogError("Failed to "+operation, err)
+	return fmt.Errorf("failed to %s: %w", operation, err)
+}
+
+// CreateInvitation stores a new invitation
+func (r *TeamRepository) CreateInvitation(ctx context.Context, invitation *model.TeamInvitation) error {
+	if err := r.db.WithContext(ctx).Create(invitation).Error; err != nil {
+		r.logger.LogError("Failed to create team invitation", err)
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"team_id":       invitation.TeamID,
+		"invitation_id": invitation.ID,
+	}).Info("Team invitation created successfully")
+
+	return nil
+}
+
+// GetInvitation retrieves an invitation by ID
+func (r *TeamRepository) GetInvitation(ctx context.Context, id string) (*model.TeamInvitation, error) {
+	var invitation model.TeamInvitation
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&invitation).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("invitation not found")
+		}
+		r.logger.LogError("Failed to get team invitation", err)
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// HasPendingInvitation checks whether an email already has an open invitation to a team
+func (r *TeamRepository) HasPendingInvitation(ctx context.Context, teamID, email string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.TeamInvitation{}).
+		Where("team_id = ? AND email = ? AND status = ? AND expires_at > ?", teamID, email, model.InvitationPending, time.Now().UTC()).
+		Count(&count).Error
+	if err != nil {
+		r.logger.LogError("Failed to check pending invitations", err)
+		return false, fmt.Errorf("failed to check invitations: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// ListPendingInvitationsByTeam retrieves a team's open invitations
+func (r *TeamRepository) ListPendingInvitationsByTeam(ctx context.Context, teamID string) ([]model.TeamInvitation, error) {
+	var invitations []model.TeamInvitation
+
+	err := r.db.WithContext(ctx).Where("team_id = ? AND status = ? AND expires_at > ?", teamID, model.InvitationPending, time.Now().UTC()).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	if err != nil {
+		r.logger.LogError("Failed to list team invitations", err)
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// ListInvitationsByEmail retrieves every invitation sent to an email address
+func (r *TeamRepository) ListInvitationsByEmail(ctx context.Context, email string, pendingOnly bool) ([]model.TeamInvitation, error) {
+	var invitations []model.TeamInvitation
+
+	query := r.db.WithContext(ctx).Where("email = ?", email)
+	if pendingOnly {
+		query = query.Where("status = ? AND expires_at > ?", model.InvitationPending, time.Now().UTC())
+	}
+
+	if err := query.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		r.logger.LogError("Failed to list user invitations", err)
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// RespondToInvitation marks a pending invitation accepted, declined or revoked. Accepting
+// also creates the membership in the same transaction.
+func (r *TeamRepository) RespondToInvitation(ctx context.Context, invitation *model.TeamInvitation, status string, userID string) error {
+	now := time.Now().UTC()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.TeamInvitation{}).
+			Where("id = ? AND status = ?", invitation.ID, model.InvitationPending).
+			Updates(map[string]interface{}{
+				"status":       status,
+				"responded_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("invitation is no longer pending")
+		}
+
+		if status != model.InvitationAccepted {
+			return nil
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.TeamMembership{
+			TeamID:   invitation.TeamID,
+			UserID:   userID,
+			TenantID: invitation.TenantID,
+			Role:     invitation.Role,
+		}).Error
+	})
+
+	if err != nil {
+		if err.Error() == "invitation is no longer pending" {
+			return err
+		}
+		r.logger.LogError("Failed to respond to team invitation", err)
+		return fmt.Errorf("failed to update invitation: %w", err)
+	}
+
+	invitation.Status = status
+	invitation.RespondedAt = &now
+	return nil
+}
+
+// DeleteInvitationsByEmail removes every invitation a tenant sent to an email address,
+// used when erasing personal data. The same email may belong to a user of another tenant.
+func (r *TeamRepository) DeleteInvitationsByEmail(ctx context.Context, tenantID, email string) error {
+	if err := r.db.WithContext(ctx).Where("tenant_id = ? AND email = ?", tenantID, email).Delete(&model.TeamInvitation{}).Error; err != nil {
+		r.logger.LogError("Failed to delete team invitations", err)
+		return fmt.Errorf("failed to delete invitations: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveUserFromAllTeams removes every membership of a user, used when erasing personal
+// data. Where the user is a team's only owner, the longest-standing remaining member is
+// promoted to owner; a team left with no members is deleted.
+func (r *TeamRepository) RemoveUserFromAllTeams(ctx context.Context, userID string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var memberships []model.TeamMembership
+		if err := tx.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+			return err
+		}
+
+		for _, membership := range memberships {
+			if err := tx.Where("team_id = ? AND user_id = ?", membership.TeamID, userID).Delete(&model.TeamMembership{}).Error; err != nil {
+				return err
+			}
+
+			if membership.Role != model.TeamRoleOwner {
+				continue
+			}
+
+			var owners int64
+			if err := tx.Model(&model.TeamMembership{}).
+				Where("team_id = ? AND role = ?", membership.TeamID, model.TeamRoleOwner).
+				Count(&owners).Error; err != nil {
+				return err
+			}
+			if owners > 0 {
+				continue
+			}
+
+			var successor model.TeamMembership
+			err := tx.Where("team_id = ?", membership.TeamID).
+				Order("CASE role WHEN 'admin' THEN 0 ELSE 1 END, created_at").
+				First(&successor).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Where("id = ?", membership.TeamID).Delete(&model.Team{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Model(&model.TeamMembership{}).
+				Where("team_id = ? AND user_id = ?", successor.TeamID, successor.UserID).
+				Update("role", model.TeamRoleOwner).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		r.logger.LogError("Failed to remove user from teams", err)
+		return fmt.Errorf("failed to remove user from teams: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// errQuotaExhausted rolls back the counting of a request that exceeds a quota
+var errQuotaExhausted = errors.New("quota exhausted")
+
+// UsageWindow is a quota window a request is counted in
+type UsageWindow struct {
+	Period string
+	Start  time.Time
+	Limit  int64 // 0 for unlimited
+}
+
+// UsageRepository handles request usage counters
+type UsageRepository struct {
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(db *gorm.DB, logger *logger.Logger) *UsageRepository {
+	return &UsageRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Consume counts a request in every window, unless one of them has reached its limit,
+// in which case nothing is counted. It returns the new count of each window, or the
+// index of the first exhausted window.
+func (r *UsageRepository) Consume(ctx context.Context, userID string, windows []UsageWindow, now time.Time) ([]int64, int, error) {
+	counts := make([]int64, len(windows))
+	exhausted := -1
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, window := range windows {
+			limit := window.Limit
+			if limit <= 0 {
+				limit = math.MaxInt64
+			}
+
+			// The conditional upsert doesn't return a row once the limit is reached. Raw SQL
+			// isn't tenant-scoped, so the counter takes the user's tenant.
+			result := tx.Raw(`INSERT INTO usage_counters (user_id, tenant_id, period, period_start, count, updated_at)
+				VALUES (?, (SELECT tenant_id FROM users WHERE id = ?), ?, ?, 1, ?)
+				ON CONFLICT (user_id, period, period_start) DO UPDATE
+				SET count = usage_counters.count + 1, updated_at = EXCLUDED.updated_at
+				WHERE usage_counters.count < ?
+				RETURNING count`, userID, userID, window.Period, window.Start, now, limit).Scan(&counts[i])
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				exhausted = i
+				return errQuotaExhausted
+			}
+		}
+		return nil
+	})
+
+	if errors.Is(err, errQuotaExhausted) {
+		return nil, exhausted, nil
+	}
+	if err != nil {
+		r.logger.LogError("Failed to count request usage", err)
+		return nil, -1, fmt.Errorf("failed to count usage: %w", err)
+	}
+
+	return counts, -1, nil
+}
+
+// GetCount returns a user's request count in a window
+func (r *UsageRepository) GetCount(ctx context.Context, userID, period string, start time.Time) (int64, error) {
+	var counter model.UsageCounter
+	err := r.db.WithContext(ctx).Where("user_id = ? AND period = ? AND period_start = ?", userID, period, start).First(&counter).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		r.logger.LogError("Failed to get usage counter", err)
+		return 0, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	return counter.Count, nil
+}
+
+// ListByUser retrieves all of a user's usage counters, newest first
+func (r *UsageRepository) ListByUser(ctx context.Context, userID string) ([]model.UsageCounter, error) {
+	var counters []model.UsageCounter
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("period_start DESC, period").Find(&counters).Error; err != nil {
+		r.logger.LogError("Failed to list usage counters", err)
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	return counters, nil
+}
+
+// DeleteByUser deletes all of a user's usage counters
+func (r *UsageRepository) DeleteByUser(ctx context.Context, userID string) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UsageCounter{}).Error; err != nil {
+		r.logger.LogError("Failed to delete usage counters", err)
+		return fmt.Errorf("failed to delete usage: %w", err)
+	}
+
+	return nil
+}
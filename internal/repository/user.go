@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dev-mayanktiwari/api-server/internal/model"
 	"github.com/dev-mayanktiwari/api-server/pkg/logger"
@@ -24,8 +26,8 @@ func NewUserRepository(db *gorm.DB, logger *logger.Logger) *UserRepository {
 }
 
 // Create creates a new user
-func (r *UserRepository) Create(user *model.User) error {
-	if err := r.db.Create(user).Error; err != nil {
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
 		r.logger.LogError("Failed to create user", err)
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -39,9 +41,9 @@ func (r *UserRepository) Create(user *model.User) error {
 }
 
 // GetByID retrieves a user by ID
-func (r *UserRepository) GetByID(id string) (*model.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("id = ?", id).First(&user).Error
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -55,9 +57,9 @@ func (r *UserRepository) GetByID(id string) (*model.User, error) {
 }
 
 // GetByEmail retrieves a user by email
-func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -70,11 +72,32 @@ func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 	return &user, nil
 }
 
-// Update updates a user
-func (r *UserRepository) Update(user *model.User) error {
-	if err := r.db.Save(user).Error; err != nil {
-		r.logger.LogError("Failed to update user", err)
-		return fmt.Errorf("failed to update user: %w", err)
+// Update updates a user using optimistic locking: the write only succeeds if the
+// stored version still equals user.Version, which is then incremented. Login tracking
+// columns are left alone since they are written without a version bump.
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	currentVersion := user.Version
+	user.Version = currentVersion + 1
+
+	result := r.db.WithContext(ctx).Model(user).
+		Where("version = ?", currentVersion).
+		Select("*").
+		Omit("tenant_id", "created_at", "last_login_at", "last_login_ip").
+		Updates(user)
+
+	if result.Error != nil {
+		user.Version = currentVersion
+		r.logger.LogError("Failed to update user", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		user.Version = currentVersion
+		r.logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"version": currentVersion,
+		}).Warn("User update rejected due to version mismatch")
+		return fmt.Errorf("version mismatch")
 	}
 
 	r.logger.WithFields(map[string]interface{}{
@@ -86,8 +109,8 @@ func (r *UserRepository) Update(user *model.User) error {
 }
 
 // Delete soft deletes a user
-func (r *UserRepository) Delete(id string) error {
-	result := r.db.Where("id = ?", id).Delete(&model.User{})
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.User{})
 
 	if result.Error != nil {
 		r.logger.LogError("Failed to delete user", result.Error)
@@ -102,19 +125,109 @@ func (r *UserRepository) Delete(id string) error {
 	return nil
 }
 
-// List retrieves users with pagination
-func (r *UserRepository) List(offset, limit int) ([]model.User, int64, error) {
+// SetDeletionSchedule schedules the user's account for deletion at the given time, or
+// cancels a pending deletion when at is nil
+func (r *UserRepository) SetDeletionSchedule(ctx context.Context, userID string, at *time.Time) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"deletion_scheduled_at": at,
+		"version":               gorm.Expr("version + 1"),
+	})
+
+	if result.Error != nil {
+		r.logger.LogError("Failed to update user deletion schedule", result.Error)
+		return fmt.Errorf("failed to update deletion schedule: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	r.logger.WithFields(map[string]interface{}{
+		"user_id":      userID,
+		"scheduled_at": at,
+	}).Info("User deletion schedule updated successfully")
+
+	return nil
+}
+
+// ListDueForDeletion retrieves users whose scheduled deletion time has passed
+func (r *UserRepository) ListDueForDeletion(ctx context.Context, now time.Time, limit int) ([]model.User, error) {
+	var users []model.User
+
+	err := r.db.WithContext(ctx).Where("deletion_scheduled_at <= ? AND erased_at IS NULL", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		r.logger.LogError("Failed to list users due for deletion", err)
+		return nil, fmt.Errorf("failed to list users due for deletion: %w", err)
+	}
+
+	return users, nil
+}
+
+// DeleteScheduled soft deletes a user whose scheduled deletion is due. It does nothing
+// if the deletion was cancelled in the meantime.
+func (r *UserRepository) DeleteScheduled(ctx context.Context, userID string, now time.Time) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND deletion_scheduled_at <= ?", userID, now).Delete(&model.User{})
+
+	if result.Error != nil {
+		r.logger.LogError("Failed to delete scheduled user", result.Error)
+		return fmt.Errorf("failed to delete user: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("deletion not due")
+	}
+
+	r.logger.WithField("user_id", userID).Info("Scheduled user deletion completed")
+	return nil
+}
+
+// UserFilter narrows the users returned by List
+type UserFilter struct {
+	// Preferences matches users whose preference value (as text) equals the given value.
+	// Keys must be valid preference keys; callers should restrict them to indexed keys.
+	Preferences map[string]string
+
+	// InactiveSince matches users who haven't logged in since the given time. Users who
+	// never logged in match if they were created before it.
+	InactiveSince *time.Time
+}
+
+// apply adds the filter's conditions to a query
+func (f UserFilter) apply(db *gorm.DB) *gorm.DB {
+	for key, value := range f.Preferences {
+		// The key is inlined rather than bound so the query matches the expression index
+		db = db.Where(fmt.Sprintf("preferences->>'%s' = ?", key), value)
+	}
+
+	if f.InactiveSince != nil {
+		db = db.Where("COALESCE(last_login_at, created_at) < ?", *f.InactiveSince)
+	}
+
+	return db
+}
+
+// List retrieves users matching the filter with pagination
+func (r *UserRepository) List(ctx context.Context, offset, limit int, filter UserFilter) ([]model.User, int64, error) {
 	var users []model.User
 	var total int64
 
+	for key := range filter.Preferences {
+		if !model.IsValidPreferenceKey(key) {
+			return nil, 0, fmt.Errorf("invalid preference key: %s", key)
+		}
+	}
+
 	// Get total count
-	if err := r.db.Model(&model.User{}).Count(&total).Error; err != nil {
+	if err := filter.apply(r.db.WithContext(ctx).Model(&model.User{})).Count(&total).Error; err != nil {
 		r.logger.LogError("Failed to count users", err)
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
 
 	// Get users with pagination
-	if err := r.db.Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+	if err := filter.apply(r.db.WithContext(ctx)).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
 		r.logger.LogError("Failed to list users", err)
 		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -122,10 +235,87 @@ func (r *UserRepository) List(offset, limit int) ([]model.User, int64, error) {
 	return users, total, nil
 }
 
+// UserSearchHit is a user matched by Search, with its relevance score and the name and
+// email with matched fragments wrapped in the highlight markers given to Search
+type UserSearchHit struct {
+	model.User
+	Score          float64
+	NameHighlight  string
+	EmailHighlight string
+}
+
+// Search finds users whose name or email matches the full-text query tsquery (a
+// to_tsquery expression in the 'simple' configuration) or is similar to text by trigram
+// similarity, best matches first. It relies on the search_vector column and trigram
+// indexes added by the SQL migrations.
+func (r *UserRepository) Search(ctx context.Context, text, tsquery string, limit int, startSel, stopSel string) ([]UserSearchHit, error) {
+	var hits []UserSearchHit
+
+	args := map[string]interface{}{
+		"text":    text,
+		"tsquery": tsquery,
+		"options": `StartSel="` + startSel + `", StopSel="` + stopSel + `", HighlightAll=true`,
+	}
+
+	err := r.db.WithContext(ctx).Model(&model.User{}).
+		Select(`users.*,
+			GREATEST(
+				ts_rank_cd(search_vector, to_tsquery('simple', @tsquery)),
+				similarity(first_name || ' ' || last_name, @text),
+				similarity(email, @text)
+			) AS score,
+			ts_headline('simple', first_name || ' ' || last_name, to_tsquery('simple', @tsquery), @options) AS name_highlight,
+			ts_headline('simple', email, to_tsquery('simple', @tsquery), @options) AS email_highlight`, args).
+		// Parenthesized explicitly: gorm doesn't group named conditions, and the OR would
+		// otherwise escape the tenant and soft delete conditions
+		Where(`(search_vector @@ to_tsquery('simple', @tsquery)
+			OR (first_name || ' ' || last_name) % @text
+			OR email % @text)`, args).
+		Order("score DESC, created_at DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		r.logger.LogError("Failed to search users", err)
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return hits, nil
+}
+
+// RecordLogin stores the time and client IP of a successful login. It doesn't bump the
+// version, so logging in never invalidates a concurrent edit of the profile.
+func (r *UserRepository) RecordLogin(ctx context.Context, userID string, ip string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+		"last_login_at": at,
+		"last_login_ip": ip,
+	})
+
+	if result.Error != nil {
+		r.logger.LogError("Failed to record user login", result.Error)
+		return fmt.Errorf("failed to record login: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// ClearLastLoginIP removes the stored last login IP, used when erasing personal data
+func (r *UserRepository) ClearLastLoginIP(ctx context.Context, userID string) error {
+	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).UpdateColumn("last_login_ip", "").Error; err != nil {
+		r.logger.LogError("Failed to clear last login IP", err)
+		return fmt.Errorf("failed to clear last login IP: %w", err)
+	}
+
+	return nil
+}
+
 // ExistsByEmail checks if a user exists with the given email
-func (r *UserRepository) ExistsByEmail(email string) (bool, error) {
+func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("email = ?", email).Count(&count).Error
 
 	if err != nil {
 		r.logger.LogError("Failed to check user existence by email", err)
@@ -136,13 +326,16 @@ func (r *UserRepository) ExistsByEmail(email string) (bool, error) {
 }
 
 // UpdatePassword updates a user's password
-func (r *UserRepository) UpdatePassword(userID, newPassword string) error {
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, newPassword string) error {
 	user := &model.User{ID: userID}
 	if err := user.SetPassword(newPassword); err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	result := r.db.Model(&model.User{}).Where("id = ?", userID).Update("password", user.Password)
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password": user.Password,
+		"version":  gorm.Expr("version + 1"),
+	})
 
 	if result.Error != nil {
 		r.logger.LogError("Failed to update user password", result.Error)
@@ -158,10 +351,10 @@ func (r *UserRepository) UpdatePassword(userID, newPassword string) error {
 }
 
 // GetActiveUsers retrieves all active users
-func (r *UserRepository) GetActiveUsers() ([]model.User, error) {
+func (r *UserRepository) GetActiveUsers(ctx context.Context) ([]model.User, error) {
 	var users []model.User
 
-	if err := r.db.Where("is_active = ?", true).Find(&users).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("is_active = ?", true).Find(&users).Error; err != nil {
 		r.logger.LogError("Failed to get active users", err)
 		return nil, fmt.Errorf("failed to get active users: %w", err)
 	}
@@ -170,8 +363,11 @@ func (r *UserRepository) GetActiveUsers() ([]model.User, error) {
 }
 
 // SetUserStatus updates a user's active status
-func (r *UserRepository) SetUserStatus(userID string, isActive bool) error {
-	result := r.db.Model(&model.User{}).Where("id = ?", userID).Update("is_active", isActive)
+func (r *UserRepository) SetUserStatus(ctx context.Context, userID string, isActive bool) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"is_active": isActive,
+		"version":   gorm.Expr("version + 1"),
+	})
 
 	if result.Error != nil {
 		r.logger.LogError("Failed to update user status", result.Error)
@@ -5,30 +5,67 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/dev-mayanktiwari/api-server/internal/config"
 	"github.com/dev-mayanktiwari/api-server/internal/handler"
 	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/migrations"
 	"github.com/dev-mayanktiwari/api-server/internal/model"
 	"github.com/dev-mayanktiwari/api-server/internal/repository"
 	"github.com/dev-mayanktiwari/api-server/internal/service"
 	"github.com/dev-mayanktiwari/api-server/pkg/auth"
 	"github.com/dev-mayanktiwari/api-server/pkg/database"
+	"github.com/dev-mayanktiwari/api-server/pkg/idempotency"
+	"github.com/dev-mayanktiwari/api-server/pkg/ipfilter"
 	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/mailer"
+	"github.com/dev-mayanktiwari/api-server/pkg/maintenance"
+	"github.com/dev-mayanktiwari/api-server/pkg/ratelimit"
 	"github.com/dev-mayanktiwari/api-server/pkg/response"
+	"github.com/dev-mayanktiwari/api-server/pkg/storage"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	config      *config.Config
-	logger      *logger.Logger
-	db          *database.Database
-	jwtManager  *auth.JWTManager
-	httpServer  *http.Server
-	router      *gin.Engine
-	userHandler *handler.UserHandler
+	config          *config.Config
+	logger          *logger.Logger
+	db              *database.Database
+	jwtManager      *auth.JWTManager
+	httpServer      *http.Server
+	router          *gin.Engine
+	userHandler     *handler.UserHandler
+	privacyHandler  *handler.PrivacyHandler
+	avatarHandler   *handler.AvatarHandler
+	prefHandler     *handler.PreferenceHandler
+	activityHandler *handler.ActivityHandler
+	accountHandler  *handler.AccountHandler
+	teamHandler     *handler.TeamHandler
+	teamService     *service.TeamService
+	orgHandler      *handler.OrganizationHandler
+	orgService      *service.OrganizationService
+	accountService  *service.AccountService
+	apiKeyHandler   *handler.APIKeyHandler
+	apiKeyService   *service.APIKeyService
+	usageHandler    *handler.UsageHandler
+	maintHandler    *handler.MaintenanceHandler
+	flagHandler     *handler.FeatureFlagHandler
+	flagService     *service.FeatureFlagService
+	usageService    *service.UsageService
+	rateLimiter     ratelimit.Limiter
+	idempotency     idempotency.Store
+	ipFilter        *ipfilter.Filter
+	ipFilterGroups  []string // route groups checked by the IP filter
+	maintenance     *maintenance.Switch
+	redis           *redis.Client
+	stopJobs        context.CancelFunc
+	jobsDone        chan struct{}
 }
 
 // New creates a new HTTP server instance
@@ -49,52 +86,415 @@ func New(cfg *config.Config, logger *logger.Logger) (*Server, error) {
 	}
 
 	// Run database migrations
-	if err := db.Migrate(&model.User{}); err != nil {
+	if err := db.Migrate(&model.Organization{}, &model.User{}, &model.PreferenceSchema{}, &model.UserActivity{},
+		&model.Team{}, &model.TeamMembership{}, &model.TeamInvitation{}, &model.APIKey{}, &model.UsageCounter{},
+		&model.FeatureFlag{}, &idempotency.DatabaseRecord{}); err != nil {
 		return nil, fmt.Errorf("failed to run database migrations: %w", err)
 	}
 
+	// Apply the SQL migrations for schema changes AutoMigrate can't express
+	if err := db.MigrateSQL(migrations.FS); err != nil {
+		return nil, fmt.Errorf("failed to run SQL migrations: %w", err)
+	}
+
+	// Scope every query on tenant-scoped models to the request's tenant
+	if err := tenant.RegisterCallbacks(db.DB); err != nil {
+		return nil, fmt.Errorf("failed to register tenant scoping: %w", err)
+	}
+
 	// Initialize JWT manager
 	jwtManager := auth.NewJWTManager(cfg.JWT.Secret, cfg.JWT.Expiry)
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db.DB, logger)
+	preferenceRepo := repository.NewPreferenceRepository(db.DB, logger)
+	activityRepo := repository.NewActivityRepository(db.DB, logger)
+	teamRepo := repository.NewTeamRepository(db.DB, logger)
+	orgRepo := repository.NewOrganizationRepository(db.DB, logger)
+	apiKeyRepo := repository.NewAPIKeyRepository(db.DB, logger)
+	usageRepo := repository.NewUsageRepository(db.DB, logger)
+	flagRepo := repository.NewFeatureFlagRepository(db.DB, logger)
+
+	// Make sure single-tenant deployments and pre-existing users have a tenant
+	if _, err := orgRepo.EnsureDefault(); err != nil {
+		return nil, fmt.Errorf("failed to initialize default organization: %w", err)
+	}
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, jwtManager, logger)
+	orgService := service.NewOrganizationService(orgRepo, logger)
+	activityService := service.NewActivityService(activityRepo, userRepo, logger)
+	userService := service.NewUserService(userRepo, preferenceRepo, activityService, jwtManager, planNames(cfg.RateLimit), logger)
+	privacyService := service.NewPrivacyService(userRepo, activityService, logger)
+	preferenceService := service.NewPreferenceService(preferenceRepo, userRepo, activityService, logger)
+
+	// Initialize blob storage and signed links
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize blob storage: %w", err)
+	}
 
-	// Initialize handlers
-	userHandler := handler.NewUserHandler(userService, logger)
+	urlSecret := cfg.Storage.URLSecret
+	if urlSecret == "" {
+		urlSecret = cfg.JWT.Secret
+	}
+	urlSigner := storage.NewURLSigner(urlSecret, cfg.Storage.PublicURL+"/api/v1/files", cfg.Storage.URLExpiry)
+	model.SetAvatarURLFunc(urlSigner.SignedURL)
+
+	avatarService := service.NewAvatarService(userRepo, activityService, blobStore, cfg.Storage.MaxAvatarSize, logger)
+	privacyService.RegisterHook(avatarService.DataHook())
+
+	mail := newMailer(cfg, logger)
+	redisClient := newRedisClient(cfg, logger)
+	rateLimiter := newRateLimiter(cfg, redisClient)
+	idempotencyStore := newIdempotencyStore(cfg, db.DB, redisClient)
+
+	var ipFilter *ipfilter.Filter
+	if cfg.IPFilter.Enabled {
+		if ipFilter, err = ipfilter.NewFilter(cfg.IPFilter.RulesFile, cfg.IPFilter.GeoIPDatabase); err != nil {
+			return nil, fmt.Errorf("failed to initialize IP filter: %w", err)
+		}
+	}
+
+	maintenanceMode := maintenance.ModeOff
+	if cfg.Maintenance.Mode != "" {
+		maintenanceMode = maintenance.Mode(cfg.Maintenance.Mode)
+	}
+	maintenanceSwitch, err := maintenance.NewSwitch(maintenanceMode, cfg.Maintenance.StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize maintenance mode: %w", err)
+	}
 
-	// Create Gin router
+	accountService := service.NewAccountService(userRepo, privacyService, activityService, mail,
+		cfg.Account.DeletionGracePeriod, cfg.Account.DeletionMode, logger)
+
+	teamService := service.NewTeamService(teamRepo, userRepo, mail, logger)
+	privacyService.RegisterHook(teamService.DataHook())
+
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, logger)
+	privacyService.RegisterHook(apiKeyService.DataHook())
+
+	usageService := service.NewUsageService(usageRepo, planResolver(cfg.RateLimit), logger)
+	privacyService.RegisterHook(usageService.DataHook())
+
+	flagService := service.NewFeatureFlagService(flagRepo, cfg.Flags.File, logger)
+	if err := flagService.Refresh(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	// Related resources clients can embed in user responses with ?expand=
+	userExpanders := response.Expanders{
+		"teams": response.ExpandFunc(func(ctx context.Context, user model.SafeUser) (interface{}, error) {
+			return teamService.ListTeams(ctx, user.ID)
+		}),
+	}
+
+	// Initialize handlers
+	userHandler := handler.NewUserHandler(userService, userExpanders, logger)
+	privacyHandler := handler.NewPrivacyHandler(privacyService, logger)
+	avatarHandler := handler.NewAvatarHandler(avatarService, blobStore, urlSigner, logger)
+	prefHandler := handler.NewPreferenceHandler(preferenceService, logger)
+	activityHandler := handler.NewActivityHandler(activityService, logger)
+	accountHandler := handler.NewAccountHandler(accountService, logger)
+	teamHandler := handler.NewTeamHandler(teamService, logger)
+	orgHandler := handler.NewOrganizationHandler(orgService, logger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, logger)
+	usageHandler := handler.NewUsageHandler(usageService, logger)
+	maintHandler := handler.NewMaintenanceHandler(maintenanceSwitch, logger)
+	flagHandler := handler.NewFeatureFlagHandler(flagService, logger)
+
+	// Create Gin router; only the configured proxies may set the client IP
 	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
 
 	// Create HTTP server
 	httpServer := &http.Server{
-		Addr:           cfg.GetServerAddress(),
-		Handler:        router,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		IdleTimeout:    60 * time.Second,
-		MaxHeaderBytes: 1 << 20, // 1MB
+		Addr:              cfg.GetServerAddress(),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
 
 	server := &Server{
-		config:      cfg,
-		logger:      logger,
-		db:          db,
-		jwtManager:  jwtManager,
-		httpServer:  httpServer,
-		router:      router,
-		userHandler: userHandler,
+		config:          cfg,
+		logger:          logger,
+		db:              db,
+		jwtManager:      jwtManager,
+		httpServer:      httpServer,
+		router:          router,
+		userHandler:     userHandler,
+		privacyHandler:  privacyHandler,
+		avatarHandler:   avatarHandler,
+		prefHandler:     prefHandler,
+		activityHandler: activityHandler,
+		accountHandler:  accountHandler,
+		accountService:  accountService,
+		teamHandler:     teamHandler,
+		teamService:     teamService,
+		orgHandler:      orgHandler,
+		orgService:      orgService,
+		apiKeyHandler:   apiKeyHandler,
+		apiKeyService:   apiKeyService,
+		usageHandler:    usageHandler,
+		maintHandler:    maintHandler,
+		flagHandler:     flagHandler,
+		flagService:     flagService,
+		usageService:    usageService,
+		rateLimiter:     rateLimiter,
+		idempotency:     idempotencyStore,
+		ipFilter:        ipFilter,
+		maintenance:     maintenanceSwitch,
+		redis:           redisClient,
 	}
 
 	// Setup middlewares and routes
 	server.setupMiddlewares()
 	server.setupRoutes()
 
+	// Start background jobs; Stop shuts them down
+	server.startJobs()
+
 	return server, nil
 }
 
+// newBlobStore creates the blob store selected by the storage configuration
+func newBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.Storage.Driver {
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			Region:          cfg.Storage.S3.Region,
+			Bucket:          cfg.Storage.S3.Bucket,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+			UsePathStyle:    cfg.Storage.S3.UsePathStyle,
+		})
+	default:
+		return storage.NewLocalStore(cfg.Storage.LocalPath)
+	}
+}
+
+// startJobs starts the background jobs
+func (s *Server) startJobs() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopJobs = cancel
+	s.jobsDone = make(chan struct{})
+
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		s.accountService.RunDeletionJob(ctx, s.config.Account.DeletionCheckInterval)
+	}()
+
+	// Redis expires idempotency records by itself; the database needs a sweep
+	if store, ok := s.idempotency.(*idempotency.DatabaseStore); ok {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			s.runIdempotencyCleanup(ctx, store, idempotencyCleanupInterval)
+		}()
+	}
+
+	// Pick up changes to the IP filter's rules and GeoIP database
+	if s.ipFilter != nil {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			s.runIPFilterReload(ctx, s.config.IPFilter.ReloadInterval)
+		}()
+	}
+
+	// Pick up maintenance mode switches written to the state file, e.g. by other instances
+	if s.config.Maintenance.StateFile != "" {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			s.runMaintenanceReload(ctx, s.config.Maintenance.ReloadInterval)
+		}()
+	}
+
+	// Pick up changes to the flags file and flags changed through other instances
+	if s.config.Flags.RefreshInterval > 0 {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			s.flagService.RunRefreshJob(ctx, s.config.Flags.RefreshInterval)
+		}()
+	}
+
+	go func() {
+		jobs.Wait()
+		close(s.jobsDone)
+	}()
+}
+
+// runIPFilterReload reloads the IP filter's files every interval until ctx is done.
+// Files that fail to load leave the rules loaded last in effect.
+func (s *Server) runIPFilterReload(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := s.ipFilter.Reload()
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to reload IP filter, keeping the current rules")
+			continue
+		}
+		if reloaded {
+			s.logger.Info("IP filter reloaded")
+			s.warnUnknownIPFilterGroups()
+		}
+	}
+}
+
+// maintenancePath is the admin endpoint that switches maintenance mode, which must stay
+// reachable to switch it off again
+const maintenancePath = "/api/v1/admin/maintenance"
+
+// runMaintenanceReload reloads the maintenance state file every interval until ctx is
+// done. A file that fails to load leaves the current mode in effect.
+func (s *Server) runMaintenanceReload(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := s.maintenance.Reload()
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to reload maintenance state, keeping the current mode")
+			continue
+		}
+		if reloaded {
+			s.logger.WithField("mode", s.maintenance.State().Mode).Info("Maintenance mode reloaded")
+		}
+	}
+}
+
+// idempotencyCleanupInterval is how often expired idempotency records are removed
+const idempotencyCleanupInterval = time.Hour
+
+// runIdempotencyCleanup removes expired idempotency records every interval until ctx
+// is done
+func (s *Server) runIdempotencyCleanup(ctx context.Context, store *idempotency.DatabaseStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := store.DeleteExpired(ctx); err != nil && ctx.Err() == nil {
+			s.logger.WithError(err).Error("Idempotency cleanup job failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// newRedisClient connects to Redis if the rate limiter or the idempotency store uses
+// it, and returns nil otherwise
+func newRedisClient(cfg *config.Config, logger *logger.Logger) *redis.Client {
+	rateLimitRedis := cfg.RateLimit.Enabled && cfg.RateLimit.Store == "redis"
+	idempotencyRedis := cfg.Idempotency.Enabled && cfg.Idempotency.Store == "redis"
+	if !rateLimitRedis && !idempotencyRedis {
+		return nil
+	}
+
+	// Short timeouts: requests wait for the rate limiter and idempotency checks, which
+	// decide for themselves what happens when Redis is slow or down
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Redis.Addr,
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		DialTimeout:  time.Second,
+		ReadTimeout:  200 * time.Millisecond,
+		WriteTimeout: 200 * time.Millisecond,
+	})
+
+	// Redis being down isn't fatal; rate limiting fails according to the failure mode
+	// and idempotency checks are skipped
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.WithError(err).Warn("Redis is unreachable, requests are handled without it until it is back")
+	}
+
+	return client
+}
+
+// newRateLimiter creates the limiter selected by the rate limit configuration
+func newRateLimiter(cfg *config.Config, redisClient *redis.Client) ratelimit.Limiter {
+	if cfg.RateLimit.Store == "redis" && redisClient != nil {
+		return ratelimit.NewRedisLimiter(redisClient, "ratelimit:")
+	}
+	return ratelimit.NewMemoryLimiter(cfg.RateLimit.MaxKeys)
+}
+
+// newIdempotencyStore creates the store selected by the idempotency configuration
+func newIdempotencyStore(cfg *config.Config, db *gorm.DB, redisClient *redis.Client) idempotency.Store {
+	if cfg.Idempotency.Store == "redis" && redisClient != nil {
+		return idempotency.NewRedisStore(redisClient, "idempotency:")
+	}
+	return idempotency.NewDatabaseStore(db)
+}
+
+// planResolver adapts the rate limit plans for the usage service
+func planResolver(cfg config.RateLimitConfig) service.PlanResolver {
+	return func(role, plan string) (service.QuotaPlan, bool) {
+		name, limits, ok := cfg.Plan(role, plan)
+		if !ok {
+			return service.QuotaPlan{}, false
+		}
+
+		return service.QuotaPlan{
+			Name:         name,
+			Requests:     limits.Requests,
+			Period:       limits.Period,
+			Burst:        limits.Burst,
+			DailyQuota:   limits.DailyQuota,
+			MonthlyQuota: limits.MonthlyQuota,
+		}, true
+	}
+}
+
+// planNames returns the names of the configured rate limit plans in order
+func planNames(cfg config.RateLimitConfig) []string {
+	names := make([]string, 0, len(cfg.Plans))
+	for name := range cfg.Plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// newMailer creates the mailer selected by the mailer configuration
+func newMailer(cfg *config.Config, logger *logger.Logger) mailer.Mailer {
+	switch cfg.Mailer.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.Mailer.SMTP.Host, cfg.Mailer.SMTP.Port, cfg.Mailer.SMTP.Username, cfg.Mailer.SMTP.Password, cfg.Mailer.From)
+	default:
+		return mailer.NewLogMailer(logger)
+	}
+}
+
 // setupMiddlewares configures all middlewares
 func (s *Server) setupMiddlewares() {
 	// Recovery middleware (must be first)
@@ -103,29 +503,74 @@ func (s *Server) setupMiddlewares() {
 	// Request ID middleware
 	s.router.Use(middleware.RequestIDMiddleware())
 
+	// Client IP resolution through trusted proxies
+	s.router.Use(middleware.ClientIPMiddleware(s.config.Server.TrustedProxies, s.config.Server.RemoteIPHeaders))
+
 	// CORS middleware
 	s.router.Use(middleware.CORSMiddleware(s.config))
 
 	// Logging middleware
 	s.router.Use(middleware.LoggingMiddleware(s.logger))
 
+	// Response compression
+	if s.config.Compression.Enabled {
+		s.router.Use(middleware.CompressionMiddleware(s.config.Compression))
+	}
+
 	// JSON validation middleware
-	s.router.Use(middleware.ValidateJSON())
-
-	// Security headers middleware
-	s.router.Use(func(c *gin.Context) {
-		c.Header("X-Content-Type-Options", "nosniff")
-		c.Header("X-Frame-Options", "DENY")
-		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
-		c.Next()
-	})
+	s.router.Use(middleware.ValidateJSON("/api/v1/profile/avatar"))
+
+	// Security headers middleware; HSTS only in release mode
+	s.router.Use(middleware.SecurityHeadersMiddleware(s.config.Security, s.config.IsProduction()))
+
+	// Feature flags, evaluated for the authenticated user by handlers and RequireFlag
+	s.router.Use(middleware.FeatureFlagsMiddleware(s.flagService))
+
+	// Maintenance mode; health checks and the maintenance switch stay reachable
+	s.router.Use(middleware.MaintenanceMiddleware(s.maintenance, s.config.Maintenance.AllowedIPs,
+		s.config.Maintenance.RetryAfter, "/health", "/ready", "/live", "/version", "/api/health", maintenancePath))
+
+	// Request body size limits
+	s.router.Use(middleware.BodyLimitMiddleware(s.config.Server.MaxBodySize, s.config.Server.BodyLimits))
+	if s.config.Compression.Enabled {
+		s.router.Use(middleware.DecompressionMiddleware(s.config.Compression.MaxDecompressedSize))
+	}
+
+	// Opt-in body logging for debugging; bodies are logged decompressed and with the
+	// final response, timeouts included
+	if s.config.Logger.Bodies.Enabled {
+		s.router.Use(middleware.BodyLoggingMiddleware(s.config.Logger.Bodies, s.logger))
+	}
+
+	// Handler timeouts; headers set up to here survive a timeout
+	s.router.Use(middleware.TimeoutMiddleware(s.config.Server.HandlerTimeout, s.config.Server.HandlerTimeouts))
+}
+
+// ipFilterGroup returns the IP filter of a route group, or nothing if the filter is
+// disabled
+func (s *Server) ipFilterGroup(name string) []gin.HandlerFunc {
+	if s.ipFilter == nil {
+		return nil
+	}
+	s.ipFilterGroups = append(s.ipFilterGroups, name)
+	return []gin.HandlerFunc{middleware.IPFilterMiddleware(s.ipFilter, name, s.logger)}
+}
+
+// warnUnknownIPFilterGroups warns about IP filter rules for groups that no route group
+// checks, which usually means a typo in the rules file
+func (s *Server) warnUnknownIPFilterGroups() {
+	if s.ipFilter == nil {
+		return
+	}
+	for _, name := range s.ipFilter.Unknown(s.ipFilterGroups) {
+		s.logger.WithField("group", name).Warn("IP filter rules don't match any route group and are ignored")
+	}
 }
 
 // setupRoutes configures all routes
 func (s *Server) setupRoutes() {
 	// Create handlers
-	healthHandler := handler.NewHealthHandler("1.0.0")
+	healthHandler := handler.NewHealthHandler("1.0.0", s.maintenance)
 
 	// Health check routes (no authentication required)
 	s.router.GET("/health", healthHandler.Health)
@@ -133,9 +578,14 @@ func (s *Server) setupRoutes() {
 	s.router.GET("/live", healthHandler.Liveness)
 	s.router.GET("/version", healthHandler.Version)
 
+	// Rate limit policies: group policies are added where each group is declared, route
+	// policies as the routes are registered
+	limits := middleware.NewRateLimits(s.config.RateLimit, s.rateLimiter, s.logger)
+
 	// API routes group with rate limiting
-	api := s.router.Group("/api")
-	api.Use(middleware.APIRateLimitMiddleware())
+	api := limits.Routes(s.router.Group("/api"))
+	api.Use(s.ipFilterGroup("api")...)
+	api.Use(limits.Group("api")...)
 	{
 		// API health check
 		api.GET("/health", healthHandler.Health)
@@ -146,9 +596,15 @@ func (s *Server) setupRoutes() {
 			// Public endpoints (no authentication required)
 			v1.GET("/ping", s.pingHandler)
 
+			// Signed blob links (the signature is the authorization)
+			v1.GET("/files/*key", s.avatarHandler.ServeFile)
+
 			// Auth endpoints with strict rate limiting
 			auth := v1.Group("/auth")
-			auth.Use(middleware.AuthRateLimitMiddleware())
+			auth.Use(s.ipFilterGroup("auth")...)
+			auth.Use(limits.Group("auth")...)
+			auth.Use(middleware.TenantMiddleware(s.orgService, s.config))
+			// No idempotency here: stored responses would keep the issued tokens
 			{
 				auth.POST("/register", s.userHandler.Register)
 				auth.POST("/login", s.userHandler.Login)
@@ -156,34 +612,108 @@ func (s *Server) setupRoutes() {
 
 			// Protected endpoints (authentication required)
 			protected := v1.Group("/")
-			protected.Use(middleware.AuthMiddleware(s.jwtManager, s.logger))
+			protected.Use(s.ipFilterGroup("protected")...)
+			protected.Use(middleware.AuthMiddleware(s.jwtManager, s.apiKeyService, s.logger))
+			protected.Use(middleware.TenantMiddleware(s.orgService, s.config))
+			protected.Use(limits.Group("protected")...)
+			if s.config.RateLimit.Enabled {
+				// Usage stays readable once a quota is used up
+				protected.Use(middleware.QuotaMiddleware(s.usageService, s.config.RateLimit.FailureMode == "closed",
+					s.logger, "/api/v1/profile/usage"))
+			}
+			if s.config.Idempotency.Enabled {
+				// New API keys aren't stored for replays
+				protected.Use(middleware.IdempotencyMiddleware(s.idempotency, s.config.Idempotency, s.logger,
+					"/api/v1/profile/api-keys"))
+			}
 			{
+				// Feature flags evaluated for the current user
+				protected.GET("/flags", s.flagHandler.GetMyFlags)
+
 				// User profile endpoints
 				profile := protected.Group("/profile")
+				profile.Use(s.ipFilterGroup("profile")...)
+				profile.Use(limits.Group("profile")...)
 				{
 					profile.GET("", s.userHandler.GetProfile)
 					profile.PUT("", s.userHandler.UpdateProfile)
+					profile.PATCH("", s.userHandler.PatchProfile)
+					profile.DELETE("", s.accountHandler.DeleteAccount)
+					profile.POST("/cancel-deletion", s.accountHandler.CancelDeletion)
 					profile.POST("/change-password", s.userHandler.ChangePassword)
+					profile.GET("/data-export", s.privacyHandler.ExportProfileData)
+					profile.POST("/erase", s.privacyHandler.EraseProfile)
+					profile.PUT("/avatar", s.avatarHandler.UploadAvatar)
+					profile.DELETE("/avatar", s.avatarHandler.DeleteAvatar)
+					profile.GET("/preferences", s.prefHandler.GetPreferences)
+					profile.PUT("/preferences", s.prefHandler.UpdatePreferences)
+					profile.GET("/usage", s.usageHandler.GetUsage)
+					profile.POST("/api-keys", s.apiKeyHandler.CreateAPIKey)
+					profile.GET("/api-keys", s.apiKeyHandler.ListAPIKeys)
+					profile.DELETE("/api-keys/:id", s.apiKeyHandler.RevokeAPIKey)
+				}
+
+				// Team endpoints; routes under /:team_id check the caller's team role
+				teams := protected.Group("/teams")
+				teams.Use(s.ipFilterGroup("teams")...)
+				teams.Use(limits.Group("teams")...)
+				{
+					teams.POST("", s.teamHandler.CreateTeam)
+					teams.GET("", s.teamHandler.ListTeams)
+
+					member := middleware.TeamRoleMiddleware(s.teamService, model.TeamRoleMember)
+					admin := middleware.TeamRoleMiddleware(s.teamService, model.TeamRoleAdmin)
+					owner := middleware.TeamRoleMiddleware(s.teamService, model.TeamRoleOwner)
+
+					teams.GET("/:team_id", member, s.teamHandler.GetTeam)
+					teams.PUT("/:team_id", admin, s.teamHandler.UpdateTeam)
+					teams.DELETE("/:team_id", owner, s.teamHandler.DeleteTeam)
+					teams.GET("/:team_id/members", member, s.teamHandler.ListMembers)
+					teams.PUT("/:team_id/members/:user_id", admin, s.teamHandler.UpdateMember)
+					teams.DELETE("/:team_id/members/:user_id", member, s.teamHandler.RemoveMember)
+					teams.POST("/:team_id/transfer-ownership", owner, s.teamHandler.TransferOwnership)
+					teams.POST("/:team_id/invitations", admin, s.teamHandler.InviteMember)
+					teams.GET("/:team_id/invitations", admin, s.teamHandler.ListTeamInvitations)
+					teams.DELETE("/:team_id/invitations/:invitation_id", admin, s.teamHandler.RevokeInvitation)
+				}
+
+				// Invitations sent to the current user
+				invitations := protected.Group("/invitations")
+				invitations.Use(s.ipFilterGroup("invitations")...)
+				invitations.Use(limits.Group("invitations")...)
+				{
+					invitations.GET("", s.teamHandler.ListMyInvitations)
+					invitations.POST("/:id/accept", s.teamHandler.AcceptInvitation)
+					invitations.POST("/:id/decline", s.teamHandler.DeclineInvitation)
 				}
 
 				// Admin endpoints (admin role required)
 				admin := protected.Group("/admin")
+				admin.Use(s.ipFilterGroup("admin")...)
 				admin.Use(middleware.AdminMiddleware())
+				admin.Use(limits.Group("admin")...)
+				s.setupAdminRoutes(admin)
+
+				// Organization (tenant) management (super admin role required)
+				orgs := protected.Group("/organizations")
+				orgs.Use(middleware.SuperAdminMiddleware())
+				orgs.Use(s.ipFilterGroup("organizations")...)
+				orgs.Use(limits.Group("organizations")...)
 				{
-					// User management
-					users := admin.Group("/users")
-					users.Use(middleware.ValidatePagination())
-					{
-						users.GET("", s.userHandler.ListUsers)
-						users.GET("/:id", s.userHandler.GetUser)
-						users.PUT("/:id", s.userHandler.UpdateUser)
-						users.DELETE("/:id", s.userHandler.DeleteUser)
-					}
+					orgs.GET("", s.orgHandler.ListOrganizations)
+					orgs.POST("", s.orgHandler.CreateOrganization)
+					orgs.GET("/:id", s.orgHandler.GetOrganization)
+					orgs.PUT("/:id", s.orgHandler.UpdateOrganization)
 				}
 			}
 		}
 	}
 
+	for _, name := range limits.Unused() {
+		s.logger.WithField("policy", name).Warn("Rate limit policy doesn't match any route group or route")
+	}
+	s.warnUnknownIPFilterGroups()
+
 	// Handle 404 for unknown routes
 	s.router.NoRoute(func(c *gin.Context) {
 		response.NotFound(c, "The requested endpoint was not found")
@@ -195,6 +725,52 @@ func (s *Server) setupRoutes() {
 	})
 }
 
+// setupAdminRoutes registers the admin endpoints. Tenant admins manage their own
+// tenant; settings shared by every tenant are left to super admins.
+func (s *Server) setupAdminRoutes(admin *middleware.RateLimitedGroup) {
+	// User management
+	users := admin.Group("/users")
+	users.Use(middleware.ValidatePagination())
+	{
+		users.GET("", s.userHandler.ListUsers)
+		users.GET("/search", s.userHandler.SearchUsers)
+		users.GET("/:id", s.userHandler.GetUser)
+		users.PUT("/:id", s.userHandler.UpdateUser)
+		users.PATCH("/:id", s.userHandler.PatchUser)
+		users.DELETE("/:id", s.userHandler.DeleteUser)
+		users.GET("/:id/data-export", s.privacyHandler.ExportUserData)
+		users.POST("/:id/erase", s.privacyHandler.EraseUser)
+		users.GET("/:id/activity", s.activityHandler.ListUserActivity)
+	}
+
+	// Deployment-wide settings (super admin role required)
+	platform := admin.Group("")
+	platform.Use(middleware.SuperAdminMiddleware())
+	{
+		// Maintenance mode, which takes every tenant offline
+		platform.GET("/maintenance", s.maintHandler.GetMaintenance)
+		platform.PUT("/maintenance", s.maintHandler.SetMaintenance)
+
+		// Feature flag management; flags apply to every tenant
+		flags := platform.Group("/feature-flags")
+		{
+			flags.GET("", s.flagHandler.ListFlags)
+			flags.GET("/:key", s.flagHandler.GetFlag)
+			flags.PUT("/:key", s.flagHandler.SaveFlag)
+			flags.DELETE("/:key", s.flagHandler.DeleteFlag)
+		}
+
+		// Preference schema management; schemas, and the indexes they create on the
+		// users table, are shared by every tenant
+		prefSchemas := platform.Group("/preference-schemas")
+		{
+			prefSchemas.GET("", s.prefHandler.ListSchemas)
+			prefSchemas.PUT("/:key", s.prefHandler.SaveSchema)
+			prefSchemas.DELETE("/:key", s.prefHandler.DeleteSchema)
+		}
+	}
+}
+
 // pingHandler is a simple ping endpoint for testing
 func (s *Server) pingHandler(c *gin.Context) {
 	response.Success(c, "pong", gin.H{
@@ -228,6 +804,28 @@ func (s *Server) Stop(ctx context.Context) error {
 		return err
 	}
 
+	// Stop background jobs before closing the database they use
+	if s.stopJobs != nil {
+		s.stopJobs()
+		select {
+		case <-s.jobsDone:
+		case <-ctx.Done():
+			s.logger.Warn("Timed out waiting for background jobs to stop")
+		}
+	}
+
+	// Stop the sweep of the memory rate limiter
+	if limiter, ok := s.rateLimiter.(*ratelimit.MemoryLimiter); ok {
+		limiter.Stop()
+	}
+
+	// Close the Redis connection of the rate limiter and idempotency store
+	if s.redis != nil {
+		if err := s.redis.Close(); err != nil {
+			s.logger.WithError(err).Error("Failed to close Redis connection")
+		}
+	}
+
 	// Close database connection
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
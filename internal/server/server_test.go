@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/internal/config"
+	"github.com/dev-mayanktiwari/api-server/internal/handler"
+	"github.com/dev-mayanktiwari/api-server/internal/middleware"
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/pkg/maintenance"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminRoutesRequireSuperAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sw, err := maintenance.NewSwitch(maintenance.ModeOff, "")
+	if err != nil {
+		t.Fatalf("Failed to create maintenance switch: %v", err)
+	}
+	s := &Server{maintHandler: handler.NewMaintenanceHandler(sw, nil)}
+	router := gin.New()
+	// Stands in for AuthMiddleware
+	router.Use(func(c *gin.Context) {
+		c.Set("user_role", c.GetHeader("X-User-Role"))
+		c.Next()
+	})
+	admin := middleware.NewRateLimits(config.RateLimitConfig{}, nil, nil).Routes(router.Group("/api/v1/admin"))
+	admin.Use(middleware.AdminMiddleware())
+	s.setupAdminRoutes(admin)
+
+	// Deployment-wide settings are shared by every tenant, so tenant admins can't change them
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/v1/admin/maintenance"},
+		{http.MethodPut, "/api/v1/admin/maintenance"},
+		{http.MethodGet, "/api/v1/admin/feature-flags"},
+		{http.MethodGet, "/api/v1/admin/feature-flags/beta_search"},
+		{http.MethodPut, "/api/v1/admin/feature-flags/beta_search"},
+		{http.MethodDelete, "/api/v1/admin/feature-flags/beta_search"},
+		{http.MethodGet, "/api/v1/admin/preference-schemas"},
+		{http.MethodPut, "/api/v1/admin/preference-schemas/language"},
+		{http.MethodDelete, "/api/v1/admin/preference-schemas/language"},
+	}
+
+	request := func(method, path, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, route := range routes {
+		for _, role := range []string{model.RoleUser, model.RoleAdmin} {
+			t.Run(role+" "+route.method+" "+route.path, func(t *testing.T) {
+				if w := request(route.method, route.path, role); w.Code != http.StatusForbidden {
+					t.Errorf("Expected 403, got %d", w.Code)
+				}
+			})
+		}
+	}
+
+	if w := request(http.MethodGet, "/api/v1/admin/maintenance", model.RoleSuperAdmin); w.Code != http.StatusOK {
+		t.Errorf("Expected super admins to reach the maintenance switch, got %d", w.Code)
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/mailer"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
+)
+
+// cancelDeletionPath is the endpoint that cancels a pending account deletion
+const cancelDeletionPath = "/api/v1/profile/cancel-deletion"
+
+// deletionBatchSize caps how many accounts one run of the deletion job processes
+const deletionBatchSize = 100
+
+// Account deletion modes
+const (
+	DeletionModeSoftDelete = "soft_delete"
+	DeletionModeAnonymize  = "anonymize"
+)
+
+// AccountService handles self-service account deletion and its grace period
+type AccountService struct {
+	userRepo    *repository.UserRepository
+	privacy     *PrivacyService
+	activity    *ActivityService
+	mailer      mailer.Mailer
+	gracePeriod time.Duration
+	mode        string
+	logger      *logger.Logger
+}
+
+// NewAccountService creates a new account service. mode selects what happens when the
+// grace period ends: DeletionModeSoftDelete or DeletionModeAnonymize.
+func NewAccountService(userRepo *repository.UserRepository, privacy *PrivacyService, activity *ActivityService, mailer mailer.Mailer, gracePeriod time.Duration, mode string, logger *logger.Logger) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		privacy:     privacy,
+		activity:    activity,
+		mailer:      mailer,
+		gracePeriod: gracePeriod,
+		mode:        mode,
+		logger:      logger,
+	}
+}
+
+// ScheduleDeletion schedules the caller's account for deletion after the grace period,
+// once their password is re-confirmed
+func (s *AccountService) ScheduleDeletion(ctx context.Context, userID string, req *model.DeleteAccountRequest) (*model.PendingDeletion, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.CheckPassword(req.Password) {
+		return nil, fmt.Errorf("password is incorrect")
+	}
+
+	if user.IsDeletionScheduled() {
+		return nil, fmt.Errorf("deletion already scheduled")
+	}
+
+	at := time.Now().UTC().Add(s.gracePeriod)
+	if err := s.userRepo.SetDeletionSchedule(ctx, userID, &at); err != nil {
+		return nil, err
+	}
+
+	s.activity.Record(ctx, userID, userID, model.ActivityDeletionScheduled, "", map[string]interface{}{
+		"scheduled_for": at,
+	})
+
+	s.logger.LogUserAction(userID, "schedule_account_deletion", "user", map[string]interface{}{
+		"scheduled_for": at,
+	})
+
+	user.DeletionScheduledAt = &at
+	return pendingDeletionFor(user), nil
+}
+
+// CancelDeletion cancels the caller's pending account deletion
+func (s *AccountService) CancelDeletion(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.IsDeletionScheduled() {
+		return fmt.Errorf("no deletion scheduled")
+	}
+
+	if err := s.userRepo.SetDeletionSchedule(ctx, userID, nil); err != nil {
+		return err
+	}
+
+	s.activity.Record(ctx, userID, userID, model.ActivityDeletionCancelled, "", nil)
+	s.logger.LogUserAction(userID, "cancel_account_deletion", "user", nil)
+
+	return nil
+}
+
+// RunDeletionJob carries out due deletions every interval until ctx is cancelled
+func (s *AccountService) RunDeletionJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessDueDeletions(ctx); err != nil {
+			s.logger.WithError(err).Error("Account deletion job failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDueDeletions deletes or anonymizes every account whose grace period has
+// ended and emails each user a confirmation. It returns the number of accounts processed.
+func (s *AccountService) ProcessDueDeletions(ctx context.Context) (int, error) {
+	// The job works through every tenant's due deletions
+	ctx = tenant.WithAllTenants(ctx)
+
+	users, err := s.userRepo.ListDueForDeletion(ctx, time.Now().UTC(), deletionBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i := range users {
+		if ctx.Err() != nil {
+			break
+		}
+
+		user := &users[i]
+		if err := s.deleteAccount(ctx, user); err != nil {
+			s.logger.WithError(err).WithField("user_id", user.ID).Error("Failed to delete scheduled account")
+			continue
+		}
+		processed++
+
+		s.sendDeletionConfirmation(ctx, user)
+	}
+
+	if processed > 0 {
+		s.logger.WithField("count", processed).Info("Scheduled account deletions completed")
+	}
+
+	return processed, nil
+}
+
+// deleteAccount performs the final deletion of a single account
+func (s *AccountService) deleteAccount(ctx context.Context, user *model.User) error {
+	if s.mode == DeletionModeAnonymize {
+		if err := s.privacy.EraseScheduled(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := s.userRepo.SetDeletionSchedule(ctx, user.ID, nil); err != nil {
+			return err
+		}
+	} else if err := s.userRepo.DeleteScheduled(ctx, user.ID, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	// The user may be gone by now, so their tenant can't be looked up
+	s.activity.Record(tenant.WithTenant(ctx, user.TenantID), user.ID, "system", model.ActivityAccountDeleted, "", map[string]interface{}{
+		"mode": s.mode,
+	})
+
+	return nil
+}
+
+// sendDeletionConfirmation emails the user that their account is gone. Failures are
+// only logged since the deletion itself has already happened.
+func (s *AccountService) sendDeletionConfirmation(ctx context.Context, user *model.User) {
+	err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been deleted",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nAs requested, your account has been deleted.\n\nIf you didn't ask for this, please contact support.\n",
+			user.FirstName,
+		),
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("user_id", user.ID).Warn("Failed to send account deletion confirmation")
+	}
+}
+
+// pendingDeletionFor describes a user's scheduled deletion, or returns nil if there is none
+func pendingDeletionFor(user *model.User) *model.PendingDeletion {
+	if !user.IsDeletionScheduled() {
+		return nil
+	}
+
+	return &model.PendingDeletion{
+		ScheduledFor: *user.DeletionScheduledAt,
+		CancelURL:    cancelDeletionPath,
+	}
+}
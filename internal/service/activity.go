@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
+)
+
+// ActivityService records and reads users' activity timelines
+type ActivityService struct {
+	activityRepo *repository.ActivityRepository
+	userRepo     *repository.UserRepository
+	logger       *logger.Logger
+}
+
+// NewActivityService creates a new activity service
+func NewActivityService(activityRepo *repository.ActivityRepository, userRepo *repository.UserRepository, logger *logger.Logger) *ActivityService {
+	return &ActivityService{
+		activityRepo: activityRepo,
+		userRepo:     userRepo,
+		logger:       logger,
+	}
+}
+
+// Record appends an entry to a user's timeline. Failures are logged rather than
+// returned so a timeline outage never fails the action being recorded.
+func (s *ActivityService) Record(ctx context.Context, userID, actorID, action, ip string, details map[string]interface{}) {
+	activity := &model.UserActivity{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		IP:        ip,
+		Details:   details,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	// Entries recorded across tenants take the tenant of the user they belong to
+	if _, ok := tenant.FromContext(ctx); !ok {
+		user, err := s.userRepo.GetByID(tenant.WithAllTenants(ctx), userID)
+		if err != nil {
+			s.logger.WithError(err).WithFields(map[string]interface{}{
+				"user_id": userID,
+				"action":  action,
+			}).Error("Failed to record user activity")
+			return
+		}
+		activity.TenantID = user.TenantID
+	}
+
+	if err := s.activityRepo.Create(ctx, activity); err != nil {
+		s.logger.WithError(err).WithFields(map[string]interface{}{
+			"user_id": userID,
+			"action":  action,
+		}).Error("Failed to record user activity")
+	}
+}
+
+// ListUserActivity retrieves a page of a user's timeline, newest first (admin only).
+// cursor is empty for the first page, then the NextCursor of the previous page.
+func (s *ActivityService) ListUserActivity(ctx context.Context, userID string, cursor string, limit int, currentUserRole string) (*model.ActivityPage, error) {
+	if !model.IsAdminRole(currentUserRole) {
+		return nil, fmt.Errorf("insufficient permissions to view user activity")
+	}
+
+	beforeID, err := decodeActivityCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra entry to know whether another page follows
+	activities, err := s.activityRepo.ListByUser(ctx, userID, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.ActivityPage{Activities: activities}
+	if len(activities) > limit {
+		page.Activities = activities[:limit]
+		page.NextCursor = encodeActivityCursor(page.Activities[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+// encodeActivityCursor returns an opaque cursor pointing after the given entry
+func encodeActivityCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// decodeActivityCursor returns the entry ID a cursor points after, or 0 for an empty cursor
+func decodeActivityCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+
+	return id, nil
+}
+
+// DataHook returns the hook that exports and anonymizes activity for privacy requests
+func (s *ActivityService) DataHook() UserDataHook {
+	return &activityDataHook{activityRepo: s.activityRepo}
+}
+
+// activityDataHook exports a user's timeline and strips IPs and details on erasure,
+// keeping the entries themselves as an audit trail
+type activityDataHook struct {
+	activityRepo *repository.ActivityRepository
+}
+
+// Name implements UserDataHook
+func (h *activityDataHook) Name() string {
+	return "activity"
+}
+
+// Export implements UserDataHook
+func (h *activityDataHook) Export(ctx context.Context, userID string) (interface{}, error) {
+	return h.activityRepo.ListByUser(ctx, userID, 0, 0)
+}
+
+// Erase implements UserDataHook
+func (h *activityDataHook) Erase(ctx context.Context, userID string) error {
+	return h.activityRepo.AnonymizeByUser(ctx, userID)
+}
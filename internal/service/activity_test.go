@@ -0,0 +1,46 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+)
+
+func TestActivityCursor(t *testing.T) {
+	cursor := encodeActivityCursor(12345)
+
+	id, err := decodeActivityCursor(cursor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if id != 12345 {
+		t.Errorf("Expected 12345, got %d", id)
+	}
+
+	if id, err := decodeActivityCursor(""); err != nil || id != 0 {
+		t.Errorf("Expected empty cursor to decode to 0, got %d, %v", id, err)
+	}
+
+	for _, invalid := range []string{"not base64!", encodeActivityCursor(0), "YWJj"} {
+		if _, err := decodeActivityCursor(invalid); err == nil {
+			t.Errorf("Expected error for cursor %q", invalid)
+		}
+	}
+}
+
+func TestChangedUserFields(t *testing.T) {
+	before := &model.User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Role: "user", IsActive: true}
+	after := *before
+	after.FirstName = "Janet"
+	after.IsActive = false
+
+	fields := changedUserFields(before, &after)
+	if !reflect.DeepEqual(fields, []string{"first_name", "is_active"}) {
+		t.Errorf("Expected [first_name is_active], got %v", fields)
+	}
+
+	if fields := changedUserFields(before, before); len(fields) != 0 {
+		t.Errorf("Expected no changes, got %v", fields)
+	}
+}
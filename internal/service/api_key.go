@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/auth"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// maxAPIKeysPerUser caps how many API keys a user can hold
+	maxAPIKeysPerUser = 10
+	// apiKeyTouchInterval limits how often a key's last use is written
+	apiKeyTouchInterval = time.Minute
+	// apiKeyDisplayLength is how much of a key is kept to tell keys apart
+	apiKeyDisplayLength = len(model.APIKeyPrefix) + 8
+)
+
+// APIKeyService handles API keys and authenticates the requests that use them
+type APIKeyService struct {
+	keyRepo  *repository.APIKeyRepository
+	userRepo *repository.UserRepository
+	logger   *logger.Logger
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(keyRepo *repository.APIKeyRepository, userRepo *repository.UserRepository, logger *logger.Logger) *APIKeyService {
+	return &APIKeyService{
+		keyRepo:  keyRepo,
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// CreateAPIKey creates an API key for the current user. The returned key is the only
+// time the key itself is available.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req *model.CreateAPIKeyRequest, currentUserID string) (*model.CreatedAPIKey, error) {
+	count, err := s.keyRepo.CountByUser(ctx, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, fmt.Errorf("api key limit reached")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := model.APIKeyPrefix + hex.EncodeToString(secret)
+
+	apiKey := model.APIKey{
+		UserID:  currentUserID,
+		Name:    strings.TrimSpace(req.Name),
+		Prefix:  key[:apiKeyDisplayLength],
+		KeyHash: hashAPIKey(key),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := s.keyRepo.Create(ctx, &apiKey); err != nil {
+		return nil, err
+	}
+
+	s.logger.LogUserAction(currentUserID, "create_api_key", "api_key", map[string]interface{}{
+		"api_key_id": apiKey.ID,
+		"name":       apiKey.Name,
+	})
+
+	return &model.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys lists the current user's API keys
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, currentUserID string) ([]model.APIKey, error) {
+	return s.keyRepo.ListByUser(ctx, currentUserID)
+}
+
+// RevokeAPIKey deletes one of the current user's API keys
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id, currentUserID string) error {
+	if err := s.keyRepo.Delete(ctx, currentUserID, id); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "revoke_api_key", "api_key", map[string]interface{}{
+		"api_key_id": id,
+	})
+
+	return nil
+}
+
+// AuthenticateAPIKey returns the claims of the user an API key belongs to, as if they
+// had sent a token. The claims' ID is the ID of the key. It is used by the auth middleware.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Claims, error) {
+	if !strings.HasPrefix(key, model.APIKeyPrefix) {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	// The tenant isn't known until the key and its user are loaded
+	ctx = tenant.WithAllTenants(ctx)
+
+	apiKey, err := s.keyRepo.GetByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return nil, fmt.Errorf("invalid api key")
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if apiKey.IsExpired(now) {
+		return nil, fmt.Errorf("api key has expired")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "user not found") {
+			return nil, fmt.Errorf("invalid api key")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("account is deactivated")
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.keyRepo.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+			// Don't fail the request over bookkeeping
+			s.logger.WithError(err).WithField("api_key_id", apiKey.ID).Error("Failed to record API key use")
+		}
+	}
+
+	return &auth.Claims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		TenantID: user.TenantID,
+		Plan:     user.Plan,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:      apiKey.ID,
+			Subject: user.ID,
+		},
+	}, nil
+}
+
+// hashAPIKey returns the hash an API key is stored and looked up by. Keys are random,
+// so a fast unsalted hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// DataHook returns the hook that exports and erases API keys for privacy requests
+func (s *APIKeyService) DataHook() UserDataHook {
+	return &apiKeyDataHook{keyRepo: s.keyRepo}
+}
+
+// apiKeyDataHook exports a user's API keys, without the key hashes, and on erasure
+// revokes them
+type apiKeyDataHook struct {
+	keyRepo *repository.APIKeyRepository
+}
+
+// Name implements UserDataHook
+func (h *apiKeyDataHook) Name() string {
+	return "api_keys"
+}
+
+// Export implements UserDataHook
+func (h *apiKeyDataHook) Export(ctx context.Context, userID string) (interface{}, error) {
+	return h.keyRepo.ListByUser(ctx, userID)
+}
+
+// Erase implements UserDataHook
+func (h *apiKeyDataHook) Erase(ctx context.Context, userID string) error {
+	return h.keyRepo.DeleteByUser(ctx, userID)
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Register decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/storage"
+	"golang.org/x/image/draw"
+)
+
+// maxAvatarPixels caps decoded image dimensions so small, highly compressed files
+// can't expand into huge bitmaps
+const maxAvatarPixels = 40_000_000
+
+// allowedAvatarTypes are the sniffed content types accepted for avatar uploads
+var allowedAvatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// AvatarService handles avatar uploads, processing and storage
+type AvatarService struct {
+	userRepo *repository.UserRepository
+	activity *ActivityService
+	store    storage.BlobStore
+	maxSize  int64
+	logger   *logger.Logger
+}
+
+// NewAvatarService creates a new avatar service
+func NewAvatarService(userRepo *repository.UserRepository, activity *ActivityService, store storage.BlobStore, maxSize int64, logger *logger.Logger) *AvatarService {
+	return &AvatarService{
+		userRepo: userRepo,
+		activity: activity,
+		store:    store,
+		maxSize:  maxSize,
+		logger:   logger,
+	}
+}
+
+// MaxSize returns the maximum accepted upload size in bytes
+func (s *AvatarService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// UploadAvatar validates an uploaded image, stores it re-encoded at every standard
+// size and replaces the user's previous avatar. Re-encoding from decoded pixels drops
+// EXIF and any other embedded metadata.
+func (s *AvatarService) UploadAvatar(ctx context.Context, userID string, data []byte) (*model.SafeUser, error) {
+	if int64(len(data)) > s.maxSize {
+		return nil, fmt.Errorf("avatar exceeds maximum size")
+	}
+
+	if !allowedAvatarTypes[http.DetectContentType(data)] {
+		return nil, fmt.Errorf("unsupported avatar format")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid avatar image")
+	}
+	if config.Width*config.Height > maxAvatarPixels {
+		return nil, fmt.Errorf("avatar dimensions are too large")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid avatar image")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := newAvatarPrefix(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storeSizes(ctx, prefix, src); err != nil {
+		s.deleteSizes(ctx, prefix)
+		return nil, err
+	}
+
+	previous := user.AvatarKey
+	user.AvatarKey = prefix
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		s.deleteSizes(ctx, prefix)
+		if err.Error() == "version mismatch" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if previous != "" {
+		s.deleteSizes(ctx, previous)
+	}
+
+	s.activity.Record(ctx, userID, userID, model.ActivityAvatarUpdated, "", nil)
+
+	s.logger.LogUserAction(userID, "upload_avatar", "user", map[string]interface{}{
+		"width":  config.Width,
+		"height": config.Height,
+	})
+
+	safeUser := user.ToSafeUser()
+	return &safeUser, nil
+}
+
+// DeleteAvatar removes the user's avatar
+func (s *AvatarService) DeleteAvatar(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.HasAvatar() {
+		return fmt.Errorf("avatar not found")
+	}
+
+	if err := s.clearAvatar(ctx, user); err != nil {
+		return err
+	}
+
+	s.activity.Record(ctx, userID, userID, model.ActivityAvatarDeleted, "", nil)
+
+	s.logger.LogUserAction(userID, "delete_avatar", "user", nil)
+	return nil
+}
+
+// clearAvatar unsets the user's avatar and deletes its images
+func (s *AvatarService) clearAvatar(ctx context.Context, user *model.User) error {
+	previous := user.AvatarKey
+	user.AvatarKey = ""
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if err.Error() == "version mismatch" {
+			return err
+		}
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	s.deleteSizes(ctx, previous)
+	return nil
+}
+
+// storeSizes renders and uploads the image at every standard size
+func (s *AvatarService) storeSizes(ctx context.Context, prefix string, src image.Image) error {
+	for size, pixels := range model.AvatarSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, renderAvatar(src, pixels), &jpeg.Options{Quality: 85}); err != nil {
+			return fmt.Errorf("failed to encode avatar: %w", err)
+		}
+
+		key := model.AvatarObjectKey(prefix, size)
+		if err := s.store.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/jpeg"); err != nil {
+			return fmt.Errorf("failed to store avatar: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteSizes removes every stored size of an avatar. Failures are logged, not returned,
+// since a leftover blob is harmless once nothing references it.
+func (s *AvatarService) deleteSizes(ctx context.Context, prefix string) {
+	for size := range model.AvatarSizes {
+		key := model.AvatarObjectKey(prefix, size)
+		if err := s.store.Delete(ctx, key); err != nil {
+			s.logger.WithError(err).WithField("key", key).Warn("Failed to delete avatar blob")
+		}
+	}
+}
+
+// renderAvatar center-crops the image to a square and scales it to size x size pixels,
+// flattening any transparency onto white since the output is JPEG
+func renderAvatar(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	crop := image.Rect(0, 0, side, side).Add(image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	})
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+	return dst
+}
+
+// newAvatarPrefix returns a fresh blob key prefix so a new upload never overwrites
+// images that cached links may still point at
+func newAvatarPrefix(userID string) (string, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate avatar key: %w", err)
+	}
+	return "avatars/" + userID + "/" + hex.EncodeToString(token), nil
+}
+
+// DataHook returns the hook that exports and erases avatar data for privacy requests
+func (s *AvatarService) DataHook() UserDataHook {
+	return &avatarDataHook{service: s}
+}
+
+// avatarDataHook exports avatar links and deletes avatar images on erasure
+type avatarDataHook struct {
+	service *AvatarService
+}
+
+// Name implements UserDataHook
+func (h *avatarDataHook) Name() string {
+	return "avatar"
+}
+
+// Export implements UserDataHook
+func (h *avatarDataHook) Export(ctx context.Context, userID string) (interface{}, error) {
+	user, err := h.service.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.AvatarURLs(), nil
+}
+
+// Erase implements UserDataHook
+func (h *avatarDataHook) Erase(ctx context.Context, userID string) error {
+	user, err := h.service.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.HasAvatar() {
+		return nil
+	}
+
+	return h.service.clearAvatar(context.Background(), user)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/featureflag"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+)
+
+// FeatureFlagService manages feature flags and evaluates them. Flags come from an
+// optional YAML file and from the database, database flags winning; both are held in
+// memory and refreshed by RunRefreshJob, and at once by the instance that changes a flag.
+type FeatureFlagService struct {
+	flagRepo *repository.FeatureFlagRepository
+	flags    *featureflag.Set
+	logger   *logger.Logger
+}
+
+// NewFeatureFlagService creates a new feature flag service. flagsFile may be empty.
+func NewFeatureFlagService(flagRepo *repository.FeatureFlagRepository, flagsFile string, logger *logger.Logger) *FeatureFlagService {
+	s := &FeatureFlagService{
+		flagRepo: flagRepo,
+		logger:   logger,
+	}
+
+	var sources []featureflag.Source
+	if flagsFile != "" {
+		sources = append(sources, featureflag.NewFileSource(flagsFile))
+	}
+	sources = append(sources, featureflag.SourceFunc(s.databaseFlags))
+	s.flags = featureflag.NewSet(sources...)
+
+	return s
+}
+
+// databaseFlags loads the flags stored in the database for evaluation
+func (s *FeatureFlagService) databaseFlags(ctx context.Context) ([]featureflag.Flag, error) {
+	stored, err := s.flagRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]featureflag.Flag, len(stored))
+	for i := range stored {
+		flags[i] = stored[i].Flag()
+	}
+	return flags, nil
+}
+
+// Refresh reloads the flags from their sources; on failure the flags loaded last stay
+// in effect
+func (s *FeatureFlagService) Refresh(ctx context.Context) error {
+	return s.flags.Refresh(ctx)
+}
+
+// RunRefreshJob refreshes the flags every interval until ctx is done, picking up
+// changes to the flags file and flags changed through other instances
+func (s *FeatureFlagService) RunRefreshJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Refresh(ctx); err != nil {
+			s.logger.WithError(err).Error("Failed to refresh feature flags, keeping the current flags")
+		}
+	}
+}
+
+// Enabled returns whether a flag is on for the subject; unknown flags are off
+func (s *FeatureFlagService) Enabled(key string, subject featureflag.Subject) bool {
+	return s.flags.Enabled(key, subject)
+}
+
+// Evaluate returns every flag evaluated for the subject
+func (s *FeatureFlagService) Evaluate(subject featureflag.Subject) map[string]bool {
+	return s.flags.Evaluate(subject)
+}
+
+// ListFlags retrieves the flags stored in the database (super admin only)
+func (s *FeatureFlagService) ListFlags(ctx context.Context) ([]model.FeatureFlag, error) {
+	return s.flagRepo.List(ctx)
+}
+
+// GetFlag retrieves a flag stored in the database (super admin only)
+func (s *FeatureFlagService) GetFlag(ctx context.Context, key string) (*model.FeatureFlag, error) {
+	return s.flagRepo.GetByKey(ctx, key)
+}
+
+// SaveFlag creates or replaces a flag (super admin only)
+func (s *FeatureFlagService) SaveFlag(ctx context.Context, key string, req *model.FeatureFlagRequest, currentUserID string) (*model.FeatureFlag, error) {
+	flag := &model.FeatureFlag{
+		Key:          key,
+		Description:  req.Description,
+		Enabled:      req.Enabled,
+		Rollout:      req.Rollout,
+		Users:        model.StringList(req.Users),
+		Roles:        model.StringList(req.Roles),
+		EmailDomains: model.StringList(req.EmailDomains),
+		UpdatedBy:    currentUserID,
+	}
+	if err := flag.Flag().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid feature flag: %w", err)
+	}
+
+	if err := s.flagRepo.Save(ctx, flag); err != nil {
+		return nil, err
+	}
+	s.refreshAfterChange(ctx)
+
+	s.logger.LogUserAction(currentUserID, "save_feature_flag", "feature_flag", map[string]interface{}{
+		"key":     key,
+		"enabled": flag.Enabled,
+		"rollout": flag.Rollout,
+	})
+
+	return flag, nil
+}
+
+// DeleteFlag removes a flag (super admin only). A flag with the same key in the flags file
+// takes effect again.
+func (s *FeatureFlagService) DeleteFlag(ctx context.Context, key string, currentUserID string) error {
+	if err := s.flagRepo.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.refreshAfterChange(ctx)
+
+	s.logger.LogUserAction(currentUserID, "delete_feature_flag", "feature_flag", map[string]interface{}{
+		"key": key,
+	})
+
+	return nil
+}
+
+// refreshAfterChange applies a change on this instance right away; the change is saved,
+// so a failed refresh only delays it until the next scheduled one
+func (s *FeatureFlagService) refreshAfterChange(ctx context.Context) {
+	if err := s.Refresh(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh feature flags after a change")
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// OrganizationService handles organizations (tenants)
+type OrganizationService struct {
+	orgRepo *repository.OrganizationRepository
+	logger  *logger.Logger
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(orgRepo *repository.OrganizationRepository, logger *logger.Logger) *OrganizationService {
+	return &OrganizationService{
+		orgRepo: orgRepo,
+		logger:  logger,
+	}
+}
+
+// ResolveTenant finds an organization by ID or slug. It is used by the tenant middleware.
+func (s *OrganizationService) ResolveTenant(ref string) (*model.Organization, error) {
+	if _, err := uuid.Parse(ref); err == nil {
+		return s.orgRepo.GetByID(ref)
+	}
+
+	return s.orgRepo.GetBySlug(strings.ToLower(ref))
+}
+
+// ListOrganizations lists every organization (super admin only)
+func (s *OrganizationService) ListOrganizations() ([]model.Organization, error) {
+	return s.orgRepo.List()
+}
+
+// GetOrganization retrieves an organization (super admin only)
+func (s *OrganizationService) GetOrganization(id string) (*model.Organization, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("organization not found")
+	}
+
+	return s.orgRepo.GetByID(id)
+}
+
+// CreateOrganization creates a new organization (super admin only)
+func (s *OrganizationService) CreateOrganization(req *model.CreateOrganizationRequest, currentUserID string) (*model.Organization, error) {
+	slug := strings.ToLower(req.Slug)
+	if !model.IsValidOrganizationSlug(slug) {
+		return nil, fmt.Errorf("invalid organization slug")
+	}
+
+	if _, err := s.orgRepo.GetBySlug(slug); err == nil {
+		return nil, fmt.Errorf("organization slug is already taken")
+	} else if err.Error() != "organization not found" {
+		return nil, err
+	}
+
+	org := &model.Organization{
+		Name:     req.Name,
+		Slug:     slug,
+		IsActive: true,
+	}
+
+	if err := s.orgRepo.Create(org); err != nil {
+		return nil, err
+	}
+
+	s.logger.LogUserAction(currentUserID, "create_organization", "organization", map[string]interface{}{
+		"organization_id": org.ID,
+		"slug":            org.Slug,
+	})
+
+	return org, nil
+}
+
+// UpdateOrganization renames or (de)activates an organization (super admin only).
+// Users of an inactive organization can no longer sign in or use the API.
+func (s *OrganizationService) UpdateOrganization(id string, req *model.UpdateOrganizationRequest, currentUserID string) (*model.Organization, error) {
+	org, err := s.GetOrganization(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		org.Name = req.Name
+	}
+	if req.IsActive != nil {
+		org.IsActive = *req.IsActive
+	}
+
+	if err := s.orgRepo.Update(org); err != nil {
+		return nil, err
+	}
+
+	s.logger.LogUserAction(currentUserID, "update_organization", "organization", map[string]interface{}{
+		"organization_id": org.ID,
+	})
+
+	return org, nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// PreferenceService handles user preferences and the admin-defined schemas that govern them
+type PreferenceService struct {
+	preferenceRepo *repository.PreferenceRepository
+	userRepo       *repository.UserRepository
+	activity       *ActivityService
+	logger         *logger.Logger
+}
+
+// NewPreferenceService creates a new preference service
+func NewPreferenceService(preferenceRepo *repository.PreferenceRepository, userRepo *repository.UserRepository, activity *ActivityService, logger *logger.Logger) *PreferenceService {
+	return &PreferenceService{
+		preferenceRepo: preferenceRepo,
+		userRepo:       userRepo,
+		activity:       activity,
+		logger:         logger,
+	}
+}
+
+// ListSchemas retrieves every defined preference key
+func (s *PreferenceService) ListSchemas() ([]model.PreferenceSchema, error) {
+	return s.preferenceRepo.List()
+}
+
+// SaveSchema defines or replaces the schema of a preference key (super admin only)
+func (s *PreferenceService) SaveSchema(key string, req *model.PreferenceSchemaRequest, currentUserID string) (*model.PreferenceSchema, error) {
+	if !model.IsValidPreferenceKey(key) {
+		return nil, fmt.Errorf("invalid preference key: must start with a lowercase letter and contain only lowercase letters, digits and underscores (max 48)")
+	}
+
+	schema := &model.PreferenceSchema{
+		Key:         key,
+		Description: req.Description,
+		Schema:      req.Schema,
+		Indexed:     req.Indexed,
+	}
+
+	compiled, err := compilePreferenceSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := schema.Default(); ok {
+		if err := compiled.Validate(value); err != nil {
+			return nil, fmt.Errorf("invalid schema: default value does not match the schema: %s", validationMessage(err))
+		}
+	}
+
+	if err := s.preferenceRepo.Save(schema); err != nil {
+		return nil, err
+	}
+
+	s.logger.LogUserAction(currentUserID, "save_preference_schema", "preference_schema", map[string]interface{}{
+		"key":     key,
+		"indexed": schema.Indexed,
+	})
+
+	return schema, nil
+}
+
+// DeleteSchema removes a preference key (super admin only)
+func (s *PreferenceService) DeleteSchema(key string, currentUserID string) error {
+	if err := s.preferenceRepo.Delete(key); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "delete_preference_schema", "preference_schema", map[string]interface{}{
+		"key": key,
+	})
+
+	return nil
+}
+
+// GetPreferences returns the user's preferences for every defined key, falling back
+// to the schema default for keys the user hasn't set
+func (s *PreferenceService) GetPreferences(ctx context.Context, userID string) (model.JSONMap, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := s.preferenceRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return effectivePreferences(user.Preferences, schemas), nil
+}
+
+// UpdatePreferences replaces the user's preferences after validating every value
+// against its key's schema. Keys set to null are removed.
+func (s *PreferenceService) UpdatePreferences(ctx context.Context, userID string, preferences map[string]interface{}) (model.JSONMap, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := s.preferenceRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := validatePreferences(preferences, schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Preferences = stored
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.activity.Record(ctx, userID, userID, model.ActivityPreferencesUpdated, "", map[string]interface{}{
+		"keys": sortedKeys(stored),
+	})
+
+	s.logger.LogUserAction(userID, "update_preferences", "user", map[string]interface{}{
+		"keys": sortedKeys(stored),
+	})
+
+	return effectivePreferences(stored, schemas), nil
+}
+
+// validatePreferences checks preferences against the schemas and returns the map to store
+func validatePreferences(preferences map[string]interface{}, schemas []model.PreferenceSchema) (model.JSONMap, error) {
+	byKey := make(map[string]*model.PreferenceSchema, len(schemas))
+	for i := range schemas {
+		byKey[schemas[i].Key] = &schemas[i]
+	}
+
+	stored := model.JSONMap{}
+	for _, key := range sortedKeys(preferences) {
+		value := preferences[key]
+		if value == nil {
+			continue
+		}
+
+		schema, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown preference: %s", key)
+		}
+
+		compiled, err := compilePreferenceSchema(schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile preference schema %s: %w", key, err)
+		}
+
+		if err := compiled.Validate(value); err != nil {
+			return nil, fmt.Errorf("invalid preference value: %s: %s", key, validationMessage(err))
+		}
+
+		stored[key] = value
+	}
+
+	return stored, nil
+}
+
+// effectivePreferences returns the stored values of defined keys plus defaults for unset ones.
+// Values stored under keys that are no longer defined are left out.
+func effectivePreferences(stored model.JSONMap, schemas []model.PreferenceSchema) model.JSONMap {
+	result := model.JSONMap{}
+	for i := range schemas {
+		if value, ok := stored[schemas[i].Key]; ok {
+			result[schemas[i].Key] = value
+		} else if value, ok := schemas[i].Default(); ok {
+			result[schemas[i].Key] = value
+		}
+	}
+	return result
+}
+
+// compilePreferenceSchema compiles a preference schema. Remote references are refused
+// so an admin-supplied schema can't make the server fetch arbitrary URLs.
+func compilePreferenceSchema(schema *model.PreferenceSchema) (*jsonschema.Schema, error) {
+	data, err := json.Marshal(schema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+
+	url := "preference://" + schema.Key + ".json"
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("remote schema references are not allowed: %s", s)
+	}
+
+	if err := compiler.AddResource(url, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+
+	return compiled, nil
+}
+
+// validationMessage returns the most specific message of a schema validation error
+func validationMessage(err error) string {
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err.Error()
+	}
+
+	leaf := validationErr
+	for len(leaf.Causes) > 0 {
+		leaf = leaf.Causes[0]
+	}
+
+	if leaf.InstanceLocation != "" {
+		return leaf.InstanceLocation + ": " + leaf.Message
+	}
+	return leaf.Message
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+)
+
+func TestValidatePreferences(t *testing.T) {
+	schemas := []model.PreferenceSchema{
+		{Key: "locale", Schema: model.JSONMap{"type": "string", "enum": []interface{}{"en", "de", "fr"}, "default": "en"}},
+		{Key: "notifications", Schema: model.JSONMap{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"email": map[string]interface{}{"type": "boolean"},
+			},
+			"additionalProperties": false,
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError string
+		expect      string
+	}{
+		{
+			name:   "valid values",
+			input:  `{"locale": "de", "notifications": {"email": false}}`,
+			expect: `{"locale":"de","notifications":{"email":false}}`,
+		},
+		{
+			name:   "null removes a key",
+			input:  `{"locale": null}`,
+			expect: `{}`,
+		},
+		{
+			name:        "unknown key",
+			input:       `{"theme": "dark"}`,
+			expectError: "unknown preference: theme",
+		},
+		{
+			name:        "value outside enum",
+			input:       `{"locale": "xx"}`,
+			expectError: "invalid preference value: locale",
+		},
+		{
+			name:        "nested property of wrong type",
+			input:       `{"notifications": {"email": "yes"}}`,
+			expectError: "invalid preference value: notifications: /email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+				t.Fatalf("Invalid test input: %v", err)
+			}
+
+			stored, err := validatePreferences(input, schemas)
+			if tt.expectError != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.expectError) {
+					t.Fatalf("Expected error starting with %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got, _ := json.Marshal(stored)
+			if string(got) != tt.expect {
+				t.Errorf("Expected %s, got %s", tt.expect, got)
+			}
+		})
+	}
+
+	effective := effectivePreferences(model.JSONMap{"removed_key": true}, schemas)
+	if len(effective) != 1 || effective["locale"] != "en" {
+		t.Errorf("Expected only the locale default, got %v", effective)
+	}
+}
+
+func TestCompilePreferenceSchemaRejectsRemoteReferences(t *testing.T) {
+	schema := &model.PreferenceSchema{
+		Key:    "remote",
+		Schema: model.JSONMap{"$ref": "http://169.254.169.254/latest/meta-data"},
+	}
+
+	if _, err := compilePreferenceSchema(schema); err == nil || !strings.HasPrefix(err.Error(), "invalid schema") {
+		t.Errorf("Expected remote reference to be rejected, got %v", err)
+	}
+}
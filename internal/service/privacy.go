@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+)
+
+// UserDataHook exports and erases one category of personal data stored about a user.
+// Features that persist user data register a hook so data-subject requests stay complete.
+type UserDataHook interface {
+	// Name returns the category name used as the key in exports
+	Name() string
+	// Export returns everything this category stores about the user
+	Export(ctx context.Context, userID string) (interface{}, error)
+	// Erase removes or anonymizes this category's data for the user
+	Erase(ctx context.Context, userID string) error
+}
+
+// PrivacyService handles data-subject requests (data export and right to erasure)
+type PrivacyService struct {
+	userRepo *repository.UserRepository
+	activity *ActivityService
+	hooks    []UserDataHook
+	logger   *logger.Logger
+}
+
+// NewPrivacyService creates a new privacy service with the profile and activity hooks registered
+func NewPrivacyService(userRepo *repository.UserRepository, activity *ActivityService, logger *logger.Logger) *PrivacyService {
+	s := &PrivacyService{
+		userRepo: userRepo,
+		activity: activity,
+		logger:   logger,
+	}
+
+	s.RegisterHook(&profileDataHook{userRepo: userRepo})
+	s.RegisterHook(activity.DataHook())
+
+	return s
+}
+
+// RegisterHook adds a data hook to the export and erasure registry
+func (s *PrivacyService) RegisterHook(hook UserDataHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// ExportUserData collects the data of every registered hook for a user
+func (s *PrivacyService) ExportUserData(ctx context.Context, userID string, currentUserID string, currentUserRole string) (*model.DataExport, error) {
+	if !s.canAccessUserData(userID, currentUserID, currentUserRole) {
+		return nil, fmt.Errorf("insufficient permissions to access user data")
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	export := &model.DataExport{
+		UserID:      userID,
+		GeneratedAt: time.Now().UTC(),
+		Data:        make(map[string]interface{}, len(s.hooks)),
+	}
+
+	for _, hook := range s.hooks {
+		data, err := hook.Export(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s data: %w", hook.Name(), err)
+		}
+		export.Data[hook.Name()] = data
+	}
+
+	s.activity.Record(ctx, userID, currentUserID, model.ActivityDataExported, "", nil)
+
+	s.logger.LogUserAction(currentUserID, "export_user_data", "user", map[string]interface{}{
+		"target_user_id": userID,
+	})
+
+	return export, nil
+}
+
+// EraseOwnAccount erases the caller's personal data after re-confirming their password
+func (s *PrivacyService) EraseOwnAccount(ctx context.Context, userID string, req *model.EraseAccountRequest) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.CheckPassword(req.Password) {
+		return fmt.Errorf("password is incorrect")
+	}
+
+	return s.erase(ctx, user, userID)
+}
+
+// EraseUser erases the personal data of any user (admin only)
+func (s *PrivacyService) EraseUser(ctx context.Context, userID string, currentUserID string, currentUserRole string) error {
+	if !model.IsAdminRole(currentUserRole) {
+		return fmt.Errorf("insufficient permissions to erase user data")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.erase(ctx, user, currentUserID)
+}
+
+// EraseScheduled erases a user whose self-service deletion is due. It is called by the
+// account deletion job and does nothing if the deletion was cancelled in the meantime.
+func (s *PrivacyService) EraseScheduled(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.IsDeletionScheduled() || user.DeletionScheduledAt.After(time.Now().UTC()) {
+		return fmt.Errorf("deletion not due")
+	}
+
+	return s.erase(ctx, user, "system")
+}
+
+// erase runs every hook's erasure in reverse registration order so the profile,
+// which other categories reference, is anonymized last
+func (s *PrivacyService) erase(ctx context.Context, user *model.User, actorID string) error {
+	if user.IsErased() {
+		return fmt.Errorf("user data already erased")
+	}
+
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		hook := s.hooks[i]
+		if err := hook.Erase(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to erase %s data: %w", hook.Name(), err)
+		}
+	}
+
+	s.activity.Record(ctx, user.ID, actorID, model.ActivityUserErased, "", nil)
+
+	s.logger.LogUserAction(actorID, "erase_user_data", "user", map[string]interface{}{
+		"target_user_id": user.ID,
+	})
+
+	return nil
+}
+
+// canAccessUserData checks if the current user can export the target user's data
+func (s *PrivacyService) canAccessUserData(targetUserID, currentUserID, currentUserRole string) bool {
+	// Admins can export anyone's data
+	if model.IsAdminRole(currentUserRole) {
+		return true
+	}
+
+	// Users can only export their own data
+	return targetUserID == currentUserID
+}
+
+// profileDataHook exports and anonymizes the user record itself
+type profileDataHook struct {
+	userRepo *repository.UserRepository
+}
+
+// Name implements UserDataHook
+func (h *profileDataHook) Name() string {
+	return "profile"
+}
+
+// Export implements UserDataHook
+func (h *profileDataHook) Export(ctx context.Context, userID string) (interface{}, error) {
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.ToSafeUser(), nil
+}
+
+// Erase implements UserDataHook by anonymizing PII in place, keeping the row
+// (and its ID) so records referencing the user stay valid
+func (h *profileDataHook) Erase(ctx context.Context, userID string) error {
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Replace the password with a random one nobody knows
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate random password: %w", err)
+	}
+	if err := user.SetPassword(hex.EncodeToString(secret)); err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now().UTC()
+	user.Email = fmt.Sprintf("erased-%s@erased.invalid", user.ID)
+	user.FirstName = "Erased"
+	user.LastName = "User"
+	user.Preferences = model.JSONMap{}
+	user.IsActive = false
+	user.ErasedAt = &now
+
+	if err := h.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	return h.userRepo.ClearLastLoginIP(ctx, user.ID)
+}
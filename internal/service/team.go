@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/mailer"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
+)
+
+// teamInvitationTTL is how long a team invitation stays open
+const teamInvitationTTL = 7 * 24 * time.Hour
+
+// TeamService handles teams, their memberships and invitations
+type TeamService struct {
+	teamRepo *repository.TeamRepository
+	userRepo *repository.UserRepository
+	mailer   mailer.Mailer
+	logger   *logger.Logger
+}
+
+// NewTeamService creates a new team service
+func NewTeamService(teamRepo *repository.TeamRepository, userRepo *repository.UserRepository, mailer mailer.Mailer, logger *logger.Logger) *TeamService {
+	return &TeamService{
+		teamRepo: teamRepo,
+		userRepo: userRepo,
+		mailer:   mailer,
+		logger:   logger,
+	}
+}
+
+// GetMemberRole returns a user's role in a team. It is used by the team role middleware.
+func (s *TeamService) GetMemberRole(ctx context.Context, teamID, userID string) (string, error) {
+	membership, err := s.teamRepo.GetMembership(ctx, teamID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return membership.Role, nil
+}
+
+// CreateTeam creates a team owned by the current user
+func (s *TeamService) CreateTeam(ctx context.Context, req *model.CreateTeamRequest, currentUserID string) (*model.TeamWithRole, error) {
+	team := &model.Team{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		CreatedBy:   currentUserID,
+	}
+
+	if team.Name == "" {
+		return nil, fmt.Errorf("team name is required")
+	}
+
+	// Teams created across tenants belong to their creator's tenant
+	if _, ok := tenant.FromContext(ctx); !ok {
+		creator, err := s.userRepo.GetByID(tenant.WithAllTenants(ctx), currentUserID)
+		if err != nil {
+			return nil, err
+		}
+		team.TenantID = creator.TenantID
+	}
+
+	if err := s.teamRepo.Create(ctx, team, currentUserID); err != nil {
+		return nil, err
+	}
+
+	s.logger.LogUserAction(currentUserID, "create_team", "team", map[string]interface{}{
+		"team_id": team.ID,
+	})
+
+	return &model.TeamWithRole{Team: *team, Role: model.TeamRoleOwner}, nil
+}
+
+// ListTeams lists the teams the current user belongs to
+func (s *TeamService) ListTeams(ctx context.Context, currentUserID string) ([]model.TeamWithRole, error) {
+	return s.teamRepo.ListByUser(ctx, currentUserID)
+}
+
+// GetTeam retrieves a team along with the caller's role in it
+func (s *TeamService) GetTeam(ctx context.Context, teamID, currentTeamRole string) (*model.TeamWithRole, error) {
+	team, err := s.teamRepo.GetByID(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TeamWithRole{Team: *team, Role: currentTeamRole}, nil
+}
+
+// UpdateTeam updates a team's name and description (admin or owner)
+func (s *TeamService) UpdateTeam(ctx context.Context, teamID string, req *model.UpdateTeamRequest, currentUserID string) (*model.Team, error) {
+	team, err := s.teamRepo.GetByID(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("team name is required")
+		}
+		team.Name = name
+	}
+	if req.Description != nil {
+		team.Description = *req.Description
+	}
+
+	if err := s.teamRepo.Update(ctx, team); err != nil {
+		return nil, err
+	}
+
+	s.logger.LogUserAction(currentUserID, "update_team", "team", map[string]interface{}{
+		"team_id": teamID,
+	})
+
+	return team, nil
+}
+
+// DeleteTeam deletes a team (owner only)
+func (s *TeamService) DeleteTeam(ctx context.Context, teamID, currentUserID string) error {
+	if err := s.teamRepo.Delete(ctx, teamID); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "delete_team", "team", map[string]interface{}{
+		"team_id": teamID,
+	})
+
+	return nil
+}
+
+// ListMembers lists a team's members
+func (s *TeamService) ListMembers(ctx context.Context, teamID string) ([]model.TeamMember, error) {
+	return s.teamRepo.ListMembers(ctx, teamID)
+}
+
+// UpdateMemberRole changes another member's role. Nobody can grant a role above their
+// own, and only owners can promote to or demote from owner.
+func (s *TeamService) UpdateMemberRole(ctx context.Context, teamID, userID string, req *model.UpdateTeamMemberRequest, currentUserID, currentTeamRole string) error {
+	if !model.IsValidTeamRole(req.Role) {
+		return fmt.Errorf("invalid team role")
+	}
+
+	if userID == currentUserID {
+		return fmt.Errorf("cannot change your own team role")
+	}
+
+	membership, err := s.teamRepo.GetMembership(ctx, teamID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTeamRoleChange(currentTeamRole, membership.Role, req.Role); err != nil {
+		return err
+	}
+
+	if err := s.teamRepo.UpdateMemberRole(ctx, teamID, userID, req.Role); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "update_team_member_role", "team", map[string]interface{}{
+		"team_id":        teamID,
+		"target_user_id": userID,
+		"old_role":       membership.Role,
+		"new_role":       req.Role,
+	})
+
+	return nil
+}
+
+// RemoveMember removes a member from a team. Any member may leave on their own;
+// removing someone else requires outranking them or being an owner.
+func (s *TeamService) RemoveMember(ctx context.Context, teamID, userID, currentUserID, currentTeamRole string) error {
+	membership, err := s.teamRepo.GetMembership(ctx, teamID, userID)
+	if err != nil {
+		return err
+	}
+
+	if userID != currentUserID {
+		if err := checkTeamRoleChange(currentTeamRole, membership.Role, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := s.teamRepo.RemoveMember(ctx, teamID, userID); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "remove_team_member", "team", map[string]interface{}{
+		"team_id":        teamID,
+		"target_user_id": userID,
+	})
+
+	return nil
+}
+
+// TransferOwnership makes another member the owner and demotes the caller to admin (owner only)
+func (s *TeamService) TransferOwnership(ctx context.Context, teamID string, req *model.TransferTeamOwnershipRequest, currentUserID string) error {
+	if req.UserID == currentUserID {
+		return fmt.Errorf("cannot transfer ownership to yourself")
+	}
+
+	if err := s.teamRepo.TransferOwnership(ctx, teamID, currentUserID, req.UserID); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "transfer_team_ownership", "team", map[string]interface{}{
+		"team_id":        teamID,
+		"target_user_id": req.UserID,
+	})
+
+	return nil
+}
+
+// checkTeamRoleChange verifies that a member with actorRole may move a member from
+// currentRole to newRole. An empty newRole means removal.
+func checkTeamRoleChange(actorRole, currentRole, newRole string) error {
+	if actorRole == model.TeamRoleOwner {
+		return nil
+	}
+
+	// Non-owners may only manage members below them and grant roles below their own
+	if model.TeamRoleAtLeast(currentRole, actorRole) || (newRole != "" && model.TeamRoleAtLeast(newRole, actorRole)) {
+		return fmt.Errorf("insufficient team role")
+	}
+
+	return nil
+}
+
+// InviteMember invites an email address to join a team, emailing the invitee
+func (s *TeamService) InviteMember(ctx context.Context, teamID string, req *model.InviteTeamMemberRequest, currentUserID, currentTeamRole string) (*model.TeamInvitation, error) {
+	role := req.Role
+	if role == "" {
+		role = model.TeamRoleMember
+	}
+
+	if !model.IsValidTeamRole(role) {
+		return nil, fmt.Errorf("invalid team role")
+	}
+
+	if err := checkTeamRoleChange(currentTeamRole, model.TeamRoleMember, role); err != nil {
+		return nil, err
+	}
+
+	team, err := s.teamRepo.GetByID(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	if user, err := s.userRepo.GetByEmail(ctx, email); err == nil {
+		if _, err := s.teamRepo.GetMembership(ctx, teamID, user.ID); err == nil {
+			return nil, fmt.Errorf("user is already a team member")
+		}
+	}
+
+	pending, err := s.teamRepo.HasPendingInvitation(ctx, teamID, email)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, fmt.Errorf("invitation already pending")
+	}
+
+	invitation := &model.TeamInvitation{
+		TeamID:    teamID,
+		TenantID:  team.TenantID,
+		Email:     email,
+		Role:      role,
+		Status:    model.InvitationPending,
+		InvitedBy: currentUserID,
+		ExpiresAt: time.Now().UTC().Add(teamInvitationTTL),
+	}
+
+	if err := s.teamRepo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	s.sendInvitation(ctx, team, invitation)
+
+	s.logger.LogUserAction(currentUserID, "invite_team_member", "team", map[string]interface{}{
+		"team_id":       teamID,
+		"invitation_id": invitation.ID,
+		"role":          role,
+	})
+
+	return invitation, nil
+}
+
+// sendInvitation emails an invitation. Failures are only logged since the invitee can
+// still find the invitation through the API.
+func (s *TeamService) sendInvitation(ctx context.Context, team *model.Team, invitation *model.TeamInvitation) {
+	err := s.mailer.Send(ctx, mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You've been invited to join %s", team.Name),
+		Body: fmt.Sprintf(
+			"Hi,\n\nYou've been invited to join the team %q as %s.\n\nSign in with this email address to accept or decline. The invitation expires on %s.\n",
+			team.Name, invitation.Role, invitation.ExpiresAt.Format(time.RFC1123),
+		),
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("invitation_id", invitation.ID).Warn("Failed to send team invitation")
+	}
+}
+
+// ListTeamInvitations lists a team's open invitations
+func (s *TeamService) ListTeamInvitations(ctx context.Context, teamID string) ([]model.TeamInvitation, error) {
+	return s.teamRepo.ListPendingInvitationsByTeam(ctx, teamID)
+}
+
+// RevokeInvitation revokes one of a team's open invitations
+func (s *TeamService) RevokeInvitation(ctx context.Context, teamID, invitationID, currentUserID string) error {
+	invitation, err := s.teamRepo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+
+	if invitation.TeamID != teamID {
+		return fmt.Errorf("invitation not found")
+	}
+
+	if err := s.teamRepo.RespondToInvitation(ctx, invitation, model.InvitationRevoked, ""); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "revoke_team_invitation", "team", map[string]interface{}{
+		"team_id":       teamID,
+		"invitation_id": invitationID,
+	})
+
+	return nil
+}
+
+// ListMyInvitations lists the open invitations sent to the current user's email
+func (s *TeamService) ListMyInvitations(ctx context.Context, currentUserID string) ([]model.TeamInvitation, error) {
+	user, err := s.userRepo.GetByID(ctx, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.teamRepo.ListInvitationsByEmail(ctx, strings.ToLower(user.Email), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return invitationsForTenant(invitations, user.TenantID), nil
+}
+
+// invitationsForTenant drops invitations sent from other tenants. Email addresses are
+// only unique within a tenant, so an invitation is only meant for users of its team's tenant.
+func invitationsForTenant(invitations []model.TeamInvitation, tenantID string) []model.TeamInvitation {
+	visible := make([]model.TeamInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		if invitation.TenantID == tenantID {
+			visible = append(visible, invitation)
+		}
+	}
+
+	return visible
+}
+
+// RespondToInvitation accepts or declines an invitation sent to the current user's email
+func (s *TeamService) RespondToInvitation(ctx context.Context, invitationID string, accept bool, currentUserID string) error {
+	user, err := s.userRepo.GetByID(ctx, currentUserID)
+	if err != nil {
+		return err
+	}
+
+	invitation, err := s.teamRepo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+
+	// Invitations addressed to someone else, including the same email in another
+	// tenant, are reported as missing
+	if !strings.EqualFold(invitation.Email, user.Email) || invitation.TenantID != user.TenantID {
+		return fmt.Errorf("invitation not found")
+	}
+
+	if !invitation.IsOpen() {
+		return fmt.Errorf("invitation is no longer pending")
+	}
+
+	status := model.InvitationDeclined
+	if accept {
+		status = model.InvitationAccepted
+	}
+
+	if err := s.teamRepo.RespondToInvitation(ctx, invitation, status, currentUserID); err != nil {
+		return err
+	}
+
+	s.logger.LogUserAction(currentUserID, "respond_team_invitation", "team", map[string]interface{}{
+		"team_id":       invitation.TeamID,
+		"invitation_id": invitationID,
+		"status":        status,
+	})
+
+	return nil
+}
+
+// DataHook returns the hook that exports and erases team data for privacy requests
+func (s *TeamService) DataHook() UserDataHook {
+	return &teamDataHook{teamRepo: s.teamRepo, userRepo: s.userRepo}
+}
+
+// teamDataHook exports a user's memberships and invitations, and on erasure removes
+// them from every team and deletes the invitations their tenant sent to their email
+type teamDataHook struct {
+	teamRepo *repository.TeamRepository
+	userRepo *repository.UserRepository
+}
+
+// Name implements UserDataHook
+func (h *teamDataHook) Name() string {
+	return "teams"
+}
+
+// Export implements UserDataHook
+func (h *teamDataHook) Export(ctx context.Context, userID string) (interface{}, error) {
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := h.teamRepo.ListMembershipsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := h.teamRepo.ListInvitationsByEmail(ctx, strings.ToLower(user.Email), false)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"memberships": memberships,
+		"invitations": invitationsForTenant(invitations, user.TenantID),
+	}, nil
+}
+
+// Erase implements UserDataHook. It runs before the profile hook anonymizes the email.
+func (h *teamDataHook) Erase(ctx context.Context, userID string) error {
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.teamRepo.RemoveUserFromAllTeams(ctx, userID); err != nil {
+		return err
+	}
+
+	return h.teamRepo.DeleteInvitationsByEmail(ctx, user.TenantID, strings.ToLower(user.Email))
+}
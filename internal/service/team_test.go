@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+)
+
+func TestCheckTeamRoleChange(t *testing.T) {
+	tests := []struct {
+		name        string
+		actorRole   string
+		currentRole string
+		newRole     string
+		wantErr     bool
+	}{
+		{"owner promotes to owner", model.TeamRoleOwner, model.TeamRoleAdmin, model.TeamRoleOwner, false},
+		{"owner demotes owner", model.TeamRoleOwner, model.TeamRoleOwner, model.TeamRoleMember, false},
+		{"admin promotes member to admin", model.TeamRoleAdmin, model.TeamRoleMember, model.TeamRoleAdmin, true},
+		{"admin invites member", model.TeamRoleAdmin, model.TeamRoleMember, model.TeamRoleMember, false},
+		{"admin demotes admin", model.TeamRoleAdmin, model.TeamRoleAdmin, model.TeamRoleMember, true},
+		{"admin removes member", model.TeamRoleAdmin, model.TeamRoleMember, "", false},
+		{"admin removes owner", model.TeamRoleAdmin, model.TeamRoleOwner, "", true},
+		{"member removes member", model.TeamRoleMember, model.TeamRoleMember, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTeamRoleChange(tt.actorRole, tt.currentRole, tt.newRole)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInvitationsForTenant(t *testing.T) {
+	invitations := []model.TeamInvitation{
+		{ID: "a1", TenantID: "tenant-a"},
+		{ID: "b1", TenantID: "tenant-b"},
+		{ID: "a2", TenantID: "tenant-a"},
+	}
+
+	visible := invitationsForTenant(invitations, "tenant-a")
+	if len(visible) != 2 || visible[0].ID != "a1" || visible[1].ID != "a2" {
+		t.Errorf("Expected only tenant-a invitations, got %+v", visible)
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	"github.com/dev-mayanktiwari/api-server/internal/repository"
+	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+)
+
+// QuotaPlan holds the limits of a rate limit plan
+type QuotaPlan struct {
+	Name         string
+	Requests     int
+	Period       time.Duration
+	Burst        int
+	DailyQuota   int64 // 0 for unlimited
+	MonthlyQuota int64 // 0 for unlimited
+}
+
+// PlanResolver returns the plan that applies to a user with the given role and plan,
+// or false if none does
+type PlanResolver func(role, plan string) (QuotaPlan, bool)
+
+// UsageService counts the requests of authenticated users against the daily and
+// monthly quotas of their plan
+type UsageService struct {
+	usageRepo *repository.UsageRepository
+	plans     PlanResolver
+	logger    *logger.Logger
+}
+
+// NewUsageService creates a new usage service
+func NewUsageService(usageRepo *repository.UsageRepository, plans PlanResolver, logger *logger.Logger) *UsageService {
+	return &UsageService{
+		usageRepo: usageRepo,
+		plans:     plans,
+		logger:    logger,
+	}
+}
+
+// ConsumeQuota counts a request of a user against their plan's quotas. If a quota is
+// used up the request isn't counted, and the period of that quota and when it resets
+// are returned. It is used by the quota middleware.
+func (s *UsageService) ConsumeQuota(ctx context.Context, userID, role, plan string) (string, time.Time, error) {
+	quotaPlan, _ := s.plans(role, plan)
+	now := time.Now().UTC()
+	windows := quotaWindows(quotaPlan, now)
+
+	_, exhausted, err := s.usageRepo.Consume(ctx, userID, windows, now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if exhausted < 0 {
+		return "", time.Time{}, nil
+	}
+
+	window := windows[exhausted]
+	s.logger.WithFields(map[string]interface{}{
+		"user_id": userID,
+		"plan":    quotaPlan.Name,
+		"period":  window.Period,
+		"limit":   window.Limit,
+	}).Warn("Request quota exceeded")
+
+	return window.Period, periodEnd(window.Period, window.Start), nil
+}
+
+// GetUsage reports the plan of a user and how much of its quotas they have used
+func (s *UsageService) GetUsage(ctx context.Context, userID, role, plan string) (*model.UsageReport, error) {
+	quotaPlan, _ := s.plans(role, plan)
+	windows := quotaWindows(quotaPlan, time.Now())
+
+	usage := make([]model.QuotaUsage, len(windows))
+	for i, window := range windows {
+		used, err := s.usageRepo.GetCount(ctx, userID, window.Period, window.Start)
+		if err != nil {
+			return nil, err
+		}
+		usage[i] = quotaUsage(used, window.Limit, periodEnd(window.Period, window.Start))
+	}
+
+	burst := quotaPlan.Burst
+	if burst <= 0 {
+		burst = quotaPlan.Requests
+	}
+
+	return &model.UsageReport{
+		Plan: quotaPlan.Name,
+		RateLimit: model.PlanRateLimit{
+			Requests:      quotaPlan.Requests,
+			PeriodSeconds: int(math.Ceil(quotaPlan.Period.Seconds())),
+			Burst:         burst,
+		},
+		Daily:   usage[0],
+		Monthly: usage[1],
+	}, nil
+}
+
+// quotaWindows returns the daily and monthly windows, in that order, that a request
+// at the given time is counted in
+func quotaWindows(plan QuotaPlan, now time.Time) []repository.UsageWindow {
+	now = now.UTC()
+	return []repository.UsageWindow{
+		{
+			Period: model.UsagePeriodDay,
+			Start:  time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+			Limit:  plan.DailyQuota,
+		},
+		{
+			Period: model.UsagePeriodMonth,
+			Start:  time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+			Limit:  plan.MonthlyQuota,
+		},
+	}
+}
+
+// periodEnd returns when a quota window that started at start resets
+func periodEnd(period string, start time.Time) time.Time {
+	if period == model.UsagePeriodMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// quotaUsage reports the use of a quota window; limit is 0 for unlimited quotas
+func quotaUsage(used, limit int64, resetsAt time.Time) model.QuotaUsage {
+	usage := model.QuotaUsage{Used: used, ResetsAt: resetsAt}
+	if limit > 0 {
+		remaining := limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.Limit = limit
+		usage.Remaining = &remaining
+	}
+	return usage
+}
+
+// DataHook returns the hook that exports and erases usage counters for privacy requests
+func (s *UsageService) DataHook() UserDataHook {
+	return &usageDataHook{usageRepo: s.usageRepo}
+}
+
+// usageDataHook exports a user's request counts and deletes them on erasure
+type usageDataHook struct {
+	usageRepo *repository.UsageRepository
+}
+
+// Name implements UserDataHook
+func (h *usageDataHook) Name() string {
+	return "usage"
+}
+
+// Export implements UserDataHook
+func (h *usageDataHook) Export(ctx context.Context, userID string) (interface{}, error) {
+	return h.usageRepo.ListByUser(ctx, userID)
+}
+
+// Erase implements UserDataHook
+func (h *usageDataHook) Erase(ctx context.Context, userID string) error {
+	return h.usageRepo.DeleteByUser(ctx, userID)
+}
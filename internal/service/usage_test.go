@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+)
+
+func TestQuotaWindows(t *testing.T) {
+	plan := QuotaPlan{Name: "free", DailyQuota: 100, MonthlyQuota: 1000}
+
+	// Late on the last day of January in UTC-5 is already February in UTC
+	now := time.Date(2024, time.January, 31, 22, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	windows := quotaWindows(plan, now)
+
+	if len(windows) != 2 {
+		t.Fatalf("Expected daily and monthly windows, got %d", len(windows))
+	}
+
+	day, month := windows[0], windows[1]
+	if day.Period != model.UsagePeriodDay || !day.Start.Equal(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)) || day.Limit != 100 {
+		t.Errorf("Unexpected daily window: %+v", day)
+	}
+	if month.Period != model.UsagePeriodMonth || !month.Start.Equal(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)) || month.Limit != 1000 {
+		t.Errorf("Unexpected monthly window: %+v", month)
+	}
+
+	if end := periodEnd(day.Period, day.Start); !end.Equal(time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the day to reset on February 2, got %s", end)
+	}
+	if end := periodEnd(month.Period, month.Start); !end.Equal(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the month to reset on March 1, got %s", end)
+	}
+}
+
+func TestQuotaUsage(t *testing.T) {
+	resetsAt := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	usage := quotaUsage(40, 100, resetsAt)
+	if usage.Limit != 100 || usage.Remaining == nil || *usage.Remaining != 60 {
+		t.Errorf("Expected 60 of 100 remaining, got %+v", usage)
+	}
+
+	if usage := quotaUsage(120, 100, resetsAt); usage.Remaining == nil || *usage.Remaining != 0 {
+		t.Errorf("Expected remaining to stop at 0, got %+v", usage)
+	}
+
+	// Unlimited quotas only report what was used
+	if usage := quotaUsage(40, 0, resetsAt); usage.Limit != 0 || usage.Remaining != nil || usage.Used != 40 {
+		t.Errorf("Expected an unlimited quota without limit or remaining, got %+v", usage)
+	}
+}
@@ -1,35 +1,49 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dev-mayanktiwari/api-server/internal/model"
 	"github.com/dev-mayanktiwari/api-server/internal/repository"
 	"github.com/dev-mayanktiwari/api-server/pkg/auth"
 	"github.com/dev-mayanktiwari/api-server/pkg/logger"
+	"github.com/dev-mayanktiwari/api-server/pkg/tenant"
 )
 
 // UserService handles user business logic
 type UserService struct {
-	userRepo   *repository.UserRepository
-	jwtManager *auth.JWTManager
-	logger     *logger.Logger
+	userRepo       *repository.UserRepository
+	preferenceRepo *repository.PreferenceRepository
+	activity       *ActivityService
+	jwtManager     *auth.JWTManager
+	plans          map[string]bool // rate limit plans admins may assign
+	logger         *logger.Logger
 }
 
-// NewUserService creates a new user service
-func NewUserService(userRepo *repository.UserRepository, jwtManager *auth.JWTManager, logger *logger.Logger) *UserService {
+// NewUserService creates a new user service. plans lists the rate limit plans users can be assigned.
+func NewUserService(userRepo *repository.UserRepository, preferenceRepo *repository.PreferenceRepository, activity *ActivityService, jwtManager *auth.JWTManager, plans []string, logger *logger.Logger) *UserService {
+	validPlans := make(map[string]bool, len(plans))
+	for _, plan := range plans {
+		validPlans[plan] = true
+	}
+
 	return &UserService{
-		userRepo:   userRepo,
-		jwtManager: jwtManager,
-		logger:     logger,
+		userRepo:       userRepo,
+		preferenceRepo: preferenceRepo,
+		activity:       activity,
+		jwtManager:     jwtManager,
+		plans:          validPlans,
+		logger:         logger,
 	}
 }
 
 // CreateUser creates a new user
-func (s *UserService) CreateUser(req *model.CreateUserRequest) (*model.SafeUser, error) {
+func (s *UserService) CreateUser(ctx context.Context, req *model.CreateUserRequest) (*model.SafeUser, error) {
 	// Check if user already exists
-	exists, err := s.userRepo.ExistsByEmail(req.Email)
+	exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check user existence: %w", err)
 	}
@@ -41,7 +55,7 @@ func (s *UserService) CreateUser(req *model.CreateUserRequest) (*model.SafeUser,
 	// Set default role if not provided
 	role := req.Role
 	if role == "" {
-		role = "user"
+		role = model.RoleUser
 	}
 
 	// Validate role
@@ -60,7 +74,7 @@ func (s *UserService) CreateUser(req *model.CreateUserRequest) (*model.SafeUser,
 	}
 
 	// Create user in database
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -74,10 +88,11 @@ func (s *UserService) CreateUser(req *model.CreateUserRequest) (*model.SafeUser,
 	return &safeUser, nil
 }
 
-// Login authenticates a user and returns a JWT token
-func (s *UserService) Login(req *model.LoginRequest) (*model.LoginResponse, error) {
+// Login authenticates a user and returns a JWT token. clientIP is recorded as the
+// user's last login IP.
+func (s *UserService) Login(ctx context.Context, req *model.LoginRequest, clientIP string) (*model.LoginResponse, error) {
 	// Get user by email
-	user, err := s.userRepo.GetByEmail(strings.ToLower(req.Email))
+	user, err := s.userRepo.GetByEmail(ctx, strings.ToLower(req.Email))
 	if err != nil {
 		s.logger.WithFields(map[string]interface{}{
 			"email": req.Email,
@@ -104,25 +119,38 @@ func (s *UserService) Login(req *model.LoginRequest) (*model.LoginResponse, erro
 	}
 
 	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Role)
+	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Role, user.TenantID, user.Plan)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
+	now := time.Now().UTC()
+	if err := s.userRepo.RecordLogin(ctx, user.ID, clientIP, now); err != nil {
+		// Don't fail the login over bookkeeping
+		s.logger.WithError(err).WithField("user_id", user.ID).Error("Failed to record last login")
+	} else {
+		user.LastLoginAt = &now
+		user.LastLoginIP = clientIP
+	}
+	s.activity.Record(ctx, user.ID, user.ID, model.ActivityLogin, clientIP, nil)
+
 	s.logger.LogUserAction(user.ID, "login", "user", map[string]interface{}{
 		"email": user.Email,
+		"ip":    clientIP,
 	})
 
+	// A pending deletion doesn't block login; the response offers to cancel it
 	safeUser := user.ToSafeUser()
 	return &model.LoginResponse{
-		User:  safeUser,
-		Token: token,
+		User:            safeUser,
+		Token:           token,
+		PendingDeletion: pendingDeletionFor(user),
 	}, nil
 }
 
 // GetUserByID retrieves a user by ID
-func (s *UserService) GetUserByID(userID string) (*model.SafeUser, error) {
-	user, err := s.userRepo.GetByID(userID)
+func (s *UserService) GetUserByID(ctx context.Context, userID string) (*model.SafeUser, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -131,24 +159,47 @@ func (s *UserService) GetUserByID(userID string) (*model.SafeUser, error) {
 	return &safeUser, nil
 }
 
-// UpdateUser updates a user's information
-func (s *UserService) UpdateUser(userID string, req *model.UpdateUserRequest, currentUserID string, currentUserRole string) (*model.SafeUser, error) {
+// UpdateUser updates a user's information. If expectedVersion is non-zero the update
+// is rejected unless it matches the user's current version.
+func (s *UserService) UpdateUser(ctx context.Context, userID string, req *model.UpdateUserRequest, expectedVersion int, currentUserID string, currentUserRole string) (*model.SafeUser, error) {
+	user, err := s.getUserForUpdate(ctx, userID, expectedVersion, currentUserID, currentUserRole)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.applyUserUpdate(ctx, user, req, currentUserID, currentUserRole)
+}
+
+// getUserForUpdate loads a user and checks the caller may update the version they hold
+func (s *UserService) getUserForUpdate(ctx context.Context, userID string, expectedVersion int, currentUserID string, currentUserRole string) (*model.User, error) {
 	// Get existing user
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	// Check permissions
-	if !s.canUpdateUser(userID, currentUserID, currentUserRole) {
+	if !s.canUpdateUser(userID, currentUserID, currentUserRole) || !canManageRole(user.Role, userID, currentUserID, currentUserRole) {
 		return nil, fmt.Errorf("insufficient permissions to update user")
 	}
 
+	// Check the client is editing the version it last read
+	if expectedVersion != 0 && user.Version != expectedVersion {
+		return nil, fmt.Errorf("version mismatch")
+	}
+
+	return user, nil
+}
+
+// applyUserUpdate applies the provided fields to a user and saves it
+func (s *UserService) applyUserUpdate(ctx context.Context, user *model.User, req *model.UpdateUserRequest, currentUserID string, currentUserRole string) (*model.SafeUser, error) {
+	before := *user
+
 	// Update fields if provided
 	if req.Email != "" {
 		// Check if email is already taken by another user
-		existingUser, err := s.userRepo.GetByEmail(strings.ToLower(req.Email))
-		if err == nil && existingUser.ID != userID {
+		existingUser, err := s.userRepo.GetByEmail(ctx, strings.ToLower(req.Email))
+		if err == nil && existingUser.ID != user.ID {
 			return nil, fmt.Errorf("email is already taken")
 		}
 		user.Email = strings.ToLower(req.Email)
@@ -162,27 +213,47 @@ func (s *UserService) UpdateUser(userID string, req *model.UpdateUserRequest, cu
 		user.LastName = req.LastName
 	}
 
-	// Only admins can update role and status
-	if currentUserRole == "admin" {
+	// Only admins can update role, plan and status
+	if model.IsAdminRole(currentUserRole) {
 		if req.Role != "" {
-			if !isValidRole(req.Role) {
+			if !isValidRole(req.Role) && !(req.Role == model.RoleSuperAdmin && currentUserRole == model.RoleSuperAdmin) {
 				return nil, fmt.Errorf("invalid role: %s", req.Role)
 			}
 			user.Role = req.Role
 		}
 
+		if req.Plan != "" {
+			if !s.plans[req.Plan] {
+				return nil, fmt.Errorf("invalid plan: %s", req.Plan)
+			}
+			user.Plan = req.Plan
+		}
+
 		if req.IsActive != nil {
 			user.IsActive = *req.IsActive
 		}
 	}
 
 	// Update user in database
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if err.Error() == "version mismatch" {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	if fields := changedUserFields(&before, user); len(fields) > 0 {
+		action := model.ActivityProfileUpdated
+		if currentUserID != user.ID {
+			action = model.ActivityUserUpdated
+		}
+		s.activity.Record(ctx, user.ID, currentUserID, action, "", map[string]interface{}{
+			"fields": fields,
+		})
+	}
+
 	s.logger.LogUserAction(currentUserID, "update_user", "user", map[string]interface{}{
-		"target_user_id": userID,
+		"target_user_id": user.ID,
 		"email":          user.Email,
 	})
 
@@ -191,9 +262,9 @@ func (s *UserService) UpdateUser(userID string, req *model.UpdateUserRequest, cu
 }
 
 // ChangePassword changes a user's password
-func (s *UserService) ChangePassword(userID string, req *model.ChangePasswordRequest) error {
+func (s *UserService) ChangePassword(ctx context.Context, userID string, req *model.ChangePasswordRequest) error {
 	// Get user
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
@@ -204,10 +275,12 @@ func (s *UserService) ChangePassword(userID string, req *model.ChangePasswordReq
 	}
 
 	// Update password
-	if err := s.userRepo.UpdatePassword(userID, req.NewPassword); err != nil {
+	if err := s.userRepo.UpdatePassword(ctx, userID, req.NewPassword); err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
+	s.activity.Record(ctx, userID, userID, model.ActivityPasswordChanged, "", nil)
+
 	s.logger.LogUserAction(userID, "change_password", "user", map[string]interface{}{
 		"user_id": userID,
 	})
@@ -216,7 +289,7 @@ func (s *UserService) ChangePassword(userID string, req *model.ChangePasswordReq
 }
 
 // DeleteUser soft deletes a user
-func (s *UserService) DeleteUser(userID string, currentUserID string, currentUserRole string) error {
+func (s *UserService) DeleteUser(ctx context.Context, userID string, currentUserID string, currentUserRole string) error {
 	// Check permissions
 	if !s.canDeleteUser(userID, currentUserID, currentUserRole) {
 		return fmt.Errorf("insufficient permissions to delete user")
@@ -227,10 +300,20 @@ func (s *UserService) DeleteUser(userID string, currentUserID string, currentUse
 		return fmt.Errorf("cannot delete your own account")
 	}
 
-	if err := s.userRepo.Delete(userID); err != nil {
+	target, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if !canManageRole(target.Role, userID, currentUserID, currentUserRole) {
+		return fmt.Errorf("insufficient permissions to delete user")
+	}
+
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
+	s.activity.Record(tenant.WithTenant(ctx, target.TenantID), userID, currentUserID, model.ActivityUserDeleted, "", nil)
+
 	s.logger.LogUserAction(currentUserID, "delete_user", "user", map[string]interface{}{
 		"target_user_id": userID,
 	})
@@ -238,15 +321,19 @@ func (s *UserService) DeleteUser(userID string, currentUserID string, currentUse
 	return nil
 }
 
-// ListUsers retrieves users with pagination
-func (s *UserService) ListUsers(page, limit int, currentUserRole string) ([]model.SafeUser, int64, error) {
+// ListUsers retrieves users matching the filter with pagination
+func (s *UserService) ListUsers(ctx context.Context, page, limit int, filter repository.UserFilter, currentUserRole string) ([]model.SafeUser, int64, error) {
 	// Only admins can list all users
-	if currentUserRole != "admin" {
+	if !model.IsAdminRole(currentUserRole) {
 		return nil, 0, fmt.Errorf("insufficient permissions to list users")
 	}
 
+	if err := s.validatePreferenceFilter(filter.Preferences); err != nil {
+		return nil, 0, err
+	}
+
 	offset := (page - 1) * limit
-	users, total, err := s.userRepo.List(offset, limit)
+	users, total, err := s.userRepo.List(ctx, offset, limit, filter)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -260,10 +347,60 @@ func (s *UserService) ListUsers(page, limit int, currentUserRole string) ([]mode
 	return safeUsers, total, nil
 }
 
+// validatePreferenceFilter checks that every filtered preference key is defined and indexed,
+// so list queries can't scan the whole table on arbitrary JSON keys
+func (s *UserService) validatePreferenceFilter(preferences map[string]string) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+
+	schemas, err := s.preferenceRepo.List()
+	if err != nil {
+		return err
+	}
+
+	indexed := make(map[string]bool, len(schemas))
+	for _, schema := range schemas {
+		indexed[schema.Key] = schema.Indexed
+	}
+
+	for _, key := range sortedKeys(preferences) {
+		if !indexed[key] {
+			return fmt.Errorf("preference is not filterable: %s", key)
+		}
+	}
+
+	return nil
+}
+
+// changedUserFields returns the names of the editable fields that differ between two versions of a user
+func changedUserFields(before, after *model.User) []string {
+	var fields []string
+	if before.Email != after.Email {
+		fields = append(fields, "email")
+	}
+	if before.FirstName != after.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if before.LastName != after.LastName {
+		fields = append(fields, "last_name")
+	}
+	if before.Role != after.Role {
+		fields = append(fields, "role")
+	}
+	if before.Plan != after.Plan {
+		fields = append(fields, "plan")
+	}
+	if before.IsActive != after.IsActive {
+		fields = append(fields, "is_active")
+	}
+	return fields
+}
+
 // canUpdateUser checks if the current user can update the target user
 func (s *UserService) canUpdateUser(targetUserID, currentUserID, currentUserRole string) bool {
 	// Admins can update anyone
-	if currentUserRole == "admin" {
+	if model.IsAdminRole(currentUserRole) {
 		return true
 	}
 
@@ -274,14 +411,24 @@ func (s *UserService) canUpdateUser(targetUserID, currentUserID, currentUserRole
 // canDeleteUser checks if the current user can delete the target user
 func (s *UserService) canDeleteUser(targetUserID, currentUserID, currentUserRole string) bool {
 	// Only admins can delete users
-	return currentUserRole == "admin"
+	return model.IsAdminRole(currentUserRole)
+}
+
+// canManageRole checks that the current user may manage an account holding targetRole.
+// Only super admins manage other super admins.
+func canManageRole(targetRole, targetUserID, currentUserID, currentUserRole string) bool {
+	if targetRole != model.RoleSuperAdmin || targetUserID == currentUserID {
+		return true
+	}
+	return currentUserRole == model.RoleSuperAdmin
 }
 
-// isValidRole checks if the role is valid
+// isValidRole checks if the role is valid for assignment. The super admin role can
+// only be granted by another super admin.
 func isValidRole(role string) bool {
 	validRoles := map[string]bool{
-		"user":  true,
-		"admin": true,
+		model.RoleUser:  true,
+		model.RoleAdmin: true,
 	}
 	return validRoles[role]
 }
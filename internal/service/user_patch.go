@@ -0,0 +1,160 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// Patch media types accepted by PatchUser
+const (
+	MergePatchMediaType = "application/merge-patch+json" // RFC 7386
+	JSONPatchMediaType  = "application/json-patch+json"  // RFC 6902
+)
+
+// profilePatchFields are the fields any user may patch on their own account
+var profilePatchFields = []string{"email", "first_name", "last_name"}
+
+// adminPatchFields are the fields admins may patch on any account
+var adminPatchFields = []string{"email", "first_name", "last_name", "role", "plan", "is_active"}
+
+// patchValidator validates patched documents using the same `binding` tags as request binding
+var patchValidator = newPatchValidator()
+
+func newPatchValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	return v
+}
+
+// PatchUser applies a JSON Merge Patch or JSON Patch document to the whitelisted
+// fields of a user. If expectedVersion is non-zero the patch is rejected unless it
+// matches the user's current version.
+func (s *UserService) PatchUser(ctx context.Context, userID string, mediaType string, patch []byte, expectedVersion int, currentUserID string, currentUserRole string) (*model.SafeUser, error) {
+	if mediaType != MergePatchMediaType && mediaType != JSONPatchMediaType {
+		return nil, fmt.Errorf("unsupported patch media type")
+	}
+
+	user, err := s.getUserForUpdate(ctx, userID, expectedVersion, currentUserID, currentUserRole)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := profilePatchFields
+	if model.IsAdminRole(currentUserRole) {
+		allowed = adminPatchFields
+	}
+
+	original, err := json.Marshal(patchDocumentFor(user, allowed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user document: %w", err)
+	}
+
+	patched, err := applyPatch(mediaType, original, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decodePatchedDocument(patched, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &model.UpdateUserRequest{
+		Email:     doc.Email,
+		FirstName: doc.FirstName,
+		LastName:  doc.LastName,
+		Role:      doc.Role,
+		Plan:      doc.Plan,
+		IsActive:  doc.IsActive,
+	}
+
+	return s.applyUserUpdate(ctx, user, req, currentUserID, currentUserRole)
+}
+
+// patchDocumentFor builds the JSON document a patch is applied to, limited to the allowed fields
+func patchDocumentFor(user *model.User, allowed []string) map[string]interface{} {
+	all := map[string]interface{}{
+		"email":      user.Email,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"role":       user.Role,
+		"plan":       user.Plan,
+		"is_active":  user.IsActive,
+	}
+
+	doc := make(map[string]interface{}, len(allowed))
+	for _, field := range allowed {
+		doc[field] = all[field]
+	}
+
+	return doc
+}
+
+// applyPatch applies a patch document of the given media type to the original document
+func applyPatch(mediaType string, original, patch []byte) ([]byte, error) {
+	if mediaType == MergePatchMediaType {
+		patched, err := jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch document: %w", err)
+		}
+		return patched, nil
+	}
+
+	operations, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch document: %w", err)
+	}
+
+	patched, err := operations.Apply(original)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, fmt.Errorf("patch test failed")
+		}
+		return nil, fmt.Errorf("invalid patch document: %w", err)
+	}
+
+	return patched, nil
+}
+
+// decodePatchedDocument checks the patched document only touches allowed fields and validates it
+func decodePatchedDocument(patched []byte, allowed []string) (*model.PatchUserDocument, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &fields); err != nil {
+		return nil, fmt.Errorf("invalid patch document: result is not a JSON object")
+	}
+
+	for field := range fields {
+		if !containsString(allowed, field) {
+			return nil, fmt.Errorf("field is not patchable: %s", field)
+		}
+	}
+
+	var doc model.PatchUserDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid patch document: %w", err)
+	}
+
+	if err := patchValidator.Struct(&doc); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+)
+
+func TestApplyUserPatch(t *testing.T) {
+	user := &model.User{
+		Email:     "jane@example.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Role:      "user",
+		IsActive:  true,
+	}
+
+	tests := []struct {
+		name        string
+		mediaType   string
+		patch       string
+		allowed     []string
+		expectError string
+		expectFirst string
+	}{
+		{
+			name:        "merge patch updates field",
+			mediaType:   MergePatchMediaType,
+			patch:       `{"first_name": "Janet"}`,
+			allowed:     profilePatchFields,
+			expectFirst: "Janet",
+		},
+		{
+			name:        "merge patch clearing required field fails validation",
+			mediaType:   MergePatchMediaType,
+			patch:       `{"last_name": null}`,
+			allowed:     profilePatchFields,
+			expectError: "validation",
+		},
+		{
+			name:        "merge patch adding unknown field",
+			mediaType:   MergePatchMediaType,
+			patch:       `{"password": "secret"}`,
+			allowed:     profilePatchFields,
+			expectError: "field is not patchable: password",
+		},
+		{
+			name:        "merge patch on role is rejected for profile fields",
+			mediaType:   MergePatchMediaType,
+			patch:       `{"role": "admin"}`,
+			allowed:     profilePatchFields,
+			expectError: "field is not patchable: role",
+		},
+		{
+			name:        "json patch replace",
+			mediaType:   JSONPatchMediaType,
+			patch:       `[{"op": "test", "path": "/first_name", "value": "Jane"}, {"op": "replace", "path": "/first_name", "value": "Janet"}]`,
+			allowed:     adminPatchFields,
+			expectFirst: "Janet",
+		},
+		{
+			name:        "json patch failed test",
+			mediaType:   JSONPatchMediaType,
+			patch:       `[{"op": "test", "path": "/first_name", "value": "John"}]`,
+			allowed:     profilePatchFields,
+			expectError: "patch test failed",
+		},
+		{
+			name:        "json patch malformed",
+			mediaType:   JSONPatchMediaType,
+			patch:       `{"op": "replace"}`,
+			allowed:     profilePatchFields,
+			expectError: "invalid patch document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, err := json.Marshal(patchDocumentFor(user, tt.allowed))
+			if err != nil {
+				t.Fatalf("Failed to encode document: %v", err)
+			}
+
+			patched, err := applyPatch(tt.mediaType, original, []byte(tt.patch))
+			var doc *model.PatchUserDocument
+			if err == nil {
+				doc, err = decodePatchedDocument(patched, tt.allowed)
+			}
+
+			if tt.expectError != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing %q but got none", tt.expectError)
+				}
+				if tt.expectError != "validation" && !strings.HasPrefix(err.Error(), tt.expectError) {
+					t.Errorf("Expected error %q, got %q", tt.expectError, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if doc.FirstName != tt.expectFirst {
+				t.Errorf("Expected first name %s, got %s", tt.expectFirst, doc.FirstName)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/dev-mayanktiwari/api-server/internal/model"
+)
+
+const (
+	// minSearchQueryLength is the shortest search query accepted; trigram similarity is
+	// meaningless for shorter strings
+	minSearchQueryLength = 2
+
+	// Highlight markers passed to ts_headline. They are swapped for <mark> tags after
+	// the user's own text has been HTML-escaped.
+	highlightStart = "\u0002"
+	highlightStop  = "\u0003"
+)
+
+// SearchUsers ranks users by how well their name or email matches the query, using
+// prefix full-text matching for partial names and trigram similarity for misspellings
+// (admin only). Matched fragments are wrapped in <mark> tags in the highlights.
+func (s *UserService) SearchUsers(ctx context.Context, query string, limit int, currentUserRole string) ([]model.UserSearchResult, error) {
+	if !model.IsAdminRole(currentUserRole) {
+		return nil, fmt.Errorf("insufficient permissions to search users")
+	}
+
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < minSearchQueryLength {
+		return nil, fmt.Errorf("search query is too short")
+	}
+
+	hits, err := s.userRepo.Search(ctx, query, buildSearchTSQuery(query), limit, highlightStart, highlightStop)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.UserSearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = model.UserSearchResult{
+			User:  hit.ToSafeUser(),
+			Score: hit.Score,
+			Highlights: map[string]string{
+				"name":  renderHighlight(hit.NameHighlight),
+				"email": renderHighlight(hit.EmailHighlight),
+			},
+		}
+	}
+
+	return results, nil
+}
+
+// buildSearchTSQuery turns free text into a to_tsquery expression requiring every word
+// as a prefix, e.g. "jo smi" becomes "jo:* & smi:*". Only letters, digits and the
+// characters of an email address are kept, so user input can't inject tsquery operators.
+func buildSearchTSQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("@.-_+", r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.Trim(word, "@.-_+")
+		if word == "" {
+			continue
+		}
+		// Quote the lexeme so characters like '-' aren't read as operators
+		terms = append(terms, "'"+word+"':*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// renderHighlight HTML-escapes a ts_headline result and turns its markers into <mark> tags
+func renderHighlight(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
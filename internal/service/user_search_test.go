@@ -0,0 +1,35 @@
+package service
+
+import "testing"
+
+func TestBuildSearchTSQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"single word", "John", "'john':*"},
+		{"partial name", "jo  smi", "'jo':* & 'smi':*"},
+		{"email", "john.doe@example.com", "'john.doe@example.com':*"},
+		{"tsquery operators", "jo & !smith | (x)", "'jo':* & 'smith':* & 'x':*"},
+		{"quotes", "o'brien", "'o':* & 'brien':*"},
+		{"only punctuation", "..", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildSearchTSQuery(tt.query); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderHighlight(t *testing.T) {
+	headline := highlightStart + "John" + highlightStop + " <script>"
+	want := "<mark>John</mark> &lt;script&gt;"
+
+	if got := renderHighlight(headline); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
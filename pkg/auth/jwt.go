@@ -16,9 +16,11 @@ type JWTManager struct {
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Plan     string `json:"plan,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -30,17 +32,19 @@ func NewJWTManager(secretKey string, tokenTTL time.Duration) *JWTManager {
 	}
 }
 
-// GenerateToken generates a new JWT token
-func (j *JWTManager) GenerateToken(userID, email, role string) (string, error) {
+// GenerateToken generates a new JWT token for a user of the given tenant and plan
+func (j *JWTManager) GenerateToken(userID, email, role, tenantID, plan string) (string, error) {
 	if j.secretKey == "" {
 		return "", errors.New("JWT secret key is not set")
 	}
 
 	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:   userID,
+		Email:    email,
+		Role:     role,
+		TenantID: tenantID,
+		Plan:     plan,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.tokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -98,7 +102,7 @@ func (j *JWTManager) RefreshToken(tokenString string) (string, error) {
 	}
 
 	// Generate new token with same claims
-	return j.GenerateToken(claims.UserID, claims.Email, claims.Role)
+	return j.GenerateToken(claims.UserID, claims.Email, claims.Role, claims.TenantID, claims.Plan)
 }
 
 // ExtractTokenFromHeader extracts JWT token from Authorization header
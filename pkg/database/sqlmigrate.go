@@ -0,0 +1,76 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// sqlMigrationLockID is the advisory lock key that serializes SQL migrations across
+// instances starting at the same time
+const sqlMigrationLockID = 0x6d696772 // "migr"
+
+// MigrateSQL applies the versioned SQL migrations in fsys that haven't run yet. Files are
+// named <version>_<description>.sql and applied in lexical order, each in its own
+// transaction, with applied versions recorded in the schema_migrations table. Use it for
+// schema changes AutoMigrate can't express, such as extensions, generated columns and
+// expression indexes; it runs after AutoMigrate so it can build on the model tables.
+func (d *Database) MigrateSQL(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return fmt.Errorf("failed to list SQL migrations: %w", err)
+	}
+	sort.Strings(files)
+
+	if err := d.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`).Error; err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	for _, file := range files {
+		version, _, _ := strings.Cut(path.Base(file), "_")
+		version = strings.TrimSuffix(version, ".sql")
+
+		script, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read SQL migration %s: %w", file, err)
+		}
+
+		applied := false
+		err = d.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", sqlMigrationLockID).Error; err != nil {
+				return err
+			}
+
+			var count int64
+			if err := tx.Raw("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			if err := tx.Exec(string(script)).Error; err != nil {
+				return err
+			}
+			applied = true
+
+			return tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply SQL migration %s: %w", file, err)
+		}
+
+		if applied {
+			d.logger.WithField("migration", file).Info("Applied SQL migration")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Flag is a feature flag. A disabled flag is off for everyone. An enabled flag is on
+// for the users, roles and email domains it targets, and for Rollout percent of the
+// other users, picked by a hash of the flag key and user ID so each user keeps their
+// answer as the rollout grows. A rollout of 100 turns the flag on for everyone,
+// anonymous callers included.
+type Flag struct {
+	Key          string   `json:"key" yaml:"-"`
+	Description  string   `json:"description,omitempty" yaml:"description"`
+	Enabled      bool     `json:"enabled" yaml:"enabled"`
+	Rollout      int      `json:"rollout" yaml:"rollout"` // percent of users, 0-100
+	Users        []string `json:"users,omitempty" yaml:"users"`
+	Roles        []string `json:"roles,omitempty" yaml:"roles"`
+	EmailDomains []string `json:"email_domains,omitempty" yaml:"email_domains"`
+}
+
+// keyPattern restricts flag keys to identifiers that read well in URLs and code
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+
+// IsValidKey returns true if the key can be used as a flag key
+func IsValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Validate checks the flag's key and rollout
+func (f Flag) Validate() error {
+	if !IsValidKey(f.Key) {
+		return fmt.Errorf("invalid flag key %q: must start with a lowercase letter and contain only lowercase letters, digits, '_', '.' and '-' (max 64)", f.Key)
+	}
+	if f.Rollout < 0 || f.Rollout > 100 {
+		return fmt.Errorf("invalid rollout of flag %q: must be between 0 and 100", f.Key)
+	}
+	return nil
+}
+
+// Subject is who a flag is evaluated for; the zero Subject is an anonymous caller
+type Subject struct {
+	UserID string
+	Role   string
+	Email  string
+}
+
+// Evaluate returns whether the flag is on for the subject
+func (f Flag) Evaluate(s Subject) bool {
+	if !f.Enabled {
+		return false
+	}
+	if f.Rollout >= 100 {
+		return true
+	}
+
+	if s.UserID != "" && contains(f.Users, s.UserID) {
+		return true
+	}
+	if s.Role != "" && contains(f.Roles, s.Role) {
+		return true
+	}
+	if at := strings.LastIndexByte(s.Email, '@'); at >= 0 && containsFold(f.EmailDomains, s.Email[at+1:]) {
+		return true
+	}
+
+	if s.UserID == "" || f.Rollout <= 0 {
+		return false
+	}
+	return bucket(f.Key, s.UserID) < f.Rollout
+}
+
+// bucket places a user in one of 100 buckets for a flag
+func bucket(key, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{':'})
+	h.Write([]byte(userID))
+	return int(h.Sum32() % 100)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Source provides feature flags
+type Source interface {
+	Flags(ctx context.Context) ([]Flag, error)
+}
+
+// SourceFunc adapts a function to a Source
+type SourceFunc func(ctx context.Context) ([]Flag, error)
+
+// Flags calls f
+func (f SourceFunc) Flags(ctx context.Context) ([]Flag, error) {
+	return f(ctx)
+}
+
+// Set evaluates flags from its sources. It keeps the flags in memory, so evaluating
+// them costs no I/O; Refresh loads them again. A flag in a later source replaces the
+// flag with the same key in an earlier one.
+type Set struct {
+	sources []Source
+	flags   atomic.Pointer[map[string]Flag]
+}
+
+// NewSet creates a set over the sources, in increasing order of precedence. It holds
+// no flags until the first Refresh.
+func NewSet(sources ...Source) *Set {
+	s := &Set{sources: sources}
+	s.flags.Store(&map[string]Flag{})
+	return s
+}
+
+// Refresh loads the flags of every source. If a source fails, the flags loaded last
+// stay in effect.
+func (s *Set) Refresh(ctx context.Context) error {
+	flags := make(map[string]Flag)
+	for _, source := range s.sources {
+		loaded, err := source.Flags(ctx)
+		if err != nil {
+			return err
+		}
+		for _, flag := range loaded {
+			flags[flag.Key] = flag
+		}
+	}
+
+	s.flags.Store(&flags)
+	return nil
+}
+
+// Enabled returns whether a flag is on for the subject; unknown flags are off
+func (s *Set) Enabled(key string, subject Subject) bool {
+	flag, ok := (*s.flags.Load())[key]
+	return ok && flag.Evaluate(subject)
+}
+
+// Evaluate returns every flag evaluated for the subject
+func (s *Set) Evaluate(subject Subject) map[string]bool {
+	flags := *s.flags.Load()
+	result := make(map[string]bool, len(flags))
+	for key, flag := range flags {
+		result[key] = flag.Evaluate(subject)
+	}
+	return result
+}
+
+// Flags returns the flags in effect, ordered by key
+func (s *Set) Flags() []Flag {
+	flags := *s.flags.Load()
+	result := make([]Flag, 0, len(flags))
+	for _, flag := range flags {
+		result = append(result, flag)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
@@ -0,0 +1,117 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlagEvaluate(t *testing.T) {
+	targeted := Flag{
+		Key:          "new_dashboard",
+		Enabled:      true,
+		Users:        []string{"user-1"},
+		Roles:        []string{"admin"},
+		EmailDomains: []string{"example.com"},
+	}
+
+	tests := []struct {
+		name    string
+		flag    Flag
+		subject Subject
+		want    bool
+	}{
+		{"disabled flags are off", Flag{Key: "f", Rollout: 100}, Subject{UserID: "user-1"}, false},
+		{"full rollout is on for everyone", Flag{Key: "f", Enabled: true, Rollout: 100}, Subject{}, true},
+		{"no rollout is off", Flag{Key: "f", Enabled: true}, Subject{UserID: "user-1"}, false},
+		{"targeted user", targeted, Subject{UserID: "user-1"}, true},
+		{"targeted role", targeted, Subject{UserID: "user-2", Role: "admin"}, true},
+		{"targeted email domain", targeted, Subject{UserID: "user-2", Email: "jane@Example.com"}, true},
+		{"subdomains are other domains", targeted, Subject{UserID: "user-2", Email: "jane@mail.example.com"}, false},
+		{"untargeted user", targeted, Subject{UserID: "user-2", Role: "user", Email: "jane@example.org"}, false},
+		{"partial rollouts leave out anonymous callers", Flag{Key: "f", Enabled: true, Rollout: 99}, Subject{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.Evaluate(tt.subject); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFlagRollout(t *testing.T) {
+	flag := Flag{Key: "new_dashboard", Enabled: true, Rollout: 25}
+
+	on := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		if flag.Evaluate(Subject{UserID: userID}) {
+			on[userID] = true
+		}
+	}
+	if len(on) < 2300 || len(on) > 2700 {
+		t.Errorf("Expected about 25%% of users, got %d of 10000", len(on))
+	}
+
+	// Growing the rollout keeps everyone who had the flag
+	flag.Rollout = 50
+	for userID := range on {
+		if !flag.Evaluate(Subject{UserID: userID}) {
+			t.Fatalf("Expected %s to keep the flag as the rollout grows", userID)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	content := "new_dashboard:\n  enabled: true\n  rollout: 100\nbeta_search:\n  enabled: true\n  roles: [admin]\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write flags file: %v", err)
+	}
+
+	var dbErr error
+	database := SourceFunc(func(ctx context.Context) ([]Flag, error) {
+		return []Flag{{Key: "new_dashboard", Enabled: false}}, dbErr
+	})
+
+	set := NewSet(NewFileSource(path), database)
+	if set.Enabled("new_dashboard", Subject{}) {
+		t.Error("Expected no flags before the first refresh")
+	}
+	if err := set.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+
+	flags := set.Evaluate(Subject{UserID: "user-1", Role: "admin"})
+	if len(flags) != 2 || flags["new_dashboard"] || !flags["beta_search"] {
+		t.Errorf("Expected the later source to win, got %v", flags)
+	}
+	if set.Enabled("unknown", Subject{}) {
+		t.Error("Expected unknown flags to be off")
+	}
+
+	// A failing source keeps the flags in effect
+	dbErr = errors.New("connection refused")
+	if err := set.Refresh(context.Background()); err == nil {
+		t.Error("Expected the refresh to fail")
+	}
+	if len(set.Flags()) != 2 {
+		t.Errorf("Expected the previous flags to stay in effect, got %v", set.Flags())
+	}
+
+	// Invalid files and missing files
+	if err := os.WriteFile(path, []byte("Bad Key:\n  enabled: true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write flags file: %v", err)
+	}
+	if _, err := NewFileSource(path).Flags(context.Background()); err == nil {
+		t.Error("Expected an invalid key to be rejected")
+	}
+	if flags, err := NewFileSource(filepath.Join(t.TempDir(), "missing.yaml")).Flags(context.Background()); err != nil || len(flags) != 0 {
+		t.Errorf("Expected a missing file to have no flags, got %v, %v", flags, err)
+	}
+}
@@ -0,0 +1,54 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileSource reads flags from a YAML file mapping flag keys to flags:
+//
+//	new_dashboard:
+//	  enabled: true
+//	  rollout: 25
+//	  roles: [admin]
+//	  email_domains: [example.com]
+//
+// The file is read on every call, so edits take effect on the next Refresh. A
+// missing file has no flags.
+type FileSource struct {
+	path string
+}
+
+// NewFileSource creates a source reading the YAML file at path
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// Flags reads the flags of the file
+func (f *FileSource) Flags(ctx context.Context) ([]Flag, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feature flags file: %w", err)
+	}
+
+	var byKey map[string]Flag
+	if err := yaml.Unmarshal(data, &byKey); err != nil {
+		return nil, fmt.Errorf("invalid feature flags file: %w", err)
+	}
+
+	flags := make([]Flag, 0, len(byKey))
+	for key, flag := range byKey {
+		flag.Key = key
+		if err := flag.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid feature flags file: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
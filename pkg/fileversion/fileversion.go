@@ -0,0 +1,43 @@
+// Package fileversion tells whether a file has changed since it was loaded, for files
+// that are reloaded without a restart
+package fileversion
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Version identifies a file's content by its modification time and size. The zero
+// Version is a missing file.
+type Version struct {
+	modTime time.Time
+	size    int64
+}
+
+// Missing returns true if the version is that of a missing file
+func (v Version) Missing() bool {
+	return v == Version{}
+}
+
+// Stat returns the current version of a file
+func Stat(path string) (Version, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return Version{}, nil
+	}
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	return Version{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Changed returns the current version of a file and whether it differs from the
+// version loaded; with force every version counts as changed
+func Changed(path string, loaded Version, force bool) (Version, bool, error) {
+	version, err := Stat(path)
+	if err != nil {
+		return Version{}, false, err
+	}
+	return version, force || version != loaded, nil
+}
@@ -0,0 +1,49 @@
+package fileversion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+
+	missing, changed, err := Changed(path, Version{}, false)
+	if err != nil || changed || !missing.Missing() {
+		t.Fatalf("Expected a missing file to be unchanged, got %v, %v", changed, err)
+	}
+	if _, changed, _ := Changed(path, Version{}, true); !changed {
+		t.Error("Expected force to report a change")
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	os.Chtimes(path, modTime, modTime)
+	loaded, changed, err := Changed(path, missing, false)
+	if err != nil || !changed || loaded.Missing() {
+		t.Fatalf("Expected a created file to be changed, got %v, %v", changed, err)
+	}
+	if _, changed, _ := Changed(path, loaded, false); changed {
+		t.Error("Expected an unchanged file not to be reported")
+	}
+
+	// A rewrite within the same modification time is caught by the size
+	if err := os.WriteFile(path, []byte("ab"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	os.Chtimes(path, modTime, modTime)
+	if _, changed, _ := Changed(path, loaded, false); !changed {
+		t.Error("Expected a resized file to be changed")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if version, changed, _ := Changed(path, loaded, false); !changed || !version.Missing() {
+		t.Error("Expected a removed file to be changed and missing")
+	}
+}
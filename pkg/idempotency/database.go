@@ -0,0 +1,116 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DatabaseRecord is the row of an idempotency key. Reserved keys expire after the lock
+// timeout and completed keys after their TTL; expired rows can be taken over by a new
+// request and are removed by DeleteExpired.
+type DatabaseRecord struct {
+	Key         string `gorm:"primaryKey"`
+	Fingerprint string `gorm:"not null"`
+	Status      int    `gorm:"not null;default:0"`
+	Header      []byte // JSON encoded
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+}
+
+// TableName returns the table name for DatabaseRecord model
+func (DatabaseRecord) TableName() string {
+	return "idempotency_keys"
+}
+
+// DatabaseStore keeps idempotency records in the database. Migrate DatabaseRecord
+// before using it.
+type DatabaseStore struct {
+	db *gorm.DB
+}
+
+// NewDatabaseStore creates a store keeping records in the idempotency_keys table
+func NewDatabaseStore(db *gorm.DB) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+// Begin implements Store
+func (s *DatabaseStore) Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*Record, bool, error) {
+	now := time.Now().UTC()
+
+	// Insert the reservation, or take over an expired row; an existing live row
+	// makes the statement affect nothing
+	result := s.db.WithContext(ctx).Exec(`INSERT INTO idempotency_keys (key, fingerprint, status, header, body, expires_at, created_at)
+		VALUES (?, ?, 0, NULL, NULL, ?, ?)
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status = 0, header = NULL, body = NULL,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+		WHERE idempotency_keys.expires_at <= ?`, key, fingerprint, now.Add(lockTimeout), now, now)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return nil, true, nil
+	}
+
+	var row DatabaseRecord
+	if err := s.db.WithContext(ctx).Where("key = ?", key).First(&row).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	record := &Record{
+		Fingerprint: row.Fingerprint,
+		Status:      row.Status,
+		Body:        row.Body,
+	}
+	if len(row.Header) > 0 {
+		if err := json.Unmarshal(row.Header, &record.Header); err != nil {
+			return nil, false, fmt.Errorf("failed to decode idempotency record: %w", err)
+		}
+	}
+
+	return record, false, nil
+}
+
+// Complete implements Store
+func (s *DatabaseStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Model(&DatabaseRecord{}).
+		Where("key = ?", key).
+		Updates(map[string]interface{}{
+			"status":     record.Status,
+			"header":     header,
+			"body":       record.Body,
+			"expires_at": time.Now().UTC().Add(ttl),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// Release implements Store
+func (s *DatabaseStore) Release(ctx context.Context, key string) error {
+	if err := s.db.WithContext(ctx).Where("key = ? AND status = 0", key).Delete(&DatabaseRecord{}).Error; err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes expired records and returns how many were removed
+func (s *DatabaseStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at <= ?", time.Now().UTC()).Delete(&DatabaseRecord{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency records: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,50 @@
+// Package idempotency stores the responses of requests sent with an Idempotency-Key,
+// so retried requests are answered with the original response instead of repeating
+// their side effects. Keys are reserved while their first request is processed.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Record is what is stored for a key: the fingerprint of the request that reserved it
+// and, once that request has completed, its response
+type Record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"` // 0 while the request is in flight
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// InFlight returns true if the request that reserved the key hasn't completed yet
+func (r *Record) InFlight() bool {
+	return r.Status == 0
+}
+
+// Store keeps idempotency records
+type Store interface {
+	// Begin reserves a key for a request with the given fingerprint for up to
+	// lockTimeout. If the key is already reserved or completed, the existing record
+	// is returned instead and the reservation fails.
+	Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*Record, bool, error)
+	// Complete stores the response of a reserved key, replayed for ttl
+	Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	// Release drops the reservation of a key, so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Fingerprint identifies a request by its method, URL and body, so a key reused for
+// a different request can be told apart from a retry
+func Fingerprint(method, url string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(url))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
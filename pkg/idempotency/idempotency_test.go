@@ -0,0 +1,78 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	store := NewRedisStore(client, "idempotency:")
+	fingerprint := Fingerprint(http.MethodPost, "/api/v1/items", []byte(`{"name":"a"}`))
+
+	if _, reserved, err := store.Begin(ctx, "k1", fingerprint, time.Minute); err != nil || !reserved {
+		t.Fatalf("Expected the first request to reserve the key, got %v, %v", reserved, err)
+	}
+
+	// A duplicate sees the reservation of the request in flight
+	record, reserved, err := store.Begin(ctx, "k1", fingerprint, time.Minute)
+	if err != nil || reserved {
+		t.Fatalf("Expected the duplicate not to reserve the key, got %v, %v", reserved, err)
+	}
+	if !record.InFlight() || record.Fingerprint != fingerprint {
+		t.Errorf("Expected an in-flight record with the fingerprint, got %+v", record)
+	}
+
+	// Once completed, the response is returned until the TTL passes
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	if err := store.Complete(ctx, "k1", &Record{Fingerprint: fingerprint, Status: http.StatusCreated, Header: header, Body: []byte(`{"id":1}`)}, time.Hour); err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+	record, _, err = store.Begin(ctx, "k1", fingerprint, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	if record.Status != http.StatusCreated || string(record.Body) != `{"id":1}` || record.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected the stored response, got %+v", record)
+	}
+
+	server.FastForward(time.Hour)
+	if _, reserved, _ := store.Begin(ctx, "k1", fingerprint, time.Minute); !reserved {
+		t.Error("Expected the key to be reusable after the TTL")
+	}
+
+	// A released key can be reserved again, and reservations time out by themselves
+	if err := store.Release(ctx, "k1"); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if _, reserved, _ := store.Begin(ctx, "k1", fingerprint, time.Minute); !reserved {
+		t.Error("Expected a released key to be reservable")
+	}
+	server.FastForward(time.Minute)
+	if _, reserved, _ := store.Begin(ctx, "k1", fingerprint, time.Minute); !reserved {
+		t.Error("Expected the reservation to time out")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint(http.MethodPost, "/api/v1/items", []byte("a"))
+
+	if Fingerprint(http.MethodPost, "/api/v1/items", []byte("a")) != base {
+		t.Error("Expected equal requests to have equal fingerprints")
+	}
+	if Fingerprint(http.MethodPatch, "/api/v1/items", []byte("a")) == base {
+		t.Error("Expected the method to change the fingerprint")
+	}
+	if Fingerprint(http.MethodPost, "/api/v1/items/a", nil) == Fingerprint(http.MethodPost, "/api/v1/items", []byte("/a")) {
+		t.Error("Expected the URL and body not to run together")
+	}
+}
@@ -0,0 +1,81 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps idempotency records in Redis, expiring them with the key's TTL
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore creates a store keeping records in Redis under keys with the given prefix
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Begin implements Store
+func (s *RedisStore) Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*Record, bool, error) {
+	reservation, err := json.Marshal(&Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A record that disappears between SET NX and GET is retried once
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.client.SetNX(ctx, s.prefix+key, reservation, lockTimeout).Result()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		if reserved {
+			return nil, true, nil
+		}
+
+		value, err := s.client.Get(ctx, s.prefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get idempotency record: %w", err)
+		}
+
+		var record Record
+		if err := json.Unmarshal(value, &record); err != nil {
+			return nil, false, fmt.Errorf("failed to decode idempotency record: %w", err)
+		}
+		return &record, false, nil
+	}
+
+	return nil, false, fmt.Errorf("failed to reserve idempotency key: record keeps expiring")
+}
+
+// Complete implements Store
+func (s *RedisStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.Set(ctx, s.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+	return nil
+}
+
+// Release implements Store
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
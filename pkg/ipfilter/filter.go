@@ -0,0 +1,146 @@
+package ipfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/fileversion"
+	"gopkg.in/yaml.v3"
+)
+
+// Filter checks clients against the rules of a YAML rules file and, optionally, a GeoIP
+// database. Reload picks up changes to either file; until then, and when a changed file
+// is invalid, the rules loaded last stay in effect.
+type Filter struct {
+	rulesFile string
+	geoIPFile string
+	openGeoIP func(path string) (CountryLookup, error)
+
+	mu      sync.Mutex // serializes reloads
+	state   atomic.Pointer[filterState]
+	rulesAt fileversion.Version
+	geoIPAt fileversion.Version
+}
+
+// filterState is what a check reads, swapped as a whole on reload
+type filterState struct {
+	rules map[string]*compiledRule
+	geo   CountryLookup
+}
+
+// NewFilter loads the rules file and, if geoIPFile is set, the GeoIP database. A
+// missing rules file means no rules.
+func NewFilter(rulesFile, geoIPFile string) (*Filter, error) {
+	f := &Filter{
+		rulesFile: rulesFile,
+		geoIPFile: geoIPFile,
+		openGeoIP: func(path string) (CountryLookup, error) {
+			return OpenGeoIPDatabase(path)
+		},
+	}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Check decides whether a client may reach a route group. Groups without rules allow
+// every client.
+func (f *Filter) Check(group string, ip netip.Addr) Decision {
+	state := f.state.Load()
+	rule, ok := state.rules[group]
+	if !ok {
+		return Decision{Allowed: true}
+	}
+	return rule.check(ip.Unmap(), state.geo)
+}
+
+// Unknown lists the groups with rules that are none of the known groups, which usually
+// means a typo in the rules file: their rules never apply
+func (f *Filter) Unknown(known []string) []string {
+	names := make(map[string]bool, len(known))
+	for _, name := range known {
+		names[name] = true
+	}
+
+	var unknown []string
+	for group := range f.state.Load().rules {
+		if !names[group] {
+			unknown = append(unknown, group)
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+// Reload reloads the files that changed since they were loaded and returns whether
+// anything was reloaded
+func (f *Filter) Reload() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current := f.state.Load()
+	if current == nil {
+		current = &filterState{}
+	}
+	next := *current
+
+	rulesAt, rulesChanged, err := fileversion.Changed(f.rulesFile, f.rulesAt, current.rules == nil)
+	if err != nil {
+		return false, err
+	}
+	geoIPAt, geoIPChanged := f.geoIPAt, false
+	if f.geoIPFile != "" {
+		if geoIPAt, geoIPChanged, err = fileversion.Changed(f.geoIPFile, f.geoIPAt, current.geo == nil); err != nil {
+			return false, err
+		}
+	}
+	if !rulesChanged && !geoIPChanged {
+		return false, nil
+	}
+
+	if geoIPChanged {
+		if next.geo, err = f.openGeoIP(f.geoIPFile); err != nil {
+			return false, err
+		}
+	}
+
+	// Rules are compiled again when the database changes too, in case it appeared
+	rules, err := readRules(f.rulesFile, rulesAt)
+	if err != nil {
+		return false, err
+	}
+	if next.rules, err = rules.compile(next.geo != nil); err != nil {
+		return false, fmt.Errorf("invalid IP filter rules: %w", err)
+	}
+
+	f.state.Store(&next)
+	f.rulesAt, f.geoIPAt = rulesAt, geoIPAt
+	return true, nil
+}
+
+// readRules reads the rules file; a missing file has no rules
+func readRules(path string, version fileversion.Version) (Rules, error) {
+	if version.Missing() {
+		return Rules{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IP filter rules: %w", err)
+	}
+
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid IP filter rules: %w", err)
+	}
+	if rules == nil {
+		rules = Rules{}
+	}
+	return rules, nil
+}
@@ -0,0 +1,46 @@
+package ipfilter
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoIPDatabase looks up countries in a MaxMind-format database, such as GeoLite2
+// Country or City
+type GeoIPDatabase struct {
+	reader *maxminddb.Reader
+}
+
+// OpenGeoIPDatabase reads a MaxMind-format database file. The file is read into memory
+// rather than mapped, so it can be replaced while the database is in use.
+func OpenGeoIPDatabase(path string) (*GeoIPDatabase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+
+	return &GeoIPDatabase{reader: reader}, nil
+}
+
+// Country implements CountryLookup
+func (d *GeoIPDatabase) Country(ip netip.Addr) (string, error) {
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+
+	if err := d.reader.Lookup(net.IP(ip.AsSlice()), &record); err != nil {
+		return "", fmt.Errorf("failed to look up country: %w", err)
+	}
+	return record.Country.ISOCode, nil
+}
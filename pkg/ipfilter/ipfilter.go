@@ -0,0 +1,134 @@
+// Package ipfilter decides which client IPs may reach a route group, by CIDR allow and
+// deny lists and by country through a MaxMind-format GeoIP database. Rules and the
+// database are files that are reloaded when they change.
+package ipfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Rule restricts the clients of a route group. Deny lists win over allow lists. When
+// an allow list is set, clients must match an allowed network or an allowed country.
+type Rule struct {
+	Allow          []string `yaml:"allow"` // CIDRs or IPs
+	Deny           []string `yaml:"deny"`  // CIDRs or IPs
+	AllowCountries []string `yaml:"allow_countries"`
+	DenyCountries  []string `yaml:"deny_countries"` // ISO 3166-1 alpha-2 codes
+}
+
+// Rules are the rules of each route group, by group name
+type Rules map[string]Rule
+
+// Decision is the outcome of a check. Rule describes the rule that denied the client,
+// or allowed it when an allow list applied.
+type Decision struct {
+	Allowed bool
+	Rule    string
+	Country string // empty when unknown or not looked up
+}
+
+// CountryLookup returns the ISO country code of an IP, or an empty code if unknown
+type CountryLookup interface {
+	Country(ip netip.Addr) (string, error)
+}
+
+// compiledRule is a Rule with parsed networks and normalized countries
+type compiledRule struct {
+	allow          []netip.Prefix
+	deny           []netip.Prefix
+	allowCountries map[string]bool
+	denyCountries  map[string]bool
+}
+
+// compile parses the rules; country rules need a GeoIP database
+func (r Rules) compile(haveGeoIP bool) (map[string]*compiledRule, error) {
+	compiled := make(map[string]*compiledRule, len(r))
+	for group, rule := range r {
+		allow, err := parsePrefixes(rule.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", group, err)
+		}
+		deny, err := parsePrefixes(rule.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", group, err)
+		}
+		if !haveGeoIP && (len(rule.AllowCountries) > 0 || len(rule.DenyCountries) > 0) {
+			return nil, fmt.Errorf("group %s: country rules need a GeoIP database", group)
+		}
+
+		compiled[group] = &compiledRule{
+			allow:          allow,
+			deny:           deny,
+			allowCountries: countrySet(rule.AllowCountries),
+			denyCountries:  countrySet(rule.DenyCountries),
+		}
+	}
+	return compiled, nil
+}
+
+// parsePrefixes parses CIDRs and IPs, an IP being a network of its own
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %s (expected a CIDR or an IP)", value)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// countrySet returns the upper-cased country codes as a set
+func countrySet(countries []string) map[string]bool {
+	set := make(map[string]bool, len(countries))
+	for _, country := range countries {
+		set[strings.ToUpper(strings.TrimSpace(country))] = true
+	}
+	return set
+}
+
+// matchPrefix returns the first network containing ip
+func matchPrefix(prefixes []netip.Prefix, ip netip.Addr) (netip.Prefix, bool) {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return prefix, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// check applies the rule to a client. Countries are only looked up when the rule has
+// country lists; a failed lookup counts as an unknown country.
+func (r *compiledRule) check(ip netip.Addr, geo CountryLookup) Decision {
+	if prefix, ok := matchPrefix(r.deny, ip); ok {
+		return Decision{Rule: "deny " + prefix.String()}
+	}
+
+	country := ""
+	if geo != nil && (len(r.allowCountries) > 0 || len(r.denyCountries) > 0) {
+		country, _ = geo.Country(ip)
+	}
+
+	if country != "" && r.denyCountries[country] {
+		return Decision{Rule: "deny_countries " + country, Country: country}
+	}
+
+	if len(r.allow) == 0 && len(r.allowCountries) == 0 {
+		return Decision{Allowed: true, Country: country}
+	}
+	if prefix, ok := matchPrefix(r.allow, ip); ok {
+		return Decision{Allowed: true, Rule: "allow " + prefix.String(), Country: country}
+	}
+	if country != "" && r.allowCountries[country] {
+		return Decision{Allowed: true, Rule: "allow_countries " + country, Country: country}
+	}
+
+	return Decision{Rule: "not in allow list", Country: country}
+}
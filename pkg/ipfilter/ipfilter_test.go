@@ -0,0 +1,127 @@
+package ipfilter
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countries is a CountryLookup for tests
+type countries map[string]string
+
+func (c countries) Country(ip netip.Addr) (string, error) {
+	return c[ip.String()], nil
+}
+
+func TestRuleCheck(t *testing.T) {
+	rules, err := Rules{
+		"admin": {
+			Allow:          []string{"10.0.0.0/8", "203.0.113.7"},
+			Deny:           []string{"10.0.5.0/24"},
+			AllowCountries: []string{"de"},
+		},
+		"api": {DenyCountries: []string{"KP"}},
+	}.compile(true)
+	if err != nil {
+		t.Fatalf("Failed to compile rules: %v", err)
+	}
+
+	geo := countries{"198.51.100.1": "DE", "198.51.100.2": "US", "198.51.100.3": "KP"}
+
+	tests := []struct {
+		group   string
+		ip      string
+		allowed bool
+		rule    string
+	}{
+		{"admin", "10.1.2.3", true, "allow 10.0.0.0/8"},
+		{"admin", "203.0.113.7", true, "allow 203.0.113.7/32"},
+		{"admin", "10.0.5.9", false, "deny 10.0.5.0/24"},
+		{"admin", "198.51.100.1", true, "allow_countries DE"},
+		{"admin", "198.51.100.2", false, "not in allow list"},
+		{"admin", "192.0.2.1", false, "not in allow list"},
+		{"api", "198.51.100.3", false, "deny_countries KP"},
+		{"api", "198.51.100.2", true, ""},
+	}
+
+	for _, tt := range tests {
+		decision := rules[tt.group].check(netip.MustParseAddr(tt.ip), geo)
+		if decision.Allowed != tt.allowed || decision.Rule != tt.rule {
+			t.Errorf("%s %s: expected %v by %q, got %v by %q", tt.group, tt.ip, tt.allowed, tt.rule, decision.Allowed, decision.Rule)
+		}
+	}
+}
+
+func TestRulesCompileErrors(t *testing.T) {
+	if _, err := (Rules{"admin": {Allow: []string{"10.0.0.0/33"}}}).compile(false); err == nil {
+		t.Error("Expected an invalid network to be rejected")
+	}
+	if _, err := (Rules{"admin": {DenyCountries: []string{"KP"}}}).compile(false); err == nil {
+		t.Error("Expected country rules without a GeoIP database to be rejected")
+	}
+}
+
+func TestFilterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipfilter.yaml")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write rules: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	// A missing rules file allows everyone
+	filter, err := NewFilter(path, "")
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+	client := netip.MustParseAddr("192.0.2.1")
+	if !filter.Check("admin", client).Allowed {
+		t.Error("Expected clients to be allowed without rules")
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("admin:\n  allow: [10.0.0.0/8]\n", start)
+	if reloaded, err := filter.Reload(); err != nil || !reloaded {
+		t.Fatalf("Expected the new rules file to be loaded, got %v, %v", reloaded, err)
+	}
+	if filter.Check("admin", client).Allowed {
+		t.Error("Expected the client to be denied by the new rules")
+	}
+	if !filter.Check("api", client).Allowed {
+		t.Error("Expected groups without rules to allow every client")
+	}
+
+	if reloaded, _ := filter.Reload(); reloaded {
+		t.Error("Expected an unchanged file not to be reloaded")
+	}
+
+	write("admin:\n  allow: [10.0.0.0/8]\nadmn:\n  allow: [10.0.0.0/8]\n", start.Add(30*time.Second))
+	if _, err := filter.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if unknown := filter.Unknown([]string{"api", "admin"}); len(unknown) != 1 || unknown[0] != "admn" {
+		t.Errorf("Expected the misspelled group to be reported, got %v", unknown)
+	}
+
+	// Invalid rules keep the rules in effect
+	write("admin:\n  allow: [nginx]\n", start.Add(time.Minute))
+	if _, err := filter.Reload(); err == nil {
+		t.Error("Expected invalid rules to fail to load")
+	}
+	if filter.Check("admin", client).Allowed {
+		t.Error("Expected the previous rules to stay in effect")
+	}
+
+	write("admin:\n  allow: [192.0.2.0/24]\n", start.Add(2*time.Minute))
+	if _, err := filter.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if decision := filter.Check("admin", netip.MustParseAddr("::ffff:192.0.2.1")); !decision.Allowed {
+		t.Errorf("Expected the fixed rules to allow the client, got %q", decision.Rule)
+	}
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSMTPMailerBuild(t *testing.T) {
+	m := NewSMTPMailer("smtp.example.com", "587", "", "", "no-reply@example.com")
+
+	raw := string(m.build(Message{
+		To:      "jane@example.com",
+		Subject: "Your account has been deleted",
+		Body:    "Hi Jane,\n\nBye.\n",
+	}))
+
+	for _, want := range []string{
+		"From: no-reply@example.com\r\n",
+		"To: jane@example.com\r\n",
+		"Subject: Your account has been deleted\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nHi Jane,\r\n\r\nBye.\r\n",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, raw)
+		}
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := NewSMTPMailer("smtp.example.com", "587", "", "", "no-reply@example.com")
+
+	err := m.Send(context.Background(), Message{To: "jane@example.com\r\nBcc: victim@example.com"})
+	if err == nil {
+		t.Error("Expected recipient with a line break to be rejected")
+	}
+}
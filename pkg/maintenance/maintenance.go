@@ -0,0 +1,161 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dev-mayanktiwari/api-server/pkg/fileversion"
+)
+
+// Mode is how much of the API is available
+type Mode string
+
+const (
+	// ModeOff serves every request
+	ModeOff Mode = "off"
+	// ModeReadOnly rejects requests that could change data
+	ModeReadOnly Mode = "read_only"
+	// ModeFull rejects every request but health checks
+	ModeFull Mode = "full"
+)
+
+// Valid returns true if m is a known mode
+func (m Mode) Valid() bool {
+	return m == ModeOff || m == ModeReadOnly || m == ModeFull
+}
+
+// State is the maintenance mode in effect and why
+type State struct {
+	Mode      Mode       `json:"mode"`
+	Message   string     `json:"message,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy string     `json:"updated_by,omitempty"`
+}
+
+// Switch holds the maintenance state. The state file, when set, wins over the mode
+// the switch was created with, so operators can switch modes by writing the file and
+// Set survives restarts; Reload picks up changes to it, e.g. from other instances
+// sharing the file. Without a file the state lives in memory only.
+type Switch struct {
+	file    string
+	initial State
+
+	mu       sync.Mutex // serializes reloads and writes
+	state    atomic.Pointer[State]
+	loadedAt fileversion.Version
+}
+
+// NewSwitch creates a switch in the given mode, or in the mode of the state file if
+// file is set and exists
+func NewSwitch(mode Mode, file string) (*Switch, error) {
+	if !mode.Valid() {
+		return nil, fmt.Errorf("invalid maintenance mode: %s", mode)
+	}
+
+	s := &Switch{file: file, initial: State{Mode: mode}}
+	s.state.Store(&s.initial)
+	if _, err := s.reload(true); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// State returns the current state
+func (s *Switch) State() State {
+	return *s.state.Load()
+}
+
+// Set switches to a new state, writing it to the state file if there is one
+func (s *Switch) Set(state State) error {
+	if !state.Mode.Valid() {
+		return fmt.Errorf("invalid maintenance mode: %s", state.Mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != "" {
+		version, err := writeState(s.file, state)
+		if err != nil {
+			return err
+		}
+		s.loadedAt = version
+	}
+
+	s.state.Store(&state)
+	return nil
+}
+
+// Reload reads the state file if it changed since it was loaded and returns whether
+// the state was reloaded. A removed file brings back the initial mode.
+func (s *Switch) Reload() (bool, error) {
+	return s.reload(false)
+}
+
+func (s *Switch) reload(force bool) (bool, error) {
+	if s.file == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, changed, err := fileversion.Changed(s.file, s.loadedAt, force)
+	if err != nil || !changed {
+		return false, err
+	}
+	if version.Missing() {
+		s.state.Store(&s.initial)
+		s.loadedAt = version
+		return true, nil
+	}
+
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return false, fmt.Errorf("failed to read maintenance state file: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return false, fmt.Errorf("invalid maintenance state file: %w", err)
+	}
+	if !state.Mode.Valid() {
+		return false, fmt.Errorf("invalid maintenance mode in state file: %s", state.Mode)
+	}
+
+	s.state.Store(&state)
+	s.loadedAt = version
+	return true, nil
+}
+
+// writeState replaces the state file atomically, so readers never see half of it
+func writeState(path string, state State) (fileversion.Version, error) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fileversion.Version{}, fmt.Errorf("failed to encode maintenance state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".maintenance-*")
+	if err != nil {
+		return fileversion.Version{}, fmt.Errorf("failed to write maintenance state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fileversion.Version{}, fmt.Errorf("failed to write maintenance state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fileversion.Version{}, fmt.Errorf("failed to write maintenance state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fileversion.Version{}, fmt.Errorf("failed to write maintenance state file: %w", err)
+	}
+
+	return fileversion.Stat(path)
+}
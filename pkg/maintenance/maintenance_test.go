@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSwitch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maintenance.json")
+
+	// Without a state file the initial mode applies
+	sw, err := NewSwitch(ModeReadOnly, path)
+	if err != nil {
+		t.Fatalf("Failed to create switch: %v", err)
+	}
+	if sw.State().Mode != ModeReadOnly {
+		t.Errorf("Expected the initial mode, got %s", sw.State().Mode)
+	}
+
+	// Set writes the state file, which a new switch picks up
+	if err := sw.Set(State{Mode: ModeFull, Message: "Database upgrade"}); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	restarted, err := NewSwitch(ModeOff, path)
+	if err != nil {
+		t.Fatalf("Failed to create switch: %v", err)
+	}
+	if state := restarted.State(); state.Mode != ModeFull || state.Message != "Database upgrade" {
+		t.Errorf("Expected the state file to win over the initial mode, got %+v", state)
+	}
+	if reloaded, _ := sw.Reload(); reloaded {
+		t.Error("Expected the switch not to reload its own write")
+	}
+
+	// Changes to the file are reloaded; invalid ones keep the mode in effect
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write state file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+
+	write(`{"mode":"off"}`, start)
+	if reloaded, err := restarted.Reload(); err != nil || !reloaded || restarted.State().Mode != ModeOff {
+		t.Errorf("Expected the new mode to be loaded, got %v, %v, %s", reloaded, err, restarted.State().Mode)
+	}
+
+	write(`{"mode":"closed"}`, start.Add(time.Minute))
+	if _, err := restarted.Reload(); err == nil {
+		t.Error("Expected an invalid mode to fail to load")
+	}
+	if restarted.State().Mode != ModeOff {
+		t.Errorf("Expected the previous mode to stay in effect, got %s", restarted.State().Mode)
+	}
+
+	// Removing the file brings back the initial mode
+	os.Remove(path)
+	if reloaded, _ := sw.Reload(); !reloaded || sw.State().Mode != ModeReadOnly {
+		t.Errorf("Expected the initial mode once the file is gone, got %s", sw.State().Mode)
+	}
+
+	if err := sw.Set(State{Mode: "closed"}); err == nil {
+		t.Error("Expected an invalid mode to be rejected")
+	}
+}
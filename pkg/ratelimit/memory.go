@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxKeys is how many clients the memory limiter tracks unless told otherwise
+	DefaultMaxKeys = 100000
+
+	// memoryShards is the number of separately locked parts the memory limiter's clients
+	// are spread over, so concurrent requests rarely wait for each other
+	memoryShards = 32
+
+	// sweepInterval is how often the memory limiter drops clients whose limit has fully
+	// replenished
+	sweepInterval = time.Minute
+)
+
+// MemoryLimiter keeps rate limits in process memory. Each instance of the service
+// counts separately, so use it for single instances or as a fallback.
+//
+// It tracks at most a fixed number of clients. Beyond that the least recently seen
+// client is forgotten, which gives it a full burst when it returns, so a flood of
+// spoofed clients costs bounded memory. A background sweep drops clients whose limit
+// has fully replenished; forgetting them doesn't change their next result. Call Stop
+// to end the sweep.
+type MemoryLimiter struct {
+	shards   [memoryShards]memoryShard
+	seed     maphash.Seed
+	now      func() time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// memoryShard holds the clients whose keys hash to it, most recently seen first
+type memoryShard struct {
+	mu       sync.Mutex
+	clients  map[string]*list.Element
+	lru      *list.List // of *memoryClient
+	capacity int
+}
+
+// memoryClient is the theoretical arrival time (TAT) of a client's next request
+type memoryClient struct {
+	key string
+	tat time.Time
+}
+
+// NewMemoryLimiter creates an in-memory limiter tracking up to maxKeys clients, or
+// DefaultMaxKeys if maxKeys isn't positive, and starts its sweep
+func NewMemoryLimiter(maxKeys int) *MemoryLimiter {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	capacity := (maxKeys + memoryShards - 1) / memoryShards
+
+	l := &MemoryLimiter{
+		seed: maphash.MakeSeed(),
+		now:  time.Now,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for i := range l.shards {
+		l.shards[i].clients = make(map[string]*list.Element)
+		l.shards[i].lru = list.New()
+		l.shards[i].capacity = capacity
+	}
+
+	go l.sweepLoop(sweepInterval)
+
+	return l
+}
+
+// Allow implements Limiter
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := l.now()
+	shard := &l.shards[maphash.String(l.seed, key)%memoryShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	return shard.allow(key, limit, now), nil
+}
+
+// Len returns the number of clients currently tracked
+func (l *MemoryLimiter) Len() int {
+	n := 0
+	for i := range l.shards {
+		shard := &l.shards[i]
+		shard.mu.Lock()
+		n += shard.lru.Len()
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// Stop ends the background sweep. The limiter keeps working without it, but no longer
+// frees clients before the cap is reached. Stop can be called more than once.
+func (l *MemoryLimiter) Stop() {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	<-l.done
+}
+
+// sweepLoop sweeps every shard each interval until the limiter is stopped
+func (l *MemoryLimiter) sweepLoop(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.sweep()
+		}
+	}
+}
+
+// sweep drops the clients whose limit has fully replenished, one shard at a time
+func (l *MemoryLimiter) sweep() {
+	for i := range l.shards {
+		shard := &l.shards[i]
+		shard.mu.Lock()
+		shard.sweep(l.now())
+		shard.mu.Unlock()
+	}
+}
+
+// allow applies a request of a client and marks the client as most recently seen.
+// Rejected requests of unknown clients leave nothing to remember.
+func (s *memoryShard) allow(key string, limit Limit, now time.Time) Result {
+	if elem, ok := s.clients[key]; ok {
+		client := elem.Value.(*memoryClient)
+		result, tat := gcra(now, client.tat, limit)
+		if result.Allowed {
+			client.tat = tat
+		}
+		s.lru.MoveToFront(elem)
+		return result
+	}
+
+	result, tat := gcra(now, time.Time{}, limit)
+	if !result.Allowed {
+		return result
+	}
+
+	if s.lru.Len() >= s.capacity {
+		s.remove(s.lru.Back())
+	}
+	s.clients[key] = s.lru.PushFront(&memoryClient{key: key, tat: tat})
+
+	return result
+}
+
+// sweep drops the clients whose TAT has passed; they would start over with the full
+// burst either way
+func (s *memoryShard) sweep(now time.Time) {
+	for elem := s.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if !elem.Value.(*memoryClient).tat.After(now) {
+			s.remove(elem)
+		}
+		elem = prev
+	}
+}
+
+// remove forgets a client
+func (s *memoryShard) remove(elem *list.Element) {
+	client := s.lru.Remove(elem).(*memoryClient)
+	delete(s.clients, client.key)
+}
@@ -0,0 +1,73 @@
+// Package ratelimit implements rate limiting with the generic cell rate algorithm
+// (GCRA), in memory for a single instance or in Redis for limits shared by every
+// instance of the service.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows Requests per Period, of which up to Burst can be made at once
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int // defaults to Requests
+}
+
+// interval returns the time it takes to earn back a single request
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// withDefaults returns the limit with the burst defaulted to the number of requests
+func (l Limit) withDefaults() Limit {
+	if l.Burst <= 0 {
+		l.Burst = l.Requests
+	}
+	return l
+}
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      Limit         // the limit applied, with its burst set
+	Remaining  int           // requests that can still be made right now
+	RetryAfter time.Duration // until a rejected request would be allowed, 0 when allowed
+	ResetAfter time.Duration // until the full burst is available again
+}
+
+// Limiter decides whether the client identified by key may make another request
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// gcra applies a request to the theoretical arrival time (TAT) of a client's next
+// request and returns the result and the new TAT, which is only stored when allowed
+func gcra(now, tat time.Time, limit Limit) (Result, time.Time) {
+	limit = limit.withDefaults()
+	interval := limit.interval()
+	tolerance := interval * time.Duration(limit.Burst)
+
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(interval)
+	diff := now.Sub(newTAT.Add(-tolerance))
+	if diff < 0 {
+		return Result{
+			Allowed:    false,
+			Limit:      limit,
+			RetryAfter: -diff,
+			ResetAfter: tat.Sub(now),
+		}, tat
+	}
+
+	return Result{
+		Allowed:    true,
+		Limit:      limit,
+		Remaining:  int(diff / interval),
+		ResetAfter: newTAT.Sub(now),
+	}, newTAT
+}
@@ -0,0 +1,196 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testLimiter runs the shared limiter checks; advance moves the limiter's clock forward
+func testLimiter(t *testing.T, limiter Limiter, advance func(time.Duration)) {
+	ctx := context.Background()
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 3}
+
+	// The full burst is available at once
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, "client", limit)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("Expected request to be allowed with %d remaining, got %+v", i, result)
+		}
+	}
+
+	// Then requests are rejected until a request has been earned back
+	result, err := limiter.Allow(ctx, "client", limit)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Expected request to be rejected, got %+v", result)
+	}
+	if result.RetryAfter <= 900*time.Millisecond || result.RetryAfter > time.Second {
+		t.Errorf("Expected retry after about 1s, got %s", result.RetryAfter)
+	}
+	if result.ResetAfter <= 2900*time.Millisecond || result.ResetAfter > 3*time.Second {
+		t.Errorf("Expected reset after about 3s, got %s", result.ResetAfter)
+	}
+
+	// Other clients have their own limit
+	if result, _ := limiter.Allow(ctx, "other", limit); !result.Allowed {
+		t.Errorf("Expected another client to be allowed, got %+v", result)
+	}
+
+	advance(time.Second)
+	if result, _ := limiter.Allow(ctx, "client", limit); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected request to be allowed after a second, got %+v", result)
+	}
+
+	advance(time.Minute)
+	if result, _ := limiter.Allow(ctx, "client", limit); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("Expected the full burst after a minute, got %+v", result)
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewMemoryLimiter(0)
+	defer limiter.Stop()
+	limiter.now = func() time.Time { return now }
+
+	testLimiter(t, limiter, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestMemoryLimiterCapacity(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Requests: 1, Period: time.Hour}
+
+	// One client per shard, so every new client evicts the one seen least recently
+	limiter := NewMemoryLimiter(memoryShards)
+	defer limiter.Stop()
+
+	for i := 0; i < 10*memoryShards; i++ {
+		if result, _ := limiter.Allow(ctx, fmt.Sprintf("client-%d", i), limit); !result.Allowed {
+			t.Fatalf("Expected new client %d to be allowed", i)
+		}
+	}
+	if n := limiter.Len(); n > memoryShards {
+		t.Errorf("Expected at most %d tracked clients, got %d", memoryShards, n)
+	}
+
+	// The most recently seen client is still tracked and limited
+	last := fmt.Sprintf("client-%d", 10*memoryShards-1)
+	if result, _ := limiter.Allow(ctx, last, limit); result.Allowed {
+		t.Errorf("Expected the most recent client to stay limited, got %+v", result)
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewMemoryLimiter(0)
+	defer limiter.Stop()
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow(ctx, "idle", Limit{Requests: 60, Period: time.Minute})
+	limiter.Allow(ctx, "active", Limit{Requests: 1, Period: time.Hour})
+
+	now = now.Add(sweepInterval)
+	limiter.sweep()
+
+	// The idle client has fully replenished and is dropped; the active client keeps its state
+	if n := limiter.Len(); n != 1 {
+		t.Errorf("Expected 1 tracked client after the sweep, got %d", n)
+	}
+	if result, _ := limiter.Allow(ctx, "active", Limit{Requests: 1, Period: time.Hour}); result.Allowed {
+		t.Errorf("Expected the sweep to keep the active client limited, got %+v", result)
+	}
+}
+
+func TestMemoryLimiterStop(t *testing.T) {
+	limiter := NewMemoryLimiter(0)
+	limiter.Stop()
+	limiter.Stop()
+
+	// The limiter keeps working without its sweep
+	if result, _ := limiter.Allow(context.Background(), "client", Limit{Requests: 1, Period: time.Minute}); !result.Allowed {
+		t.Errorf("Expected request to be allowed after Stop, got %+v", result)
+	}
+}
+
+// BenchmarkMemoryLimiter measures concurrent checks spread over many clients, all on
+// a single client, and over more clients than the limiter tracks
+func BenchmarkMemoryLimiter(b *testing.B) {
+	ctx := context.Background()
+	limit := Limit{Requests: 1000000, Period: time.Second}
+
+	keys := make([]string, 1<<16)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("ip:10.0.%d.%d", i>>8, i&0xff)
+	}
+
+	benchmarks := []struct {
+		name    string
+		maxKeys int
+		key     func(i int) string
+	}{
+		{"ManyClients", len(keys), func(i int) string { return keys[i%len(keys)] }},
+		{"OneClient", len(keys), func(int) string { return keys[0] }},
+		{"OverCapacity", len(keys) / 16, func(i int) string { return keys[i%len(keys)] }},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			limiter := NewMemoryLimiter(bm.maxKeys)
+			defer limiter.Stop()
+
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					limiter.Allow(ctx, bm.key(int(next.Add(1))), limit)
+				}
+			})
+		})
+	}
+}
+
+func TestRedisLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	server := miniredis.RunT(t)
+	server.SetTime(now)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	testLimiter(t, NewRedisLimiter(client, "ratelimit:"), func(d time.Duration) {
+		now = now.Add(d)
+		server.SetTime(now)
+		server.FastForward(d)
+	})
+
+	if !server.Exists("ratelimit:client") {
+		t.Error("Expected the client's state to be stored under the key prefix")
+	}
+}
+
+func TestRedisLimiterUnavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+	server.Close()
+
+	if _, err := NewRedisLimiter(client, "ratelimit:").Allow(context.Background(), "client", Limit{Requests: 1, Period: time.Second}); err == nil {
+		t.Error("Expected an error when Redis is unreachable")
+	}
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript applies GCRA atomically. The theoretical arrival time is kept in seconds
+// and expires once the limit has replenished. Time comes from the Redis server, so
+// instances with skewed clocks share the same limits.
+//
+// KEYS[1]: client key
+// ARGV: burst, requests, period in seconds
+// Returns: allowed (0/1), remaining, retry after and reset after in seconds
+var gcraScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[3]) / tonumber(ARGV[2])
+local tolerance = interval * burst
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local diff = now - (new_tat - tolerance)
+if diff < 0 then
+	return {0, 0, tostring(-diff), tostring(tat - now)}
+end
+
+local reset_after = new_tat - now
+redis.call("SET", KEYS[1], string.format("%.6f", new_tat), "PX", math.ceil(reset_after * 1000))
+return {1, math.floor(diff / interval), "0", tostring(reset_after)}
+`)
+
+// RedisLimiter keeps rate limits in Redis, so every instance of the service shares them
+type RedisLimiter struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisLimiter creates a limiter storing its state in Redis under keys with the
+// given prefix
+func NewRedisLimiter(client redis.Scripter, prefix string) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Allow implements Limiter
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	limit = limit.withDefaults()
+
+	values, err := gcraScript.Run(ctx, l.client, []string{l.prefix + key},
+		limit.Burst, limit.Requests, limit.Period.Seconds()).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	retryAfter, err := parseSeconds(values[2])
+	if err != nil {
+		return Result{}, err
+	}
+	resetAfter, err := parseSeconds(values[3])
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    allowed == 1,
+		Limit:      limit,
+		Remaining:  int(remaining),
+		RetryAfter: retryAfter,
+		ResetAfter: resetAfter,
+	}, nil
+}
+
+// parseSeconds converts a script result in (fractional) seconds to a duration
+func parseSeconds(value interface{}) (time.Duration, error) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected rate limit script value: %v", value)
+	}
+
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected rate limit script value: %v", value)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
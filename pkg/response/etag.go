@@ -0,0 +1,84 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag formats a resource version as a strong entity tag
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// SetETag sets the ETag response header for a resource version
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", ETag(version))
+}
+
+// NotModified handles If-None-Match for a resource version. It sets the ETag header
+// and, when the client already holds the current version, sends 304 and returns true.
+func NotModified(c *gin.Context, version int) bool {
+	SetETag(c, version)
+
+	ifNoneMatch := c.GetHeader("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	etag := ETag(version)
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// If-None-Match uses weak comparison, so W/ prefixes are ignored
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if candidate == "*" || candidate == etag {
+			c.Status(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseIfMatch extracts the expected resource version from the If-Match header.
+// It returns 0 when the header is absent or "*" (any current version matches).
+func ParseIfMatch(c *gin.Context) (int, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	if strings.Contains(ifMatch, ",") {
+		return 0, errors.New("If-Match must contain a single entity tag")
+	}
+
+	// Weak tags never match under the strong comparison If-Match requires
+	if strings.HasPrefix(ifMatch, "W/") {
+		return 0, errors.New("If-Match does not accept weak entity tags")
+	}
+
+	if len(ifMatch) < 2 || ifMatch[0] != '"' || ifMatch[len(ifMatch)-1] != '"' {
+		return 0, errors.New("If-Match must be a quoted entity tag")
+	}
+
+	version, err := strconv.Atoi(ifMatch[1 : len(ifMatch)-1])
+	if err != nil || version < 1 {
+		return 0, errors.New("If-Match does not match any known version")
+	}
+
+	return version, nil
+}
+
+// PreconditionFailed sends a 412 error response
+func PreconditionFailed(c *gin.Context, message string) {
+	Error(c, http.StatusPreconditionFailed, "PRECONDITION_FAILED", message)
+}
+
+// PreconditionRequired sends a 428 error response
+func PreconditionRequired(c *gin.Context, message string) {
+	Error(c, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED", message)
+}
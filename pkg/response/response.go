@@ -63,6 +63,18 @@ func Created(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// Accepted sends a response for a request that was accepted for later processing
+func Accepted(c *gin.Context, message string, data interface{}) {
+	response := APIResponse{
+		Success:   true,
+		Message:   message,
+		Data:      data,
+		Timestamp: time.Now(),
+		RequestID: getRequestID(c),
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
 // SuccessWithMeta sends a successful response with metadata
 func SuccessWithMeta(c *gin.Context, message string, data interface{}, meta *Meta) {
 	response := APIResponse{
@@ -0,0 +1,249 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Expander loads a related resource for a resource, e.g. a user's teams. It receives the
+// resource as it was passed to Shape.Apply.
+type Expander func(ctx context.Context, resource interface{}) (interface{}, error)
+
+// Expanders maps the names accepted by ?expand= to the expander loading each relation
+type Expanders map[string]Expander
+
+// ExpandFunc adapts a typed function to an Expander. The resource may be a T or a *T.
+func ExpandFunc[T any](fn func(ctx context.Context, resource T) (interface{}, error)) Expander {
+	return func(ctx context.Context, resource interface{}) (interface{}, error) {
+		switch v := resource.(type) {
+		case T:
+			return fn(ctx, v)
+		case *T:
+			return fn(ctx, *v)
+		default:
+			return nil, fmt.Errorf("cannot expand resource of type %T", resource)
+		}
+	}
+}
+
+// ShapeError reports an invalid ?fields= or ?expand= parameter
+type ShapeError struct {
+	Code    string
+	Message string
+}
+
+func (e *ShapeError) Error() string {
+	return e.Message
+}
+
+// Shape describes how a client wants a resource shaped: which fields to return
+// (?fields=id,email) and which related resources to embed (?expand=teams)
+type Shape struct {
+	Fields []string
+	Expand []string
+}
+
+// ParseShape reads the fields and expand query parameters of a request
+func ParseShape(c *gin.Context) Shape {
+	return Shape{
+		Fields: splitList(c.Query("fields")),
+		Expand: splitList(c.Query("expand")),
+	}
+}
+
+// IsZero returns true if the client asked for the full resource without expansions
+func (s Shape) IsZero() bool {
+	return len(s.Fields) == 0 && len(s.Expand) == 0
+}
+
+// Apply shapes a struct, or a slice of structs, using the struct's JSON field names.
+// Requested fields must be JSON fields of the struct and expansions must be in expanders,
+// otherwise a *ShapeError is returned. Expanded relations are always included alongside
+// the selected fields. With a zero shape the resource is returned unchanged.
+func (s Shape) Apply(ctx context.Context, resource interface{}, expanders Expanders) (interface{}, error) {
+	if s.IsZero() {
+		return resource, nil
+	}
+
+	for _, name := range s.Expand {
+		if _, ok := expanders[name]; !ok {
+			return nil, &ShapeError{
+				Code:    "INVALID_EXPAND",
+				Message: fmt.Sprintf("Unknown expansion %q; allowed: %s", name, strings.Join(sortedKeys(expanders), ", ")),
+			}
+		}
+	}
+
+	value := reflect.ValueOf(resource)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return resource, nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		if err := s.validateFields(value.Type().Elem()); err != nil {
+			return nil, err
+		}
+
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			shaped, err := s.shapeOne(ctx, value.Index(i).Interface(), expanders)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = shaped
+		}
+		return items, nil
+	}
+
+	if err := s.validateFields(value.Type()); err != nil {
+		return nil, err
+	}
+
+	return s.shapeOne(ctx, resource, expanders)
+}
+
+// validateFields checks the requested fields against the JSON fields of a struct type
+func (s Shape) validateFields(t reflect.Type) error {
+	if len(s.Fields) == 0 {
+		return nil
+	}
+
+	allowed := jsonFields(t)
+	for _, field := range s.Fields {
+		if !allowed[field] {
+			return &ShapeError{
+				Code:    "INVALID_FIELDS",
+				Message: fmt.Sprintf("Unknown field %q; allowed: %s", field, strings.Join(sortedKeys(allowed), ", ")),
+			}
+		}
+	}
+
+	return nil
+}
+
+// shapeOne selects the requested fields of a single resource and adds its expansions
+func (s Shape) shapeOne(ctx context.Context, resource interface{}, expanders Expanders) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var full map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &full); err != nil {
+		return nil, err
+	}
+
+	shaped := make(map[string]interface{}, len(full)+len(s.Expand))
+	if len(s.Fields) == 0 {
+		for name, value := range full {
+			shaped[name] = value
+		}
+	} else {
+		// Fields tagged omitempty may be absent and stay absent
+		for _, name := range s.Fields {
+			if value, ok := full[name]; ok {
+				shaped[name] = value
+			}
+		}
+	}
+
+	for _, name := range s.Expand {
+		related, err := expanders[name](ctx, resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", name, err)
+		}
+		shaped[name] = related
+	}
+
+	return shaped, nil
+}
+
+// jsonFields returns the names a struct type is encoded with by encoding/json, including
+// the promoted fields of embedded structs
+func jsonFields(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := map[string]bool{}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			for embedded := range jsonFields(field.Type) {
+				fields[embedded] = true
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = true
+	}
+
+	return fields
+}
+
+// splitList splits a comma-separated query parameter, dropping blanks and duplicates
+func splitList(value string) []string {
+	var items []string
+	seen := map[string]bool{}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// sortedKeys returns the keys of a map in order, for stable error messages
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ShapeFailed sends the error response for a failed Shape.Apply: 400 for an invalid
+// fields or expand parameter, 500 when loading a related resource failed
+func ShapeFailed(c *gin.Context, err error) {
+	var shapeErr *ShapeError
+	if errors.As(err, &shapeErr) {
+		Error(c, http.StatusBadRequest, shapeErr.Code, shapeErr.Message)
+		return
+	}
+
+	InternalServerError(c, "Failed to load related resources")
+}
@@ -0,0 +1,84 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type shapeBase struct {
+	ID string `json:"id"`
+}
+
+type shapeUser struct {
+	shapeBase
+	Email    string `json:"email"`
+	Nickname string `json:"nickname,omitempty"`
+	Password string `json:"-"`
+	internal string
+}
+
+func shapeJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	return string(encoded)
+}
+
+func TestShapeApply(t *testing.T) {
+	user := shapeUser{shapeBase: shapeBase{ID: "1"}, Email: "a@example.com", Password: "secret", internal: "x"}
+	expanders := Expanders{
+		"teams": ExpandFunc(func(ctx context.Context, u shapeUser) (interface{}, error) {
+			return []string{"team-of-" + u.ID}, nil
+		}),
+	}
+
+	tests := []struct {
+		name     string
+		shape    Shape
+		resource interface{}
+		want     string
+		wantCode string
+	}{
+		{"zero shape", Shape{}, user, `{"id":"1","email":"a@example.com"}`, ""},
+		{"fields", Shape{Fields: []string{"email"}}, user, `{"email":"a@example.com"}`, ""},
+		{"embedded field", Shape{Fields: []string{"id"}}, &user, `{"id":"1"}`, ""},
+		{"absent omitempty field", Shape{Fields: []string{"id", "nickname"}}, user, `{"id":"1"}`, ""},
+		{"expand", Shape{Fields: []string{"id"}, Expand: []string{"teams"}}, &user, `{"id":"1","teams":["team-of-1"]}`, ""},
+		{"slice", Shape{Fields: []string{"id"}}, []shapeUser{user, user}, `[{"id":"1"},{"id":"1"}]`, ""},
+		{"unknown field", Shape{Fields: []string{"password"}}, user, "", "INVALID_FIELDS"},
+		{"unexported field", Shape{Fields: []string{"internal"}}, user, "", "INVALID_FIELDS"},
+		{"unknown expansion", Shape{Expand: []string{"sessions"}}, user, "", "INVALID_EXPAND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shaped, err := tt.shape.Apply(context.Background(), tt.resource, expanders)
+
+			if tt.wantCode != "" {
+				var shapeErr *ShapeError
+				if !errors.As(err, &shapeErr) || shapeErr.Code != tt.wantCode {
+					t.Fatalf("Expected %s error, got %v", tt.wantCode, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := shapeJSON(t, shaped); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" id, email,,id ")
+	if len(got) != 2 || got[0] != "id" || got[1] != "email" {
+		t.Errorf("Expected [id email], got %v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URLSigner builds and verifies expiring, HMAC-signed links to blobs served by the API
+type URLSigner struct {
+	secret  []byte
+	baseURL string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewURLSigner creates a URL signer. baseURL is the public URL blobs are served under
+// (e.g. https://api.example.com/api/v1/files) and ttl is how long links stay valid.
+func NewURLSigner(secret, baseURL string, ttl time.Duration) *URLSigner {
+	return &URLSigner{
+		secret:  []byte(secret),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// SignedURL returns a link to the blob that stays valid for at least the signer's TTL.
+// Expiry is rounded up to a TTL boundary so links stay stable (and cacheable) for a while.
+func (s *URLSigner) SignedURL(key string) string {
+	window := int64(s.ttl / time.Second)
+	if window < 1 {
+		window = 1
+	}
+	expires := (s.now().Unix()/window + 2) * window
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.signature(key, expires))
+
+	return s.baseURL + "/" + uriEncode(key, true) + "?" + query.Encode()
+}
+
+// Verify checks a link's signature and expiry
+func (s *URLSigner) Verify(key, expiresParam, signature string) error {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil {
+		return errors.New("invalid expiry")
+	}
+
+	expected := s.signature(key, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("invalid signature")
+	}
+
+	if s.now().Unix() > expires {
+		return errors.New("link has expired")
+	}
+
+	return nil
+}
+
+// signature computes the HMAC of the key and expiry
+func (s *URLSigner) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,175 @@
+// Package tenant carries the current tenant through request contexts and enforces
+// tenant isolation on every GORM query for models that have a tenant_id column.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Column is the column that marks a model as tenant-scoped
+const Column = "tenant_id"
+
+var (
+	// ErrNoTenant is returned when a tenant-scoped query runs without a tenant in its context
+	ErrNoTenant = errors.New("tenant scope required")
+	// ErrTenantMismatch is returned when creating a record for a tenant other than the current one
+	ErrTenantMismatch = errors.New("record belongs to another tenant")
+)
+
+type contextKey struct{}
+
+// scope is the tenant scope stored in a context
+type scope struct {
+	tenantID   string
+	allTenants bool
+}
+
+// WithTenant returns a context scoped to a single tenant
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{tenantID: tenantID})
+}
+
+// WithAllTenants returns a context that explicitly opts out of tenant scoping. It is
+// meant for super admins and background jobs that operate across tenants.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{allTenants: true})
+}
+
+// FromContext returns the tenant a context is scoped to
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	s, ok := ctx.Value(contextKey{}).(scope)
+	if !ok || s.allTenants || s.tenantID == "" {
+		return "", false
+	}
+	return s.tenantID, true
+}
+
+// IsAllTenants returns true if a context explicitly spans all tenants
+func IsAllTenants(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	s, ok := ctx.Value(contextKey{}).(scope)
+	return ok && s.allTenants
+}
+
+// RegisterCallbacks installs the GORM callbacks that scope queries, updates and deletes
+// of tenant-scoped models to the context's tenant and stamp it on created records.
+// Statements without a tenant in their context fail with ErrNoTenant unless the
+// context comes from WithAllTenants. Raw SQL is not rewritten.
+func RegisterCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", assignTenant); err != nil {
+		return fmt.Errorf("failed to register tenant create callback: %w", err)
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", scopeStatement); err != nil {
+		return fmt.Errorf("failed to register tenant query callback: %w", err)
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:row", scopeStatement); err != nil {
+		return fmt.Errorf("failed to register tenant row callback: %w", err)
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", scopeStatement); err != nil {
+		return fmt.Errorf("failed to register tenant update callback: %w", err)
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", scopeStatement); err != nil {
+		return fmt.Errorf("failed to register tenant delete callback: %w", err)
+	}
+
+	return nil
+}
+
+// isScoped returns true if the statement targets a tenant-scoped model through the query builder
+func isScoped(db *gorm.DB) bool {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return false
+	}
+	_, ok := stmt.Schema.FieldsByDBName[Column]
+	return ok
+}
+
+// scopeStatement adds "tenant_id = ?" to the statement's WHERE clause. Existing
+// conditions are grouped first so an OR among them can't escape the tenant filter.
+func scopeStatement(db *gorm.DB) {
+	if !isScoped(db) {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if IsAllTenants(ctx) {
+		return
+	}
+
+	tenantID, ok := FromContext(ctx)
+	if !ok {
+		_ = db.AddError(ErrNoTenant)
+		return
+	}
+
+	where := clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: Column}, Value: tenantID},
+	}}
+
+	if c, ok := db.Statement.Clauses["WHERE"]; ok {
+		if existing, ok := c.Expression.(clause.Where); ok && len(existing.Exprs) > 0 {
+			where.Exprs = append(where.Exprs, clause.And(existing.Exprs...))
+		}
+		c.Expression = where
+		db.Statement.Clauses["WHERE"] = c
+		return
+	}
+
+	db.Statement.AddClause(where)
+}
+
+// assignTenant sets the context's tenant on records being created, rejecting records
+// that already name a different tenant
+func assignTenant(db *gorm.DB) {
+	if !isScoped(db) {
+		return
+	}
+
+	ctx := db.Statement.Context
+	tenantID, ok := FromContext(ctx)
+	allTenants := IsAllTenants(ctx)
+	if !ok && !allTenants {
+		_ = db.AddError(ErrNoTenant)
+		return
+	}
+
+	field := db.Statement.Schema.FieldsByDBName[Column]
+	assign := func(rv reflect.Value) {
+		value, zero := field.ValueOf(ctx, rv)
+		switch {
+		case zero && allTenants:
+			// Cross-tenant callers must say which tenant a new record belongs to
+			_ = db.AddError(ErrNoTenant)
+		case zero:
+			if err := field.Set(ctx, rv, tenantID); err != nil {
+				_ = db.AddError(err)
+			}
+		case !allTenants && fmt.Sprint(value) != tenantID:
+			_ = db.AddError(ErrTenantMismatch)
+		}
+	}
+
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			assign(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		assign(rv)
+	}
+}
@@ -0,0 +1,116 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type scopedRecord struct {
+	ID       string
+	TenantID string
+	Name     string
+}
+
+type unscopedRecord struct {
+	ID   string
+	Name string
+}
+
+// newDryRunDB returns a database that builds SQL without connecting
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run database: %v", err)
+	}
+
+	if err := RegisterCallbacks(db); err != nil {
+		t.Fatalf("Failed to register callbacks: %v", err)
+	}
+
+	return db
+}
+
+func TestScopedQuery(t *testing.T) {
+	db := newDryRunDB(t)
+	ctx := WithTenant(context.Background(), "tenant-a")
+
+	var records []scopedRecord
+	stmt := db.WithContext(ctx).Where("name = ?", "x").Or("name = ?", "y").Find(&records).Statement
+
+	sql := stmt.SQL.String()
+	if !strings.Contains(sql, `"scoped_records"."tenant_id" = $1 AND (name = $2 OR name = $3)`) {
+		t.Errorf("Expected tenant filter around grouped conditions, got %s", sql)
+	}
+	if stmt.Vars[0] != "tenant-a" {
+		t.Errorf("Expected tenant-a as first var, got %v", stmt.Vars[0])
+	}
+}
+
+func TestScopedQueryRequiresTenant(t *testing.T) {
+	db := newDryRunDB(t)
+
+	var records []scopedRecord
+	err := db.WithContext(context.Background()).Find(&records).Error
+	if !errors.Is(err, ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant, got %v", err)
+	}
+
+	err = db.WithContext(WithAllTenants(context.Background())).Find(&records).Error
+	if err != nil {
+		t.Errorf("Expected all-tenants query to succeed, got %v", err)
+	}
+
+	var others []unscopedRecord
+	if err := db.Find(&others).Error; err != nil {
+		t.Errorf("Expected unscoped model to need no tenant, got %v", err)
+	}
+}
+
+func TestScopedUpdateAndDelete(t *testing.T) {
+	db := newDryRunDB(t)
+	ctx := WithTenant(context.Background(), "tenant-a")
+
+	update := db.WithContext(ctx).Model(&scopedRecord{}).Where("id = ?", "1").Update("name", "x").Statement
+	if !strings.Contains(update.SQL.String(), `"scoped_records"."tenant_id" =`) {
+		t.Errorf("Expected tenant filter on update, got %s", update.SQL.String())
+	}
+
+	del := db.WithContext(ctx).Where("id = ?", "1").Delete(&scopedRecord{}).Statement
+	if !strings.Contains(del.SQL.String(), `"scoped_records"."tenant_id" =`) {
+		t.Errorf("Expected tenant filter on delete, got %s", del.SQL.String())
+	}
+}
+
+func TestAssignTenantOnCreate(t *testing.T) {
+	db := newDryRunDB(t)
+	ctx := WithTenant(context.Background(), "tenant-a")
+
+	record := scopedRecord{Name: "x"}
+	if err := db.WithContext(ctx).Create(&record).Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if record.TenantID != "tenant-a" {
+		t.Errorf("Expected tenant-a to be assigned, got %q", record.TenantID)
+	}
+
+	other := scopedRecord{Name: "y", TenantID: "tenant-b"}
+	if err := db.WithContext(ctx).Create(&other).Error; !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("Expected ErrTenantMismatch, got %v", err)
+	}
+
+	unassigned := scopedRecord{Name: "z"}
+	if err := db.WithContext(WithAllTenants(context.Background())).Create(&unassigned).Error; !errors.Is(err, ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant for cross-tenant create without a tenant, got %v", err)
+	}
+}